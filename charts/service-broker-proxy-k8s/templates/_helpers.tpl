@@ -38,3 +38,10 @@ Create chart name and version as used by the chart label.
 {{- define "service-broker-proxy.chart" -}}
 {{- printf "%s-%s" .Chart.Name .Chart.Version | replace "+" "_" | trunc 63 | trimSuffix "-" -}}
 {{- end -}}
+
+{{/*
+Create the comma-separated list of namespaces in which service brokers are registered.
+*/}}
+{{- define "service-broker-proxy.targetNamespaces" -}}
+{{- concat (list .Values.targetNamespace) (.Values.targetNamespaces | default list) | compact | uniq | join "," -}}
+{{- end -}}
@@ -8,6 +8,9 @@ import (
 	"github.com/Peripli/service-broker-proxy-k8s/pkg/k8s/config"
 
 	"github.com/Peripli/service-broker-proxy/pkg/sbproxy"
+	"github.com/Peripli/service-broker-proxy/pkg/sbproxy/reconcile"
+	"github.com/Peripli/service-broker-proxy/pkg/sm"
+	"github.com/Peripli/service-manager/pkg/log"
 
 	"github.com/spf13/pflag"
 )
@@ -28,15 +31,70 @@ func main() {
 		panic(fmt.Errorf("error loading config: %s", err))
 	}
 
+	shutdownTracing, err := client.StartTracing(ctx, proxySettings.Tracing)
+	if err != nil {
+		panic(fmt.Errorf("error starting tracing: %s", err))
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.D().WithError(err).Error("Could not stop tracing")
+		}
+	}()
+
 	platformClient, err := client.NewClient(proxySettings)
 	if err != nil {
 		panic(fmt.Errorf("error creating K8S client: %s", err))
 	}
 
+	// the labels of the Service Manager brokers are read from the brokers which the resyncs list, so the Service
+	// Manager clients of the resyncs are created after the platform client records their lists
+	if err := platformClient.RecordSMBrokers(proxySettings.Sm); err != nil {
+		panic(fmt.Errorf("error recording SM brokers: %s", err))
+	}
+
+	// a replica which becomes the leader after it has skipped changes as a follower, or whose shard group changes,
+	// resyncs the brokers like the resync of sbproxy. Both start before the namespace watch and the broker syncs, which
+	// write brokers only once the replica knows whether it is the leader and which brokers it owns.
+	smClient, err := sm.NewClient(proxySettings.Sm)
+	if err != nil {
+		panic(fmt.Errorf("error creating SM client: %s", err))
+	}
+	resyncer := reconcile.NewResyncer(proxySettings.Reconcile, platformClient, smClient, proxySettings.Sm,
+		proxySettings.Reconcile.URL+sbproxy.APIPrefix, proxySettings.Reconcile.LegacyURL+sbproxy.APIPrefix+"/%s")
+	resync := func(ctx context.Context) {
+		resyncer.Resync(ctx, true)
+	}
+	if err := platformClient.StartLeaderElection(ctx, resync); err != nil {
+		panic(fmt.Errorf("error starting K8S leader election: %s", err))
+	}
+
+	if err := platformClient.StartSharding(ctx, resync); err != nil {
+		panic(fmt.Errorf("error starting K8S sharding: %s", err))
+	}
+
+	if err := platformClient.WatchNamespaces(ctx); err != nil {
+		panic(fmt.Errorf("error watching K8S namespaces: %s", err))
+	}
+
+	if err := platformClient.StartBrokerCache(ctx); err != nil {
+		panic(fmt.Errorf("error starting K8S broker cache: %s", err))
+	}
+
+	if err := platformClient.StartBrokerSyncs(ctx); err != nil {
+		panic(fmt.Errorf("error starting K8S broker syncs: %s", err))
+	}
+
 	proxyBuilder, err := sbproxy.New(ctx, cancel, env, &proxySettings.Settings, platformClient)
 	if err != nil {
 		panic(fmt.Errorf("error creating sbproxy: %s", err))
 	}
 
+	for _, indicator := range platformClient.HealthIndicators() {
+		proxyBuilder.SetIndicator(indicator)
+	}
+
+	proxyBuilder.RegisterControllers(platformClient.MetricsController())
+	proxyBuilder.RegisterFilters(client.NewTracingFilter())
+
 	proxyBuilder.Build().Run()
 }
@@ -1,44 +1,88 @@
 package api
 
 import (
+	"context"
+
 	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	v1core "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/util/workqueue"
 )
 
 // KubernetesAPI interface for communicating with kubernetes cluster
 //go:generate counterfeiter . KubernetesAPI
 type KubernetesAPI interface {
 	// CreateClusterServiceBroker creates cluster-wide visible service broker
-	CreateClusterServiceBroker(broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error)
+	CreateClusterServiceBroker(ctx context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error)
 	// DeleteClusterServiceBroker deletes cluster-wide visible service broker
-	DeleteClusterServiceBroker(name string, options *v1.DeleteOptions) error
+	DeleteClusterServiceBroker(ctx context.Context, name string, options *v1.DeleteOptions) error
 	// RetrieveClusterServiceBrokers gets all cluster-wide visible service brokers
-	RetrieveClusterServiceBrokers() (*v1beta1.ClusterServiceBrokerList, error)
+	RetrieveClusterServiceBrokers(ctx context.Context) (*v1beta1.ClusterServiceBrokerList, error)
 	// RetrieveClusterServiceBrokerByName gets cluster-wide visible service broker
-	RetrieveClusterServiceBrokerByName(name string) (*v1beta1.ClusterServiceBroker, error)
+	RetrieveClusterServiceBrokerByName(ctx context.Context, name string) (*v1beta1.ClusterServiceBroker, error)
 	// UpdateClusterServiceBroker gets cluster-wide visible service broker
-	UpdateClusterServiceBroker(broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error)
+	UpdateClusterServiceBroker(ctx context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error)
 	// SyncClusterServiceBroker synchronize a cluster-wide visible service broker
-	SyncClusterServiceBroker(name string, retries int) error
+	SyncClusterServiceBroker(ctx context.Context, name string, retries int) error
+	// RetrieveClusterServicePlans gets all cluster-wide visible service plans of a service broker
+	RetrieveClusterServicePlans(ctx context.Context, brokerName string) (*v1beta1.ClusterServicePlanList, error)
 
 	// CreateNamespaceServiceBroker creates namespace service broker
-	CreateNamespaceServiceBroker(broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error)
+	CreateNamespaceServiceBroker(ctx context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error)
 	// DeleteNamespaceServiceBroker deletes a service broker in a namespace
-	DeleteNamespaceServiceBroker(name string, namespace string, options *v1.DeleteOptions) error
+	DeleteNamespaceServiceBroker(ctx context.Context, name string, namespace string, options *v1.DeleteOptions) error
 	// RetrieveNamespaceServiceBrokers gets all service brokers in a namespace
-	RetrieveNamespaceServiceBrokers(namespace string) (*v1beta1.ServiceBrokerList, error)
+	RetrieveNamespaceServiceBrokers(ctx context.Context, namespace string) (*v1beta1.ServiceBrokerList, error)
 	// RetrieveNamespaceServiceBrokerByName gets a service broker in a namespace
-	RetrieveNamespaceServiceBrokerByName(name, namespace string) (*v1beta1.ServiceBroker, error)
+	RetrieveNamespaceServiceBrokerByName(ctx context.Context, name, namespace string) (*v1beta1.ServiceBroker, error)
 	// UpdateNamespaceServiceBroker updates a service broker in a namespace
-	UpdateNamespaceServiceBroker(broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error)
+	UpdateNamespaceServiceBroker(ctx context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error)
 	// SyncNamespaceServiceBroker synchronize a service broker in a namespace
-	SyncNamespaceServiceBroker(name, namespace string, retries int) error
+	SyncNamespaceServiceBroker(ctx context.Context, name, namespace string, retries int) error
+	// RetrieveNamespaceServicePlans gets all service plans of a service broker in a namespace
+	RetrieveNamespaceServicePlans(ctx context.Context, brokerName, namespace string) (*v1beta1.ServicePlanList, error)
 
 	// UpdateServiceBrokerCredentials updates broker's credentials secret
-	UpdateServiceBrokerCredentials(secret *v1core.Secret) (*v1core.Secret, error)
+	UpdateServiceBrokerCredentials(ctx context.Context, secret *v1core.Secret) (*v1core.Secret, error)
 	// CreateSecret creates a secret for broker's credentials
-	CreateSecret(secret *v1core.Secret) (*v1core.Secret, error)
+	CreateSecret(ctx context.Context, secret *v1core.Secret) (*v1core.Secret, error)
+	// RetrieveSecret gets broker credentials secret
+	RetrieveSecret(ctx context.Context, namespace, name string) (*v1core.Secret, error)
 	// DeleteSecret deletes broker credentials secret
-	DeleteSecret(namespace, name string) error
+	DeleteSecret(ctx context.Context, namespace, name string) error
+
+	// StartBrokerCache starts caching the cluster-wide and namespace service brokers until the context is done
+	StartBrokerCache(ctx context.Context) error
+	// BrokerCacheSynced reports whether the broker cache has been started and synced
+	BrokerCacheSynced() bool
+	// RetrieveCachedClusterServiceBrokers gets all cluster-wide visible service brokers from the broker cache
+	RetrieveCachedClusterServiceBrokers(ctx context.Context) (*v1beta1.ClusterServiceBrokerList, error)
+	// RetrieveCachedClusterServiceBrokerByName gets cluster-wide visible service broker from the broker cache
+	RetrieveCachedClusterServiceBrokerByName(ctx context.Context, name string) (*v1beta1.ClusterServiceBroker, error)
+	// RetrieveCachedNamespaceServiceBrokers gets all service brokers in a namespace from the broker cache
+	RetrieveCachedNamespaceServiceBrokers(ctx context.Context, namespace string) (*v1beta1.ServiceBrokerList, error)
+	// RetrieveCachedNamespaceServiceBrokerByName gets a service broker in a namespace from the broker cache
+	RetrieveCachedNamespaceServiceBrokerByName(ctx context.Context, name, namespace string) (*v1beta1.ServiceBroker, error)
+
+	// WatchNamespaces notifies the handler about namespaces which start or stop matching the label selector
+	// until the context is done. It returns once the matching namespaces have been listed.
+	WatchNamespaces(ctx context.Context, labelSelector string, handler cache.ResourceEventHandler) error
+
+	// StartBrokerSyncs starts the workers which sync the service brokers until the context is done.
+	// The rate limiter delays the retries of failed syncs and limits the rate of all syncs.
+	StartBrokerSyncs(ctx context.Context, workers int, rateLimiter workqueue.RateLimiter) error
+
+	// NewLeaseLock returns the lock of the Lease in the namespace, which is held by the identity when it is the leader
+	NewLeaseLock(namespace, name, identity string) resourcelock.Interface
+	// CreateLease creates a Lease
+	CreateLease(ctx context.Context, lease *coordinationv1.Lease) (*coordinationv1.Lease, error)
+	// UpdateLease updates a Lease
+	UpdateLease(ctx context.Context, lease *coordinationv1.Lease) (*coordinationv1.Lease, error)
+	// RetrieveLeases gets all Leases in a namespace which match the label selector
+	RetrieveLeases(ctx context.Context, namespace, labelSelector string) (*coordinationv1.LeaseList, error)
+	// DeleteLease deletes a Lease
+	DeleteLease(ctx context.Context, namespace, name string) error
 }
@@ -2,19 +2,35 @@
 package apifakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/Peripli/service-broker-proxy-k8s/pkg/k8s/api"
 	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
-	v1 "k8s.io/api/core/v1"
-	v1a "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/api/coordination/v1"
+	v1a "k8s.io/api/core/v1"
+	v1b "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/util/workqueue"
 )
 
 type FakeKubernetesAPI struct {
-	CreateClusterServiceBrokerStub        func(*v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error)
+	BrokerCacheSyncedStub        func() bool
+	brokerCacheSyncedMutex       sync.RWMutex
+	brokerCacheSyncedArgsForCall []struct {
+	}
+	brokerCacheSyncedReturns struct {
+		result1 bool
+	}
+	brokerCacheSyncedReturnsOnCall map[int]struct {
+		result1 bool
+	}
+	CreateClusterServiceBrokerStub        func(context.Context, *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error)
 	createClusterServiceBrokerMutex       sync.RWMutex
 	createClusterServiceBrokerArgsForCall []struct {
-		arg1 *v1beta1.ClusterServiceBroker
+		arg1 context.Context
+		arg2 *v1beta1.ClusterServiceBroker
 	}
 	createClusterServiceBrokerReturns struct {
 		result1 *v1beta1.ClusterServiceBroker
@@ -24,11 +40,26 @@ type FakeKubernetesAPI struct {
 		result1 *v1beta1.ClusterServiceBroker
 		result2 error
 	}
-	CreateNamespaceServiceBrokerStub        func(*v1beta1.ServiceBroker, string) (*v1beta1.ServiceBroker, error)
+	CreateLeaseStub        func(context.Context, *v1.Lease) (*v1.Lease, error)
+	createLeaseMutex       sync.RWMutex
+	createLeaseArgsForCall []struct {
+		arg1 context.Context
+		arg2 *v1.Lease
+	}
+	createLeaseReturns struct {
+		result1 *v1.Lease
+		result2 error
+	}
+	createLeaseReturnsOnCall map[int]struct {
+		result1 *v1.Lease
+		result2 error
+	}
+	CreateNamespaceServiceBrokerStub        func(context.Context, *v1beta1.ServiceBroker, string) (*v1beta1.ServiceBroker, error)
 	createNamespaceServiceBrokerMutex       sync.RWMutex
 	createNamespaceServiceBrokerArgsForCall []struct {
-		arg1 *v1beta1.ServiceBroker
-		arg2 string
+		arg1 context.Context
+		arg2 *v1beta1.ServiceBroker
+		arg3 string
 	}
 	createNamespaceServiceBrokerReturns struct {
 		result1 *v1beta1.ServiceBroker
@@ -38,24 +69,26 @@ type FakeKubernetesAPI struct {
 		result1 *v1beta1.ServiceBroker
 		result2 error
 	}
-	CreateSecretStub        func(*v1.Secret) (*v1.Secret, error)
+	CreateSecretStub        func(context.Context, *v1a.Secret) (*v1a.Secret, error)
 	createSecretMutex       sync.RWMutex
 	createSecretArgsForCall []struct {
-		arg1 *v1.Secret
+		arg1 context.Context
+		arg2 *v1a.Secret
 	}
 	createSecretReturns struct {
-		result1 *v1.Secret
+		result1 *v1a.Secret
 		result2 error
 	}
 	createSecretReturnsOnCall map[int]struct {
-		result1 *v1.Secret
+		result1 *v1a.Secret
 		result2 error
 	}
-	DeleteClusterServiceBrokerStub        func(string, *v1a.DeleteOptions) error
+	DeleteClusterServiceBrokerStub        func(context.Context, string, *v1b.DeleteOptions) error
 	deleteClusterServiceBrokerMutex       sync.RWMutex
 	deleteClusterServiceBrokerArgsForCall []struct {
-		arg1 string
-		arg2 *v1a.DeleteOptions
+		arg1 context.Context
+		arg2 string
+		arg3 *v1b.DeleteOptions
 	}
 	deleteClusterServiceBrokerReturns struct {
 		result1 error
@@ -63,12 +96,26 @@ type FakeKubernetesAPI struct {
 	deleteClusterServiceBrokerReturnsOnCall map[int]struct {
 		result1 error
 	}
-	DeleteNamespaceServiceBrokerStub        func(string, string, *v1a.DeleteOptions) error
+	DeleteLeaseStub        func(context.Context, string, string) error
+	deleteLeaseMutex       sync.RWMutex
+	deleteLeaseArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	deleteLeaseReturns struct {
+		result1 error
+	}
+	deleteLeaseReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DeleteNamespaceServiceBrokerStub        func(context.Context, string, string, *v1b.DeleteOptions) error
 	deleteNamespaceServiceBrokerMutex       sync.RWMutex
 	deleteNamespaceServiceBrokerArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 *v1a.DeleteOptions
+		arg3 string
+		arg4 *v1b.DeleteOptions
 	}
 	deleteNamespaceServiceBrokerReturns struct {
 		result1 error
@@ -76,11 +123,12 @@ type FakeKubernetesAPI struct {
 	deleteNamespaceServiceBrokerReturnsOnCall map[int]struct {
 		result1 error
 	}
-	DeleteSecretStub        func(string, string) error
+	DeleteSecretStub        func(context.Context, string, string) error
 	deleteSecretMutex       sync.RWMutex
 	deleteSecretArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
+		arg3 string
 	}
 	deleteSecretReturns struct {
 		result1 error
@@ -88,10 +136,80 @@ type FakeKubernetesAPI struct {
 	deleteSecretReturnsOnCall map[int]struct {
 		result1 error
 	}
-	RetrieveClusterServiceBrokerByNameStub        func(string) (*v1beta1.ClusterServiceBroker, error)
+	NewLeaseLockStub        func(string, string, string) resourcelock.Interface
+	newLeaseLockMutex       sync.RWMutex
+	newLeaseLockArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}
+	newLeaseLockReturns struct {
+		result1 resourcelock.Interface
+	}
+	newLeaseLockReturnsOnCall map[int]struct {
+		result1 resourcelock.Interface
+	}
+	RetrieveCachedClusterServiceBrokerByNameStub        func(context.Context, string) (*v1beta1.ClusterServiceBroker, error)
+	retrieveCachedClusterServiceBrokerByNameMutex       sync.RWMutex
+	retrieveCachedClusterServiceBrokerByNameArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	retrieveCachedClusterServiceBrokerByNameReturns struct {
+		result1 *v1beta1.ClusterServiceBroker
+		result2 error
+	}
+	retrieveCachedClusterServiceBrokerByNameReturnsOnCall map[int]struct {
+		result1 *v1beta1.ClusterServiceBroker
+		result2 error
+	}
+	RetrieveCachedClusterServiceBrokersStub        func(context.Context) (*v1beta1.ClusterServiceBrokerList, error)
+	retrieveCachedClusterServiceBrokersMutex       sync.RWMutex
+	retrieveCachedClusterServiceBrokersArgsForCall []struct {
+		arg1 context.Context
+	}
+	retrieveCachedClusterServiceBrokersReturns struct {
+		result1 *v1beta1.ClusterServiceBrokerList
+		result2 error
+	}
+	retrieveCachedClusterServiceBrokersReturnsOnCall map[int]struct {
+		result1 *v1beta1.ClusterServiceBrokerList
+		result2 error
+	}
+	RetrieveCachedNamespaceServiceBrokerByNameStub        func(context.Context, string, string) (*v1beta1.ServiceBroker, error)
+	retrieveCachedNamespaceServiceBrokerByNameMutex       sync.RWMutex
+	retrieveCachedNamespaceServiceBrokerByNameArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	retrieveCachedNamespaceServiceBrokerByNameReturns struct {
+		result1 *v1beta1.ServiceBroker
+		result2 error
+	}
+	retrieveCachedNamespaceServiceBrokerByNameReturnsOnCall map[int]struct {
+		result1 *v1beta1.ServiceBroker
+		result2 error
+	}
+	RetrieveCachedNamespaceServiceBrokersStub        func(context.Context, string) (*v1beta1.ServiceBrokerList, error)
+	retrieveCachedNamespaceServiceBrokersMutex       sync.RWMutex
+	retrieveCachedNamespaceServiceBrokersArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	retrieveCachedNamespaceServiceBrokersReturns struct {
+		result1 *v1beta1.ServiceBrokerList
+		result2 error
+	}
+	retrieveCachedNamespaceServiceBrokersReturnsOnCall map[int]struct {
+		result1 *v1beta1.ServiceBrokerList
+		result2 error
+	}
+	RetrieveClusterServiceBrokerByNameStub        func(context.Context, string) (*v1beta1.ClusterServiceBroker, error)
 	retrieveClusterServiceBrokerByNameMutex       sync.RWMutex
 	retrieveClusterServiceBrokerByNameArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	retrieveClusterServiceBrokerByNameReturns struct {
 		result1 *v1beta1.ClusterServiceBroker
@@ -101,9 +219,10 @@ type FakeKubernetesAPI struct {
 		result1 *v1beta1.ClusterServiceBroker
 		result2 error
 	}
-	RetrieveClusterServiceBrokersStub        func() (*v1beta1.ClusterServiceBrokerList, error)
+	RetrieveClusterServiceBrokersStub        func(context.Context) (*v1beta1.ClusterServiceBrokerList, error)
 	retrieveClusterServiceBrokersMutex       sync.RWMutex
 	retrieveClusterServiceBrokersArgsForCall []struct {
+		arg1 context.Context
 	}
 	retrieveClusterServiceBrokersReturns struct {
 		result1 *v1beta1.ClusterServiceBrokerList
@@ -113,11 +232,41 @@ type FakeKubernetesAPI struct {
 		result1 *v1beta1.ClusterServiceBrokerList
 		result2 error
 	}
-	RetrieveNamespaceServiceBrokerByNameStub        func(string, string) (*v1beta1.ServiceBroker, error)
+	RetrieveClusterServicePlansStub        func(context.Context, string) (*v1beta1.ClusterServicePlanList, error)
+	retrieveClusterServicePlansMutex       sync.RWMutex
+	retrieveClusterServicePlansArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	retrieveClusterServicePlansReturns struct {
+		result1 *v1beta1.ClusterServicePlanList
+		result2 error
+	}
+	retrieveClusterServicePlansReturnsOnCall map[int]struct {
+		result1 *v1beta1.ClusterServicePlanList
+		result2 error
+	}
+	RetrieveLeasesStub        func(context.Context, string, string) (*v1.LeaseList, error)
+	retrieveLeasesMutex       sync.RWMutex
+	retrieveLeasesArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	retrieveLeasesReturns struct {
+		result1 *v1.LeaseList
+		result2 error
+	}
+	retrieveLeasesReturnsOnCall map[int]struct {
+		result1 *v1.LeaseList
+		result2 error
+	}
+	RetrieveNamespaceServiceBrokerByNameStub        func(context.Context, string, string) (*v1beta1.ServiceBroker, error)
 	retrieveNamespaceServiceBrokerByNameMutex       sync.RWMutex
 	retrieveNamespaceServiceBrokerByNameArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
+		arg3 string
 	}
 	retrieveNamespaceServiceBrokerByNameReturns struct {
 		result1 *v1beta1.ServiceBroker
@@ -127,10 +276,11 @@ type FakeKubernetesAPI struct {
 		result1 *v1beta1.ServiceBroker
 		result2 error
 	}
-	RetrieveNamespaceServiceBrokersStub        func(string) (*v1beta1.ServiceBrokerList, error)
+	RetrieveNamespaceServiceBrokersStub        func(context.Context, string) (*v1beta1.ServiceBrokerList, error)
 	retrieveNamespaceServiceBrokersMutex       sync.RWMutex
 	retrieveNamespaceServiceBrokersArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	retrieveNamespaceServiceBrokersReturns struct {
 		result1 *v1beta1.ServiceBrokerList
@@ -140,11 +290,66 @@ type FakeKubernetesAPI struct {
 		result1 *v1beta1.ServiceBrokerList
 		result2 error
 	}
-	SyncClusterServiceBrokerStub        func(string, int) error
+	RetrieveNamespaceServicePlansStub        func(context.Context, string, string) (*v1beta1.ServicePlanList, error)
+	retrieveNamespaceServicePlansMutex       sync.RWMutex
+	retrieveNamespaceServicePlansArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	retrieveNamespaceServicePlansReturns struct {
+		result1 *v1beta1.ServicePlanList
+		result2 error
+	}
+	retrieveNamespaceServicePlansReturnsOnCall map[int]struct {
+		result1 *v1beta1.ServicePlanList
+		result2 error
+	}
+	RetrieveSecretStub        func(context.Context, string, string) (*v1a.Secret, error)
+	retrieveSecretMutex       sync.RWMutex
+	retrieveSecretArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	retrieveSecretReturns struct {
+		result1 *v1a.Secret
+		result2 error
+	}
+	retrieveSecretReturnsOnCall map[int]struct {
+		result1 *v1a.Secret
+		result2 error
+	}
+	StartBrokerCacheStub        func(context.Context) error
+	startBrokerCacheMutex       sync.RWMutex
+	startBrokerCacheArgsForCall []struct {
+		arg1 context.Context
+	}
+	startBrokerCacheReturns struct {
+		result1 error
+	}
+	startBrokerCacheReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StartBrokerSyncsStub        func(context.Context, int, workqueue.RateLimiter) error
+	startBrokerSyncsMutex       sync.RWMutex
+	startBrokerSyncsArgsForCall []struct {
+		arg1 context.Context
+		arg2 int
+		arg3 workqueue.RateLimiter
+	}
+	startBrokerSyncsReturns struct {
+		result1 error
+	}
+	startBrokerSyncsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	SyncClusterServiceBrokerStub        func(context.Context, string, int) error
 	syncClusterServiceBrokerMutex       sync.RWMutex
 	syncClusterServiceBrokerArgsForCall []struct {
-		arg1 string
-		arg2 int
+		arg1 context.Context
+		arg2 string
+		arg3 int
 	}
 	syncClusterServiceBrokerReturns struct {
 		result1 error
@@ -152,12 +357,13 @@ type FakeKubernetesAPI struct {
 	syncClusterServiceBrokerReturnsOnCall map[int]struct {
 		result1 error
 	}
-	SyncNamespaceServiceBrokerStub        func(string, string, int) error
+	SyncNamespaceServiceBrokerStub        func(context.Context, string, string, int) error
 	syncNamespaceServiceBrokerMutex       sync.RWMutex
 	syncNamespaceServiceBrokerArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 int
+		arg3 string
+		arg4 int
 	}
 	syncNamespaceServiceBrokerReturns struct {
 		result1 error
@@ -165,10 +371,11 @@ type FakeKubernetesAPI struct {
 	syncNamespaceServiceBrokerReturnsOnCall map[int]struct {
 		result1 error
 	}
-	UpdateClusterServiceBrokerStub        func(*v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error)
+	UpdateClusterServiceBrokerStub        func(context.Context, *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error)
 	updateClusterServiceBrokerMutex       sync.RWMutex
 	updateClusterServiceBrokerArgsForCall []struct {
-		arg1 *v1beta1.ClusterServiceBroker
+		arg1 context.Context
+		arg2 *v1beta1.ClusterServiceBroker
 	}
 	updateClusterServiceBrokerReturns struct {
 		result1 *v1beta1.ClusterServiceBroker
@@ -178,11 +385,26 @@ type FakeKubernetesAPI struct {
 		result1 *v1beta1.ClusterServiceBroker
 		result2 error
 	}
-	UpdateNamespaceServiceBrokerStub        func(*v1beta1.ServiceBroker, string) (*v1beta1.ServiceBroker, error)
+	UpdateLeaseStub        func(context.Context, *v1.Lease) (*v1.Lease, error)
+	updateLeaseMutex       sync.RWMutex
+	updateLeaseArgsForCall []struct {
+		arg1 context.Context
+		arg2 *v1.Lease
+	}
+	updateLeaseReturns struct {
+		result1 *v1.Lease
+		result2 error
+	}
+	updateLeaseReturnsOnCall map[int]struct {
+		result1 *v1.Lease
+		result2 error
+	}
+	UpdateNamespaceServiceBrokerStub        func(context.Context, *v1beta1.ServiceBroker, string) (*v1beta1.ServiceBroker, error)
 	updateNamespaceServiceBrokerMutex       sync.RWMutex
 	updateNamespaceServiceBrokerArgsForCall []struct {
-		arg1 *v1beta1.ServiceBroker
-		arg2 string
+		arg1 context.Context
+		arg2 *v1beta1.ServiceBroker
+		arg3 string
 	}
 	updateNamespaceServiceBrokerReturns struct {
 		result1 *v1beta1.ServiceBroker
@@ -192,38 +414,107 @@ type FakeKubernetesAPI struct {
 		result1 *v1beta1.ServiceBroker
 		result2 error
 	}
-	UpdateServiceBrokerCredentialsStub        func(*v1.Secret) (*v1.Secret, error)
+	UpdateServiceBrokerCredentialsStub        func(context.Context, *v1a.Secret) (*v1a.Secret, error)
 	updateServiceBrokerCredentialsMutex       sync.RWMutex
 	updateServiceBrokerCredentialsArgsForCall []struct {
-		arg1 *v1.Secret
+		arg1 context.Context
+		arg2 *v1a.Secret
 	}
 	updateServiceBrokerCredentialsReturns struct {
-		result1 *v1.Secret
+		result1 *v1a.Secret
 		result2 error
 	}
 	updateServiceBrokerCredentialsReturnsOnCall map[int]struct {
-		result1 *v1.Secret
+		result1 *v1a.Secret
 		result2 error
 	}
+	WatchNamespacesStub        func(context.Context, string, cache.ResourceEventHandler) error
+	watchNamespacesMutex       sync.RWMutex
+	watchNamespacesArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 cache.ResourceEventHandler
+	}
+	watchNamespacesReturns struct {
+		result1 error
+	}
+	watchNamespacesReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeKubernetesAPI) CreateClusterServiceBroker(arg1 *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+func (fake *FakeKubernetesAPI) BrokerCacheSynced() bool {
+	fake.brokerCacheSyncedMutex.Lock()
+	ret, specificReturn := fake.brokerCacheSyncedReturnsOnCall[len(fake.brokerCacheSyncedArgsForCall)]
+	fake.brokerCacheSyncedArgsForCall = append(fake.brokerCacheSyncedArgsForCall, struct {
+	}{})
+	stub := fake.BrokerCacheSyncedStub
+	fakeReturns := fake.brokerCacheSyncedReturns
+	fake.recordInvocation("BrokerCacheSynced", []interface{}{})
+	fake.brokerCacheSyncedMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeKubernetesAPI) BrokerCacheSyncedCallCount() int {
+	fake.brokerCacheSyncedMutex.RLock()
+	defer fake.brokerCacheSyncedMutex.RUnlock()
+	return len(fake.brokerCacheSyncedArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) BrokerCacheSyncedCalls(stub func() bool) {
+	fake.brokerCacheSyncedMutex.Lock()
+	defer fake.brokerCacheSyncedMutex.Unlock()
+	fake.BrokerCacheSyncedStub = stub
+}
+
+func (fake *FakeKubernetesAPI) BrokerCacheSyncedReturns(result1 bool) {
+	fake.brokerCacheSyncedMutex.Lock()
+	defer fake.brokerCacheSyncedMutex.Unlock()
+	fake.BrokerCacheSyncedStub = nil
+	fake.brokerCacheSyncedReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeKubernetesAPI) BrokerCacheSyncedReturnsOnCall(i int, result1 bool) {
+	fake.brokerCacheSyncedMutex.Lock()
+	defer fake.brokerCacheSyncedMutex.Unlock()
+	fake.BrokerCacheSyncedStub = nil
+	if fake.brokerCacheSyncedReturnsOnCall == nil {
+		fake.brokerCacheSyncedReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.brokerCacheSyncedReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeKubernetesAPI) CreateClusterServiceBroker(arg1 context.Context, arg2 *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
 	fake.createClusterServiceBrokerMutex.Lock()
 	ret, specificReturn := fake.createClusterServiceBrokerReturnsOnCall[len(fake.createClusterServiceBrokerArgsForCall)]
 	fake.createClusterServiceBrokerArgsForCall = append(fake.createClusterServiceBrokerArgsForCall, struct {
-		arg1 *v1beta1.ClusterServiceBroker
-	}{arg1})
-	fake.recordInvocation("CreateClusterServiceBroker", []interface{}{arg1})
+		arg1 context.Context
+		arg2 *v1beta1.ClusterServiceBroker
+	}{arg1, arg2})
+	stub := fake.CreateClusterServiceBrokerStub
+	fakeReturns := fake.createClusterServiceBrokerReturns
+	fake.recordInvocation("CreateClusterServiceBroker", []interface{}{arg1, arg2})
 	fake.createClusterServiceBrokerMutex.Unlock()
-	if fake.CreateClusterServiceBrokerStub != nil {
-		return fake.CreateClusterServiceBrokerStub(arg1)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.createClusterServiceBrokerReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -233,17 +524,17 @@ func (fake *FakeKubernetesAPI) CreateClusterServiceBrokerCallCount() int {
 	return len(fake.createClusterServiceBrokerArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) CreateClusterServiceBrokerCalls(stub func(*v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error)) {
+func (fake *FakeKubernetesAPI) CreateClusterServiceBrokerCalls(stub func(context.Context, *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error)) {
 	fake.createClusterServiceBrokerMutex.Lock()
 	defer fake.createClusterServiceBrokerMutex.Unlock()
 	fake.CreateClusterServiceBrokerStub = stub
 }
 
-func (fake *FakeKubernetesAPI) CreateClusterServiceBrokerArgsForCall(i int) *v1beta1.ClusterServiceBroker {
+func (fake *FakeKubernetesAPI) CreateClusterServiceBrokerArgsForCall(i int) (context.Context, *v1beta1.ClusterServiceBroker) {
 	fake.createClusterServiceBrokerMutex.RLock()
 	defer fake.createClusterServiceBrokerMutex.RUnlock()
 	argsForCall := fake.createClusterServiceBrokerArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeKubernetesAPI) CreateClusterServiceBrokerReturns(result1 *v1beta1.ClusterServiceBroker, result2 error) {
@@ -272,22 +563,89 @@ func (fake *FakeKubernetesAPI) CreateClusterServiceBrokerReturnsOnCall(i int, re
 	}{result1, result2}
 }
 
-func (fake *FakeKubernetesAPI) CreateNamespaceServiceBroker(arg1 *v1beta1.ServiceBroker, arg2 string) (*v1beta1.ServiceBroker, error) {
+func (fake *FakeKubernetesAPI) CreateLease(arg1 context.Context, arg2 *v1.Lease) (*v1.Lease, error) {
+	fake.createLeaseMutex.Lock()
+	ret, specificReturn := fake.createLeaseReturnsOnCall[len(fake.createLeaseArgsForCall)]
+	fake.createLeaseArgsForCall = append(fake.createLeaseArgsForCall, struct {
+		arg1 context.Context
+		arg2 *v1.Lease
+	}{arg1, arg2})
+	stub := fake.CreateLeaseStub
+	fakeReturns := fake.createLeaseReturns
+	fake.recordInvocation("CreateLease", []interface{}{arg1, arg2})
+	fake.createLeaseMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKubernetesAPI) CreateLeaseCallCount() int {
+	fake.createLeaseMutex.RLock()
+	defer fake.createLeaseMutex.RUnlock()
+	return len(fake.createLeaseArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) CreateLeaseCalls(stub func(context.Context, *v1.Lease) (*v1.Lease, error)) {
+	fake.createLeaseMutex.Lock()
+	defer fake.createLeaseMutex.Unlock()
+	fake.CreateLeaseStub = stub
+}
+
+func (fake *FakeKubernetesAPI) CreateLeaseArgsForCall(i int) (context.Context, *v1.Lease) {
+	fake.createLeaseMutex.RLock()
+	defer fake.createLeaseMutex.RUnlock()
+	argsForCall := fake.createLeaseArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeKubernetesAPI) CreateLeaseReturns(result1 *v1.Lease, result2 error) {
+	fake.createLeaseMutex.Lock()
+	defer fake.createLeaseMutex.Unlock()
+	fake.CreateLeaseStub = nil
+	fake.createLeaseReturns = struct {
+		result1 *v1.Lease
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) CreateLeaseReturnsOnCall(i int, result1 *v1.Lease, result2 error) {
+	fake.createLeaseMutex.Lock()
+	defer fake.createLeaseMutex.Unlock()
+	fake.CreateLeaseStub = nil
+	if fake.createLeaseReturnsOnCall == nil {
+		fake.createLeaseReturnsOnCall = make(map[int]struct {
+			result1 *v1.Lease
+			result2 error
+		})
+	}
+	fake.createLeaseReturnsOnCall[i] = struct {
+		result1 *v1.Lease
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) CreateNamespaceServiceBroker(arg1 context.Context, arg2 *v1beta1.ServiceBroker, arg3 string) (*v1beta1.ServiceBroker, error) {
 	fake.createNamespaceServiceBrokerMutex.Lock()
 	ret, specificReturn := fake.createNamespaceServiceBrokerReturnsOnCall[len(fake.createNamespaceServiceBrokerArgsForCall)]
 	fake.createNamespaceServiceBrokerArgsForCall = append(fake.createNamespaceServiceBrokerArgsForCall, struct {
-		arg1 *v1beta1.ServiceBroker
-		arg2 string
-	}{arg1, arg2})
-	fake.recordInvocation("CreateNamespaceServiceBroker", []interface{}{arg1, arg2})
+		arg1 context.Context
+		arg2 *v1beta1.ServiceBroker
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.CreateNamespaceServiceBrokerStub
+	fakeReturns := fake.createNamespaceServiceBrokerReturns
+	fake.recordInvocation("CreateNamespaceServiceBroker", []interface{}{arg1, arg2, arg3})
 	fake.createNamespaceServiceBrokerMutex.Unlock()
-	if fake.CreateNamespaceServiceBrokerStub != nil {
-		return fake.CreateNamespaceServiceBrokerStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.createNamespaceServiceBrokerReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -297,17 +655,17 @@ func (fake *FakeKubernetesAPI) CreateNamespaceServiceBrokerCallCount() int {
 	return len(fake.createNamespaceServiceBrokerArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) CreateNamespaceServiceBrokerCalls(stub func(*v1beta1.ServiceBroker, string) (*v1beta1.ServiceBroker, error)) {
+func (fake *FakeKubernetesAPI) CreateNamespaceServiceBrokerCalls(stub func(context.Context, *v1beta1.ServiceBroker, string) (*v1beta1.ServiceBroker, error)) {
 	fake.createNamespaceServiceBrokerMutex.Lock()
 	defer fake.createNamespaceServiceBrokerMutex.Unlock()
 	fake.CreateNamespaceServiceBrokerStub = stub
 }
 
-func (fake *FakeKubernetesAPI) CreateNamespaceServiceBrokerArgsForCall(i int) (*v1beta1.ServiceBroker, string) {
+func (fake *FakeKubernetesAPI) CreateNamespaceServiceBrokerArgsForCall(i int) (context.Context, *v1beta1.ServiceBroker, string) {
 	fake.createNamespaceServiceBrokerMutex.RLock()
 	defer fake.createNamespaceServiceBrokerMutex.RUnlock()
 	argsForCall := fake.createNamespaceServiceBrokerArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeKubernetesAPI) CreateNamespaceServiceBrokerReturns(result1 *v1beta1.ServiceBroker, result2 error) {
@@ -336,21 +694,23 @@ func (fake *FakeKubernetesAPI) CreateNamespaceServiceBrokerReturnsOnCall(i int,
 	}{result1, result2}
 }
 
-func (fake *FakeKubernetesAPI) CreateSecret(arg1 *v1.Secret) (*v1.Secret, error) {
+func (fake *FakeKubernetesAPI) CreateSecret(arg1 context.Context, arg2 *v1a.Secret) (*v1a.Secret, error) {
 	fake.createSecretMutex.Lock()
 	ret, specificReturn := fake.createSecretReturnsOnCall[len(fake.createSecretArgsForCall)]
 	fake.createSecretArgsForCall = append(fake.createSecretArgsForCall, struct {
-		arg1 *v1.Secret
-	}{arg1})
-	fake.recordInvocation("CreateSecret", []interface{}{arg1})
+		arg1 context.Context
+		arg2 *v1a.Secret
+	}{arg1, arg2})
+	stub := fake.CreateSecretStub
+	fakeReturns := fake.createSecretReturns
+	fake.recordInvocation("CreateSecret", []interface{}{arg1, arg2})
 	fake.createSecretMutex.Unlock()
-	if fake.CreateSecretStub != nil {
-		return fake.CreateSecretStub(arg1)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.createSecretReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -360,61 +720,63 @@ func (fake *FakeKubernetesAPI) CreateSecretCallCount() int {
 	return len(fake.createSecretArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) CreateSecretCalls(stub func(*v1.Secret) (*v1.Secret, error)) {
+func (fake *FakeKubernetesAPI) CreateSecretCalls(stub func(context.Context, *v1a.Secret) (*v1a.Secret, error)) {
 	fake.createSecretMutex.Lock()
 	defer fake.createSecretMutex.Unlock()
 	fake.CreateSecretStub = stub
 }
 
-func (fake *FakeKubernetesAPI) CreateSecretArgsForCall(i int) *v1.Secret {
+func (fake *FakeKubernetesAPI) CreateSecretArgsForCall(i int) (context.Context, *v1a.Secret) {
 	fake.createSecretMutex.RLock()
 	defer fake.createSecretMutex.RUnlock()
 	argsForCall := fake.createSecretArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeKubernetesAPI) CreateSecretReturns(result1 *v1.Secret, result2 error) {
+func (fake *FakeKubernetesAPI) CreateSecretReturns(result1 *v1a.Secret, result2 error) {
 	fake.createSecretMutex.Lock()
 	defer fake.createSecretMutex.Unlock()
 	fake.CreateSecretStub = nil
 	fake.createSecretReturns = struct {
-		result1 *v1.Secret
+		result1 *v1a.Secret
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeKubernetesAPI) CreateSecretReturnsOnCall(i int, result1 *v1.Secret, result2 error) {
+func (fake *FakeKubernetesAPI) CreateSecretReturnsOnCall(i int, result1 *v1a.Secret, result2 error) {
 	fake.createSecretMutex.Lock()
 	defer fake.createSecretMutex.Unlock()
 	fake.CreateSecretStub = nil
 	if fake.createSecretReturnsOnCall == nil {
 		fake.createSecretReturnsOnCall = make(map[int]struct {
-			result1 *v1.Secret
+			result1 *v1a.Secret
 			result2 error
 		})
 	}
 	fake.createSecretReturnsOnCall[i] = struct {
-		result1 *v1.Secret
+		result1 *v1a.Secret
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeKubernetesAPI) DeleteClusterServiceBroker(arg1 string, arg2 *v1a.DeleteOptions) error {
+func (fake *FakeKubernetesAPI) DeleteClusterServiceBroker(arg1 context.Context, arg2 string, arg3 *v1b.DeleteOptions) error {
 	fake.deleteClusterServiceBrokerMutex.Lock()
 	ret, specificReturn := fake.deleteClusterServiceBrokerReturnsOnCall[len(fake.deleteClusterServiceBrokerArgsForCall)]
 	fake.deleteClusterServiceBrokerArgsForCall = append(fake.deleteClusterServiceBrokerArgsForCall, struct {
-		arg1 string
-		arg2 *v1a.DeleteOptions
-	}{arg1, arg2})
-	fake.recordInvocation("DeleteClusterServiceBroker", []interface{}{arg1, arg2})
+		arg1 context.Context
+		arg2 string
+		arg3 *v1b.DeleteOptions
+	}{arg1, arg2, arg3})
+	stub := fake.DeleteClusterServiceBrokerStub
+	fakeReturns := fake.deleteClusterServiceBrokerReturns
+	fake.recordInvocation("DeleteClusterServiceBroker", []interface{}{arg1, arg2, arg3})
 	fake.deleteClusterServiceBrokerMutex.Unlock()
-	if fake.DeleteClusterServiceBrokerStub != nil {
-		return fake.DeleteClusterServiceBrokerStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.deleteClusterServiceBrokerReturns
 	return fakeReturns.result1
 }
 
@@ -424,17 +786,17 @@ func (fake *FakeKubernetesAPI) DeleteClusterServiceBrokerCallCount() int {
 	return len(fake.deleteClusterServiceBrokerArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) DeleteClusterServiceBrokerCalls(stub func(string, *v1a.DeleteOptions) error) {
+func (fake *FakeKubernetesAPI) DeleteClusterServiceBrokerCalls(stub func(context.Context, string, *v1b.DeleteOptions) error) {
 	fake.deleteClusterServiceBrokerMutex.Lock()
 	defer fake.deleteClusterServiceBrokerMutex.Unlock()
 	fake.DeleteClusterServiceBrokerStub = stub
 }
 
-func (fake *FakeKubernetesAPI) DeleteClusterServiceBrokerArgsForCall(i int) (string, *v1a.DeleteOptions) {
+func (fake *FakeKubernetesAPI) DeleteClusterServiceBrokerArgsForCall(i int) (context.Context, string, *v1b.DeleteOptions) {
 	fake.deleteClusterServiceBrokerMutex.RLock()
 	defer fake.deleteClusterServiceBrokerMutex.RUnlock()
 	argsForCall := fake.deleteClusterServiceBrokerArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeKubernetesAPI) DeleteClusterServiceBrokerReturns(result1 error) {
@@ -460,23 +822,88 @@ func (fake *FakeKubernetesAPI) DeleteClusterServiceBrokerReturnsOnCall(i int, re
 	}{result1}
 }
 
-func (fake *FakeKubernetesAPI) DeleteNamespaceServiceBroker(arg1 string, arg2 string, arg3 *v1a.DeleteOptions) error {
+func (fake *FakeKubernetesAPI) DeleteLease(arg1 context.Context, arg2 string, arg3 string) error {
+	fake.deleteLeaseMutex.Lock()
+	ret, specificReturn := fake.deleteLeaseReturnsOnCall[len(fake.deleteLeaseArgsForCall)]
+	fake.deleteLeaseArgsForCall = append(fake.deleteLeaseArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.DeleteLeaseStub
+	fakeReturns := fake.deleteLeaseReturns
+	fake.recordInvocation("DeleteLease", []interface{}{arg1, arg2, arg3})
+	fake.deleteLeaseMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeKubernetesAPI) DeleteLeaseCallCount() int {
+	fake.deleteLeaseMutex.RLock()
+	defer fake.deleteLeaseMutex.RUnlock()
+	return len(fake.deleteLeaseArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) DeleteLeaseCalls(stub func(context.Context, string, string) error) {
+	fake.deleteLeaseMutex.Lock()
+	defer fake.deleteLeaseMutex.Unlock()
+	fake.DeleteLeaseStub = stub
+}
+
+func (fake *FakeKubernetesAPI) DeleteLeaseArgsForCall(i int) (context.Context, string, string) {
+	fake.deleteLeaseMutex.RLock()
+	defer fake.deleteLeaseMutex.RUnlock()
+	argsForCall := fake.deleteLeaseArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeKubernetesAPI) DeleteLeaseReturns(result1 error) {
+	fake.deleteLeaseMutex.Lock()
+	defer fake.deleteLeaseMutex.Unlock()
+	fake.DeleteLeaseStub = nil
+	fake.deleteLeaseReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeKubernetesAPI) DeleteLeaseReturnsOnCall(i int, result1 error) {
+	fake.deleteLeaseMutex.Lock()
+	defer fake.deleteLeaseMutex.Unlock()
+	fake.DeleteLeaseStub = nil
+	if fake.deleteLeaseReturnsOnCall == nil {
+		fake.deleteLeaseReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteLeaseReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeKubernetesAPI) DeleteNamespaceServiceBroker(arg1 context.Context, arg2 string, arg3 string, arg4 *v1b.DeleteOptions) error {
 	fake.deleteNamespaceServiceBrokerMutex.Lock()
 	ret, specificReturn := fake.deleteNamespaceServiceBrokerReturnsOnCall[len(fake.deleteNamespaceServiceBrokerArgsForCall)]
 	fake.deleteNamespaceServiceBrokerArgsForCall = append(fake.deleteNamespaceServiceBrokerArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 *v1a.DeleteOptions
-	}{arg1, arg2, arg3})
-	fake.recordInvocation("DeleteNamespaceServiceBroker", []interface{}{arg1, arg2, arg3})
+		arg3 string
+		arg4 *v1b.DeleteOptions
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.DeleteNamespaceServiceBrokerStub
+	fakeReturns := fake.deleteNamespaceServiceBrokerReturns
+	fake.recordInvocation("DeleteNamespaceServiceBroker", []interface{}{arg1, arg2, arg3, arg4})
 	fake.deleteNamespaceServiceBrokerMutex.Unlock()
-	if fake.DeleteNamespaceServiceBrokerStub != nil {
-		return fake.DeleteNamespaceServiceBrokerStub(arg1, arg2, arg3)
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.deleteNamespaceServiceBrokerReturns
 	return fakeReturns.result1
 }
 
@@ -486,17 +913,17 @@ func (fake *FakeKubernetesAPI) DeleteNamespaceServiceBrokerCallCount() int {
 	return len(fake.deleteNamespaceServiceBrokerArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) DeleteNamespaceServiceBrokerCalls(stub func(string, string, *v1a.DeleteOptions) error) {
+func (fake *FakeKubernetesAPI) DeleteNamespaceServiceBrokerCalls(stub func(context.Context, string, string, *v1b.DeleteOptions) error) {
 	fake.deleteNamespaceServiceBrokerMutex.Lock()
 	defer fake.deleteNamespaceServiceBrokerMutex.Unlock()
 	fake.DeleteNamespaceServiceBrokerStub = stub
 }
 
-func (fake *FakeKubernetesAPI) DeleteNamespaceServiceBrokerArgsForCall(i int) (string, string, *v1a.DeleteOptions) {
+func (fake *FakeKubernetesAPI) DeleteNamespaceServiceBrokerArgsForCall(i int) (context.Context, string, string, *v1b.DeleteOptions) {
 	fake.deleteNamespaceServiceBrokerMutex.RLock()
 	defer fake.deleteNamespaceServiceBrokerMutex.RUnlock()
 	argsForCall := fake.deleteNamespaceServiceBrokerArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeKubernetesAPI) DeleteNamespaceServiceBrokerReturns(result1 error) {
@@ -522,22 +949,24 @@ func (fake *FakeKubernetesAPI) DeleteNamespaceServiceBrokerReturnsOnCall(i int,
 	}{result1}
 }
 
-func (fake *FakeKubernetesAPI) DeleteSecret(arg1 string, arg2 string) error {
+func (fake *FakeKubernetesAPI) DeleteSecret(arg1 context.Context, arg2 string, arg3 string) error {
 	fake.deleteSecretMutex.Lock()
 	ret, specificReturn := fake.deleteSecretReturnsOnCall[len(fake.deleteSecretArgsForCall)]
 	fake.deleteSecretArgsForCall = append(fake.deleteSecretArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-	}{arg1, arg2})
-	fake.recordInvocation("DeleteSecret", []interface{}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.DeleteSecretStub
+	fakeReturns := fake.deleteSecretReturns
+	fake.recordInvocation("DeleteSecret", []interface{}{arg1, arg2, arg3})
 	fake.deleteSecretMutex.Unlock()
-	if fake.DeleteSecretStub != nil {
-		return fake.DeleteSecretStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.deleteSecretReturns
 	return fakeReturns.result1
 }
 
@@ -547,17 +976,17 @@ func (fake *FakeKubernetesAPI) DeleteSecretCallCount() int {
 	return len(fake.deleteSecretArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) DeleteSecretCalls(stub func(string, string) error) {
+func (fake *FakeKubernetesAPI) DeleteSecretCalls(stub func(context.Context, string, string) error) {
 	fake.deleteSecretMutex.Lock()
 	defer fake.deleteSecretMutex.Unlock()
 	fake.DeleteSecretStub = stub
 }
 
-func (fake *FakeKubernetesAPI) DeleteSecretArgsForCall(i int) (string, string) {
+func (fake *FakeKubernetesAPI) DeleteSecretArgsForCall(i int) (context.Context, string, string) {
 	fake.deleteSecretMutex.RLock()
 	defer fake.deleteSecretMutex.RUnlock()
 	argsForCall := fake.deleteSecretArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeKubernetesAPI) DeleteSecretReturns(result1 error) {
@@ -583,50 +1012,375 @@ func (fake *FakeKubernetesAPI) DeleteSecretReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokerByName(arg1 string) (*v1beta1.ClusterServiceBroker, error) {
-	fake.retrieveClusterServiceBrokerByNameMutex.Lock()
-	ret, specificReturn := fake.retrieveClusterServiceBrokerByNameReturnsOnCall[len(fake.retrieveClusterServiceBrokerByNameArgsForCall)]
-	fake.retrieveClusterServiceBrokerByNameArgsForCall = append(fake.retrieveClusterServiceBrokerByNameArgsForCall, struct {
+func (fake *FakeKubernetesAPI) NewLeaseLock(arg1 string, arg2 string, arg3 string) resourcelock.Interface {
+	fake.newLeaseLockMutex.Lock()
+	ret, specificReturn := fake.newLeaseLockReturnsOnCall[len(fake.newLeaseLockArgsForCall)]
+	fake.newLeaseLockArgsForCall = append(fake.newLeaseLockArgsForCall, struct {
 		arg1 string
-	}{arg1})
-	fake.recordInvocation("RetrieveClusterServiceBrokerByName", []interface{}{arg1})
-	fake.retrieveClusterServiceBrokerByNameMutex.Unlock()
-	if fake.RetrieveClusterServiceBrokerByNameStub != nil {
-		return fake.RetrieveClusterServiceBrokerByNameStub(arg1)
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.NewLeaseLockStub
+	fakeReturns := fake.newLeaseLockReturns
+	fake.recordInvocation("NewLeaseLock", []interface{}{arg1, arg2, arg3})
+	fake.newLeaseLockMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
-		return ret.result1, ret.result2
+		return ret.result1
 	}
-	fakeReturns := fake.retrieveClusterServiceBrokerByNameReturns
-	return fakeReturns.result1, fakeReturns.result2
+	return fakeReturns.result1
 }
 
-func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokerByNameCallCount() int {
-	fake.retrieveClusterServiceBrokerByNameMutex.RLock()
-	defer fake.retrieveClusterServiceBrokerByNameMutex.RUnlock()
-	return len(fake.retrieveClusterServiceBrokerByNameArgsForCall)
+func (fake *FakeKubernetesAPI) NewLeaseLockCallCount() int {
+	fake.newLeaseLockMutex.RLock()
+	defer fake.newLeaseLockMutex.RUnlock()
+	return len(fake.newLeaseLockArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokerByNameCalls(stub func(string) (*v1beta1.ClusterServiceBroker, error)) {
-	fake.retrieveClusterServiceBrokerByNameMutex.Lock()
-	defer fake.retrieveClusterServiceBrokerByNameMutex.Unlock()
-	fake.RetrieveClusterServiceBrokerByNameStub = stub
+func (fake *FakeKubernetesAPI) NewLeaseLockCalls(stub func(string, string, string) resourcelock.Interface) {
+	fake.newLeaseLockMutex.Lock()
+	defer fake.newLeaseLockMutex.Unlock()
+	fake.NewLeaseLockStub = stub
 }
 
-func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokerByNameArgsForCall(i int) string {
-	fake.retrieveClusterServiceBrokerByNameMutex.RLock()
-	defer fake.retrieveClusterServiceBrokerByNameMutex.RUnlock()
-	argsForCall := fake.retrieveClusterServiceBrokerByNameArgsForCall[i]
-	return argsForCall.arg1
+func (fake *FakeKubernetesAPI) NewLeaseLockArgsForCall(i int) (string, string, string) {
+	fake.newLeaseLockMutex.RLock()
+	defer fake.newLeaseLockMutex.RUnlock()
+	argsForCall := fake.newLeaseLockArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
-func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokerByNameReturns(result1 *v1beta1.ClusterServiceBroker, result2 error) {
-	fake.retrieveClusterServiceBrokerByNameMutex.Lock()
-	defer fake.retrieveClusterServiceBrokerByNameMutex.Unlock()
-	fake.RetrieveClusterServiceBrokerByNameStub = nil
-	fake.retrieveClusterServiceBrokerByNameReturns = struct {
-		result1 *v1beta1.ClusterServiceBroker
-		result2 error
+func (fake *FakeKubernetesAPI) NewLeaseLockReturns(result1 resourcelock.Interface) {
+	fake.newLeaseLockMutex.Lock()
+	defer fake.newLeaseLockMutex.Unlock()
+	fake.NewLeaseLockStub = nil
+	fake.newLeaseLockReturns = struct {
+		result1 resourcelock.Interface
+	}{result1}
+}
+
+func (fake *FakeKubernetesAPI) NewLeaseLockReturnsOnCall(i int, result1 resourcelock.Interface) {
+	fake.newLeaseLockMutex.Lock()
+	defer fake.newLeaseLockMutex.Unlock()
+	fake.NewLeaseLockStub = nil
+	if fake.newLeaseLockReturnsOnCall == nil {
+		fake.newLeaseLockReturnsOnCall = make(map[int]struct {
+			result1 resourcelock.Interface
+		})
+	}
+	fake.newLeaseLockReturnsOnCall[i] = struct {
+		result1 resourcelock.Interface
+	}{result1}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedClusterServiceBrokerByName(arg1 context.Context, arg2 string) (*v1beta1.ClusterServiceBroker, error) {
+	fake.retrieveCachedClusterServiceBrokerByNameMutex.Lock()
+	ret, specificReturn := fake.retrieveCachedClusterServiceBrokerByNameReturnsOnCall[len(fake.retrieveCachedClusterServiceBrokerByNameArgsForCall)]
+	fake.retrieveCachedClusterServiceBrokerByNameArgsForCall = append(fake.retrieveCachedClusterServiceBrokerByNameArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.RetrieveCachedClusterServiceBrokerByNameStub
+	fakeReturns := fake.retrieveCachedClusterServiceBrokerByNameReturns
+	fake.recordInvocation("RetrieveCachedClusterServiceBrokerByName", []interface{}{arg1, arg2})
+	fake.retrieveCachedClusterServiceBrokerByNameMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedClusterServiceBrokerByNameCallCount() int {
+	fake.retrieveCachedClusterServiceBrokerByNameMutex.RLock()
+	defer fake.retrieveCachedClusterServiceBrokerByNameMutex.RUnlock()
+	return len(fake.retrieveCachedClusterServiceBrokerByNameArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedClusterServiceBrokerByNameCalls(stub func(context.Context, string) (*v1beta1.ClusterServiceBroker, error)) {
+	fake.retrieveCachedClusterServiceBrokerByNameMutex.Lock()
+	defer fake.retrieveCachedClusterServiceBrokerByNameMutex.Unlock()
+	fake.RetrieveCachedClusterServiceBrokerByNameStub = stub
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedClusterServiceBrokerByNameArgsForCall(i int) (context.Context, string) {
+	fake.retrieveCachedClusterServiceBrokerByNameMutex.RLock()
+	defer fake.retrieveCachedClusterServiceBrokerByNameMutex.RUnlock()
+	argsForCall := fake.retrieveCachedClusterServiceBrokerByNameArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedClusterServiceBrokerByNameReturns(result1 *v1beta1.ClusterServiceBroker, result2 error) {
+	fake.retrieveCachedClusterServiceBrokerByNameMutex.Lock()
+	defer fake.retrieveCachedClusterServiceBrokerByNameMutex.Unlock()
+	fake.RetrieveCachedClusterServiceBrokerByNameStub = nil
+	fake.retrieveCachedClusterServiceBrokerByNameReturns = struct {
+		result1 *v1beta1.ClusterServiceBroker
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedClusterServiceBrokerByNameReturnsOnCall(i int, result1 *v1beta1.ClusterServiceBroker, result2 error) {
+	fake.retrieveCachedClusterServiceBrokerByNameMutex.Lock()
+	defer fake.retrieveCachedClusterServiceBrokerByNameMutex.Unlock()
+	fake.RetrieveCachedClusterServiceBrokerByNameStub = nil
+	if fake.retrieveCachedClusterServiceBrokerByNameReturnsOnCall == nil {
+		fake.retrieveCachedClusterServiceBrokerByNameReturnsOnCall = make(map[int]struct {
+			result1 *v1beta1.ClusterServiceBroker
+			result2 error
+		})
+	}
+	fake.retrieveCachedClusterServiceBrokerByNameReturnsOnCall[i] = struct {
+		result1 *v1beta1.ClusterServiceBroker
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedClusterServiceBrokers(arg1 context.Context) (*v1beta1.ClusterServiceBrokerList, error) {
+	fake.retrieveCachedClusterServiceBrokersMutex.Lock()
+	ret, specificReturn := fake.retrieveCachedClusterServiceBrokersReturnsOnCall[len(fake.retrieveCachedClusterServiceBrokersArgsForCall)]
+	fake.retrieveCachedClusterServiceBrokersArgsForCall = append(fake.retrieveCachedClusterServiceBrokersArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.RetrieveCachedClusterServiceBrokersStub
+	fakeReturns := fake.retrieveCachedClusterServiceBrokersReturns
+	fake.recordInvocation("RetrieveCachedClusterServiceBrokers", []interface{}{arg1})
+	fake.retrieveCachedClusterServiceBrokersMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedClusterServiceBrokersCallCount() int {
+	fake.retrieveCachedClusterServiceBrokersMutex.RLock()
+	defer fake.retrieveCachedClusterServiceBrokersMutex.RUnlock()
+	return len(fake.retrieveCachedClusterServiceBrokersArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedClusterServiceBrokersCalls(stub func(context.Context) (*v1beta1.ClusterServiceBrokerList, error)) {
+	fake.retrieveCachedClusterServiceBrokersMutex.Lock()
+	defer fake.retrieveCachedClusterServiceBrokersMutex.Unlock()
+	fake.RetrieveCachedClusterServiceBrokersStub = stub
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedClusterServiceBrokersArgsForCall(i int) context.Context {
+	fake.retrieveCachedClusterServiceBrokersMutex.RLock()
+	defer fake.retrieveCachedClusterServiceBrokersMutex.RUnlock()
+	argsForCall := fake.retrieveCachedClusterServiceBrokersArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedClusterServiceBrokersReturns(result1 *v1beta1.ClusterServiceBrokerList, result2 error) {
+	fake.retrieveCachedClusterServiceBrokersMutex.Lock()
+	defer fake.retrieveCachedClusterServiceBrokersMutex.Unlock()
+	fake.RetrieveCachedClusterServiceBrokersStub = nil
+	fake.retrieveCachedClusterServiceBrokersReturns = struct {
+		result1 *v1beta1.ClusterServiceBrokerList
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedClusterServiceBrokersReturnsOnCall(i int, result1 *v1beta1.ClusterServiceBrokerList, result2 error) {
+	fake.retrieveCachedClusterServiceBrokersMutex.Lock()
+	defer fake.retrieveCachedClusterServiceBrokersMutex.Unlock()
+	fake.RetrieveCachedClusterServiceBrokersStub = nil
+	if fake.retrieveCachedClusterServiceBrokersReturnsOnCall == nil {
+		fake.retrieveCachedClusterServiceBrokersReturnsOnCall = make(map[int]struct {
+			result1 *v1beta1.ClusterServiceBrokerList
+			result2 error
+		})
+	}
+	fake.retrieveCachedClusterServiceBrokersReturnsOnCall[i] = struct {
+		result1 *v1beta1.ClusterServiceBrokerList
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedNamespaceServiceBrokerByName(arg1 context.Context, arg2 string, arg3 string) (*v1beta1.ServiceBroker, error) {
+	fake.retrieveCachedNamespaceServiceBrokerByNameMutex.Lock()
+	ret, specificReturn := fake.retrieveCachedNamespaceServiceBrokerByNameReturnsOnCall[len(fake.retrieveCachedNamespaceServiceBrokerByNameArgsForCall)]
+	fake.retrieveCachedNamespaceServiceBrokerByNameArgsForCall = append(fake.retrieveCachedNamespaceServiceBrokerByNameArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.RetrieveCachedNamespaceServiceBrokerByNameStub
+	fakeReturns := fake.retrieveCachedNamespaceServiceBrokerByNameReturns
+	fake.recordInvocation("RetrieveCachedNamespaceServiceBrokerByName", []interface{}{arg1, arg2, arg3})
+	fake.retrieveCachedNamespaceServiceBrokerByNameMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedNamespaceServiceBrokerByNameCallCount() int {
+	fake.retrieveCachedNamespaceServiceBrokerByNameMutex.RLock()
+	defer fake.retrieveCachedNamespaceServiceBrokerByNameMutex.RUnlock()
+	return len(fake.retrieveCachedNamespaceServiceBrokerByNameArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedNamespaceServiceBrokerByNameCalls(stub func(context.Context, string, string) (*v1beta1.ServiceBroker, error)) {
+	fake.retrieveCachedNamespaceServiceBrokerByNameMutex.Lock()
+	defer fake.retrieveCachedNamespaceServiceBrokerByNameMutex.Unlock()
+	fake.RetrieveCachedNamespaceServiceBrokerByNameStub = stub
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedNamespaceServiceBrokerByNameArgsForCall(i int) (context.Context, string, string) {
+	fake.retrieveCachedNamespaceServiceBrokerByNameMutex.RLock()
+	defer fake.retrieveCachedNamespaceServiceBrokerByNameMutex.RUnlock()
+	argsForCall := fake.retrieveCachedNamespaceServiceBrokerByNameArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedNamespaceServiceBrokerByNameReturns(result1 *v1beta1.ServiceBroker, result2 error) {
+	fake.retrieveCachedNamespaceServiceBrokerByNameMutex.Lock()
+	defer fake.retrieveCachedNamespaceServiceBrokerByNameMutex.Unlock()
+	fake.RetrieveCachedNamespaceServiceBrokerByNameStub = nil
+	fake.retrieveCachedNamespaceServiceBrokerByNameReturns = struct {
+		result1 *v1beta1.ServiceBroker
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedNamespaceServiceBrokerByNameReturnsOnCall(i int, result1 *v1beta1.ServiceBroker, result2 error) {
+	fake.retrieveCachedNamespaceServiceBrokerByNameMutex.Lock()
+	defer fake.retrieveCachedNamespaceServiceBrokerByNameMutex.Unlock()
+	fake.RetrieveCachedNamespaceServiceBrokerByNameStub = nil
+	if fake.retrieveCachedNamespaceServiceBrokerByNameReturnsOnCall == nil {
+		fake.retrieveCachedNamespaceServiceBrokerByNameReturnsOnCall = make(map[int]struct {
+			result1 *v1beta1.ServiceBroker
+			result2 error
+		})
+	}
+	fake.retrieveCachedNamespaceServiceBrokerByNameReturnsOnCall[i] = struct {
+		result1 *v1beta1.ServiceBroker
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedNamespaceServiceBrokers(arg1 context.Context, arg2 string) (*v1beta1.ServiceBrokerList, error) {
+	fake.retrieveCachedNamespaceServiceBrokersMutex.Lock()
+	ret, specificReturn := fake.retrieveCachedNamespaceServiceBrokersReturnsOnCall[len(fake.retrieveCachedNamespaceServiceBrokersArgsForCall)]
+	fake.retrieveCachedNamespaceServiceBrokersArgsForCall = append(fake.retrieveCachedNamespaceServiceBrokersArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.RetrieveCachedNamespaceServiceBrokersStub
+	fakeReturns := fake.retrieveCachedNamespaceServiceBrokersReturns
+	fake.recordInvocation("RetrieveCachedNamespaceServiceBrokers", []interface{}{arg1, arg2})
+	fake.retrieveCachedNamespaceServiceBrokersMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedNamespaceServiceBrokersCallCount() int {
+	fake.retrieveCachedNamespaceServiceBrokersMutex.RLock()
+	defer fake.retrieveCachedNamespaceServiceBrokersMutex.RUnlock()
+	return len(fake.retrieveCachedNamespaceServiceBrokersArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedNamespaceServiceBrokersCalls(stub func(context.Context, string) (*v1beta1.ServiceBrokerList, error)) {
+	fake.retrieveCachedNamespaceServiceBrokersMutex.Lock()
+	defer fake.retrieveCachedNamespaceServiceBrokersMutex.Unlock()
+	fake.RetrieveCachedNamespaceServiceBrokersStub = stub
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedNamespaceServiceBrokersArgsForCall(i int) (context.Context, string) {
+	fake.retrieveCachedNamespaceServiceBrokersMutex.RLock()
+	defer fake.retrieveCachedNamespaceServiceBrokersMutex.RUnlock()
+	argsForCall := fake.retrieveCachedNamespaceServiceBrokersArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedNamespaceServiceBrokersReturns(result1 *v1beta1.ServiceBrokerList, result2 error) {
+	fake.retrieveCachedNamespaceServiceBrokersMutex.Lock()
+	defer fake.retrieveCachedNamespaceServiceBrokersMutex.Unlock()
+	fake.RetrieveCachedNamespaceServiceBrokersStub = nil
+	fake.retrieveCachedNamespaceServiceBrokersReturns = struct {
+		result1 *v1beta1.ServiceBrokerList
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveCachedNamespaceServiceBrokersReturnsOnCall(i int, result1 *v1beta1.ServiceBrokerList, result2 error) {
+	fake.retrieveCachedNamespaceServiceBrokersMutex.Lock()
+	defer fake.retrieveCachedNamespaceServiceBrokersMutex.Unlock()
+	fake.RetrieveCachedNamespaceServiceBrokersStub = nil
+	if fake.retrieveCachedNamespaceServiceBrokersReturnsOnCall == nil {
+		fake.retrieveCachedNamespaceServiceBrokersReturnsOnCall = make(map[int]struct {
+			result1 *v1beta1.ServiceBrokerList
+			result2 error
+		})
+	}
+	fake.retrieveCachedNamespaceServiceBrokersReturnsOnCall[i] = struct {
+		result1 *v1beta1.ServiceBrokerList
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokerByName(arg1 context.Context, arg2 string) (*v1beta1.ClusterServiceBroker, error) {
+	fake.retrieveClusterServiceBrokerByNameMutex.Lock()
+	ret, specificReturn := fake.retrieveClusterServiceBrokerByNameReturnsOnCall[len(fake.retrieveClusterServiceBrokerByNameArgsForCall)]
+	fake.retrieveClusterServiceBrokerByNameArgsForCall = append(fake.retrieveClusterServiceBrokerByNameArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.RetrieveClusterServiceBrokerByNameStub
+	fakeReturns := fake.retrieveClusterServiceBrokerByNameReturns
+	fake.recordInvocation("RetrieveClusterServiceBrokerByName", []interface{}{arg1, arg2})
+	fake.retrieveClusterServiceBrokerByNameMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokerByNameCallCount() int {
+	fake.retrieveClusterServiceBrokerByNameMutex.RLock()
+	defer fake.retrieveClusterServiceBrokerByNameMutex.RUnlock()
+	return len(fake.retrieveClusterServiceBrokerByNameArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokerByNameCalls(stub func(context.Context, string) (*v1beta1.ClusterServiceBroker, error)) {
+	fake.retrieveClusterServiceBrokerByNameMutex.Lock()
+	defer fake.retrieveClusterServiceBrokerByNameMutex.Unlock()
+	fake.RetrieveClusterServiceBrokerByNameStub = stub
+}
+
+func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokerByNameArgsForCall(i int) (context.Context, string) {
+	fake.retrieveClusterServiceBrokerByNameMutex.RLock()
+	defer fake.retrieveClusterServiceBrokerByNameMutex.RUnlock()
+	argsForCall := fake.retrieveClusterServiceBrokerByNameArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokerByNameReturns(result1 *v1beta1.ClusterServiceBroker, result2 error) {
+	fake.retrieveClusterServiceBrokerByNameMutex.Lock()
+	defer fake.retrieveClusterServiceBrokerByNameMutex.Unlock()
+	fake.RetrieveClusterServiceBrokerByNameStub = nil
+	fake.retrieveClusterServiceBrokerByNameReturns = struct {
+		result1 *v1beta1.ClusterServiceBroker
+		result2 error
 	}{result1, result2}
 }
 
@@ -646,20 +1400,22 @@ func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokerByNameReturnsOnCall(i
 	}{result1, result2}
 }
 
-func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokers() (*v1beta1.ClusterServiceBrokerList, error) {
+func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokers(arg1 context.Context) (*v1beta1.ClusterServiceBrokerList, error) {
 	fake.retrieveClusterServiceBrokersMutex.Lock()
 	ret, specificReturn := fake.retrieveClusterServiceBrokersReturnsOnCall[len(fake.retrieveClusterServiceBrokersArgsForCall)]
 	fake.retrieveClusterServiceBrokersArgsForCall = append(fake.retrieveClusterServiceBrokersArgsForCall, struct {
-	}{})
-	fake.recordInvocation("RetrieveClusterServiceBrokers", []interface{}{})
+		arg1 context.Context
+	}{arg1})
+	stub := fake.RetrieveClusterServiceBrokersStub
+	fakeReturns := fake.retrieveClusterServiceBrokersReturns
+	fake.recordInvocation("RetrieveClusterServiceBrokers", []interface{}{arg1})
 	fake.retrieveClusterServiceBrokersMutex.Unlock()
-	if fake.RetrieveClusterServiceBrokersStub != nil {
-		return fake.RetrieveClusterServiceBrokersStub()
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.retrieveClusterServiceBrokersReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -669,12 +1425,19 @@ func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokersCallCount() int {
 	return len(fake.retrieveClusterServiceBrokersArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokersCalls(stub func() (*v1beta1.ClusterServiceBrokerList, error)) {
+func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokersCalls(stub func(context.Context) (*v1beta1.ClusterServiceBrokerList, error)) {
 	fake.retrieveClusterServiceBrokersMutex.Lock()
 	defer fake.retrieveClusterServiceBrokersMutex.Unlock()
 	fake.RetrieveClusterServiceBrokersStub = stub
 }
 
+func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokersArgsForCall(i int) context.Context {
+	fake.retrieveClusterServiceBrokersMutex.RLock()
+	defer fake.retrieveClusterServiceBrokersMutex.RUnlock()
+	argsForCall := fake.retrieveClusterServiceBrokersArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokersReturns(result1 *v1beta1.ClusterServiceBrokerList, result2 error) {
 	fake.retrieveClusterServiceBrokersMutex.Lock()
 	defer fake.retrieveClusterServiceBrokersMutex.Unlock()
@@ -701,22 +1464,155 @@ func (fake *FakeKubernetesAPI) RetrieveClusterServiceBrokersReturnsOnCall(i int,
 	}{result1, result2}
 }
 
-func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokerByName(arg1 string, arg2 string) (*v1beta1.ServiceBroker, error) {
+func (fake *FakeKubernetesAPI) RetrieveClusterServicePlans(arg1 context.Context, arg2 string) (*v1beta1.ClusterServicePlanList, error) {
+	fake.retrieveClusterServicePlansMutex.Lock()
+	ret, specificReturn := fake.retrieveClusterServicePlansReturnsOnCall[len(fake.retrieveClusterServicePlansArgsForCall)]
+	fake.retrieveClusterServicePlansArgsForCall = append(fake.retrieveClusterServicePlansArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.RetrieveClusterServicePlansStub
+	fakeReturns := fake.retrieveClusterServicePlansReturns
+	fake.recordInvocation("RetrieveClusterServicePlans", []interface{}{arg1, arg2})
+	fake.retrieveClusterServicePlansMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveClusterServicePlansCallCount() int {
+	fake.retrieveClusterServicePlansMutex.RLock()
+	defer fake.retrieveClusterServicePlansMutex.RUnlock()
+	return len(fake.retrieveClusterServicePlansArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) RetrieveClusterServicePlansCalls(stub func(context.Context, string) (*v1beta1.ClusterServicePlanList, error)) {
+	fake.retrieveClusterServicePlansMutex.Lock()
+	defer fake.retrieveClusterServicePlansMutex.Unlock()
+	fake.RetrieveClusterServicePlansStub = stub
+}
+
+func (fake *FakeKubernetesAPI) RetrieveClusterServicePlansArgsForCall(i int) (context.Context, string) {
+	fake.retrieveClusterServicePlansMutex.RLock()
+	defer fake.retrieveClusterServicePlansMutex.RUnlock()
+	argsForCall := fake.retrieveClusterServicePlansArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveClusterServicePlansReturns(result1 *v1beta1.ClusterServicePlanList, result2 error) {
+	fake.retrieveClusterServicePlansMutex.Lock()
+	defer fake.retrieveClusterServicePlansMutex.Unlock()
+	fake.RetrieveClusterServicePlansStub = nil
+	fake.retrieveClusterServicePlansReturns = struct {
+		result1 *v1beta1.ClusterServicePlanList
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveClusterServicePlansReturnsOnCall(i int, result1 *v1beta1.ClusterServicePlanList, result2 error) {
+	fake.retrieveClusterServicePlansMutex.Lock()
+	defer fake.retrieveClusterServicePlansMutex.Unlock()
+	fake.RetrieveClusterServicePlansStub = nil
+	if fake.retrieveClusterServicePlansReturnsOnCall == nil {
+		fake.retrieveClusterServicePlansReturnsOnCall = make(map[int]struct {
+			result1 *v1beta1.ClusterServicePlanList
+			result2 error
+		})
+	}
+	fake.retrieveClusterServicePlansReturnsOnCall[i] = struct {
+		result1 *v1beta1.ClusterServicePlanList
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveLeases(arg1 context.Context, arg2 string, arg3 string) (*v1.LeaseList, error) {
+	fake.retrieveLeasesMutex.Lock()
+	ret, specificReturn := fake.retrieveLeasesReturnsOnCall[len(fake.retrieveLeasesArgsForCall)]
+	fake.retrieveLeasesArgsForCall = append(fake.retrieveLeasesArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.RetrieveLeasesStub
+	fakeReturns := fake.retrieveLeasesReturns
+	fake.recordInvocation("RetrieveLeases", []interface{}{arg1, arg2, arg3})
+	fake.retrieveLeasesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveLeasesCallCount() int {
+	fake.retrieveLeasesMutex.RLock()
+	defer fake.retrieveLeasesMutex.RUnlock()
+	return len(fake.retrieveLeasesArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) RetrieveLeasesCalls(stub func(context.Context, string, string) (*v1.LeaseList, error)) {
+	fake.retrieveLeasesMutex.Lock()
+	defer fake.retrieveLeasesMutex.Unlock()
+	fake.RetrieveLeasesStub = stub
+}
+
+func (fake *FakeKubernetesAPI) RetrieveLeasesArgsForCall(i int) (context.Context, string, string) {
+	fake.retrieveLeasesMutex.RLock()
+	defer fake.retrieveLeasesMutex.RUnlock()
+	argsForCall := fake.retrieveLeasesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeKubernetesAPI) RetrieveLeasesReturns(result1 *v1.LeaseList, result2 error) {
+	fake.retrieveLeasesMutex.Lock()
+	defer fake.retrieveLeasesMutex.Unlock()
+	fake.RetrieveLeasesStub = nil
+	fake.retrieveLeasesReturns = struct {
+		result1 *v1.LeaseList
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveLeasesReturnsOnCall(i int, result1 *v1.LeaseList, result2 error) {
+	fake.retrieveLeasesMutex.Lock()
+	defer fake.retrieveLeasesMutex.Unlock()
+	fake.RetrieveLeasesStub = nil
+	if fake.retrieveLeasesReturnsOnCall == nil {
+		fake.retrieveLeasesReturnsOnCall = make(map[int]struct {
+			result1 *v1.LeaseList
+			result2 error
+		})
+	}
+	fake.retrieveLeasesReturnsOnCall[i] = struct {
+		result1 *v1.LeaseList
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokerByName(arg1 context.Context, arg2 string, arg3 string) (*v1beta1.ServiceBroker, error) {
 	fake.retrieveNamespaceServiceBrokerByNameMutex.Lock()
 	ret, specificReturn := fake.retrieveNamespaceServiceBrokerByNameReturnsOnCall[len(fake.retrieveNamespaceServiceBrokerByNameArgsForCall)]
 	fake.retrieveNamespaceServiceBrokerByNameArgsForCall = append(fake.retrieveNamespaceServiceBrokerByNameArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-	}{arg1, arg2})
-	fake.recordInvocation("RetrieveNamespaceServiceBrokerByName", []interface{}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.RetrieveNamespaceServiceBrokerByNameStub
+	fakeReturns := fake.retrieveNamespaceServiceBrokerByNameReturns
+	fake.recordInvocation("RetrieveNamespaceServiceBrokerByName", []interface{}{arg1, arg2, arg3})
 	fake.retrieveNamespaceServiceBrokerByNameMutex.Unlock()
-	if fake.RetrieveNamespaceServiceBrokerByNameStub != nil {
-		return fake.RetrieveNamespaceServiceBrokerByNameStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.retrieveNamespaceServiceBrokerByNameReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -726,17 +1622,17 @@ func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokerByNameCallCount() i
 	return len(fake.retrieveNamespaceServiceBrokerByNameArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokerByNameCalls(stub func(string, string) (*v1beta1.ServiceBroker, error)) {
+func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokerByNameCalls(stub func(context.Context, string, string) (*v1beta1.ServiceBroker, error)) {
 	fake.retrieveNamespaceServiceBrokerByNameMutex.Lock()
 	defer fake.retrieveNamespaceServiceBrokerByNameMutex.Unlock()
 	fake.RetrieveNamespaceServiceBrokerByNameStub = stub
 }
 
-func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokerByNameArgsForCall(i int) (string, string) {
+func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokerByNameArgsForCall(i int) (context.Context, string, string) {
 	fake.retrieveNamespaceServiceBrokerByNameMutex.RLock()
 	defer fake.retrieveNamespaceServiceBrokerByNameMutex.RUnlock()
 	argsForCall := fake.retrieveNamespaceServiceBrokerByNameArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokerByNameReturns(result1 *v1beta1.ServiceBroker, result2 error) {
@@ -765,21 +1661,23 @@ func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokerByNameReturnsOnCall
 	}{result1, result2}
 }
 
-func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokers(arg1 string) (*v1beta1.ServiceBrokerList, error) {
+func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokers(arg1 context.Context, arg2 string) (*v1beta1.ServiceBrokerList, error) {
 	fake.retrieveNamespaceServiceBrokersMutex.Lock()
 	ret, specificReturn := fake.retrieveNamespaceServiceBrokersReturnsOnCall[len(fake.retrieveNamespaceServiceBrokersArgsForCall)]
 	fake.retrieveNamespaceServiceBrokersArgsForCall = append(fake.retrieveNamespaceServiceBrokersArgsForCall, struct {
-		arg1 string
-	}{arg1})
-	fake.recordInvocation("RetrieveNamespaceServiceBrokers", []interface{}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.RetrieveNamespaceServiceBrokersStub
+	fakeReturns := fake.retrieveNamespaceServiceBrokersReturns
+	fake.recordInvocation("RetrieveNamespaceServiceBrokers", []interface{}{arg1, arg2})
 	fake.retrieveNamespaceServiceBrokersMutex.Unlock()
-	if fake.RetrieveNamespaceServiceBrokersStub != nil {
-		return fake.RetrieveNamespaceServiceBrokersStub(arg1)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.retrieveNamespaceServiceBrokersReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -789,17 +1687,17 @@ func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokersCallCount() int {
 	return len(fake.retrieveNamespaceServiceBrokersArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokersCalls(stub func(string) (*v1beta1.ServiceBrokerList, error)) {
+func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokersCalls(stub func(context.Context, string) (*v1beta1.ServiceBrokerList, error)) {
 	fake.retrieveNamespaceServiceBrokersMutex.Lock()
 	defer fake.retrieveNamespaceServiceBrokersMutex.Unlock()
 	fake.RetrieveNamespaceServiceBrokersStub = stub
 }
 
-func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokersArgsForCall(i int) string {
+func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokersArgsForCall(i int) (context.Context, string) {
 	fake.retrieveNamespaceServiceBrokersMutex.RLock()
 	defer fake.retrieveNamespaceServiceBrokersMutex.RUnlock()
 	argsForCall := fake.retrieveNamespaceServiceBrokersArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokersReturns(result1 *v1beta1.ServiceBrokerList, result2 error) {
@@ -828,22 +1726,280 @@ func (fake *FakeKubernetesAPI) RetrieveNamespaceServiceBrokersReturnsOnCall(i in
 	}{result1, result2}
 }
 
-func (fake *FakeKubernetesAPI) SyncClusterServiceBroker(arg1 string, arg2 int) error {
+func (fake *FakeKubernetesAPI) RetrieveNamespaceServicePlans(arg1 context.Context, arg2 string, arg3 string) (*v1beta1.ServicePlanList, error) {
+	fake.retrieveNamespaceServicePlansMutex.Lock()
+	ret, specificReturn := fake.retrieveNamespaceServicePlansReturnsOnCall[len(fake.retrieveNamespaceServicePlansArgsForCall)]
+	fake.retrieveNamespaceServicePlansArgsForCall = append(fake.retrieveNamespaceServicePlansArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.RetrieveNamespaceServicePlansStub
+	fakeReturns := fake.retrieveNamespaceServicePlansReturns
+	fake.recordInvocation("RetrieveNamespaceServicePlans", []interface{}{arg1, arg2, arg3})
+	fake.retrieveNamespaceServicePlansMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveNamespaceServicePlansCallCount() int {
+	fake.retrieveNamespaceServicePlansMutex.RLock()
+	defer fake.retrieveNamespaceServicePlansMutex.RUnlock()
+	return len(fake.retrieveNamespaceServicePlansArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) RetrieveNamespaceServicePlansCalls(stub func(context.Context, string, string) (*v1beta1.ServicePlanList, error)) {
+	fake.retrieveNamespaceServicePlansMutex.Lock()
+	defer fake.retrieveNamespaceServicePlansMutex.Unlock()
+	fake.RetrieveNamespaceServicePlansStub = stub
+}
+
+func (fake *FakeKubernetesAPI) RetrieveNamespaceServicePlansArgsForCall(i int) (context.Context, string, string) {
+	fake.retrieveNamespaceServicePlansMutex.RLock()
+	defer fake.retrieveNamespaceServicePlansMutex.RUnlock()
+	argsForCall := fake.retrieveNamespaceServicePlansArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeKubernetesAPI) RetrieveNamespaceServicePlansReturns(result1 *v1beta1.ServicePlanList, result2 error) {
+	fake.retrieveNamespaceServicePlansMutex.Lock()
+	defer fake.retrieveNamespaceServicePlansMutex.Unlock()
+	fake.RetrieveNamespaceServicePlansStub = nil
+	fake.retrieveNamespaceServicePlansReturns = struct {
+		result1 *v1beta1.ServicePlanList
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveNamespaceServicePlansReturnsOnCall(i int, result1 *v1beta1.ServicePlanList, result2 error) {
+	fake.retrieveNamespaceServicePlansMutex.Lock()
+	defer fake.retrieveNamespaceServicePlansMutex.Unlock()
+	fake.RetrieveNamespaceServicePlansStub = nil
+	if fake.retrieveNamespaceServicePlansReturnsOnCall == nil {
+		fake.retrieveNamespaceServicePlansReturnsOnCall = make(map[int]struct {
+			result1 *v1beta1.ServicePlanList
+			result2 error
+		})
+	}
+	fake.retrieveNamespaceServicePlansReturnsOnCall[i] = struct {
+		result1 *v1beta1.ServicePlanList
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveSecret(arg1 context.Context, arg2 string, arg3 string) (*v1a.Secret, error) {
+	fake.retrieveSecretMutex.Lock()
+	ret, specificReturn := fake.retrieveSecretReturnsOnCall[len(fake.retrieveSecretArgsForCall)]
+	fake.retrieveSecretArgsForCall = append(fake.retrieveSecretArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.RetrieveSecretStub
+	fakeReturns := fake.retrieveSecretReturns
+	fake.recordInvocation("RetrieveSecret", []interface{}{arg1, arg2, arg3})
+	fake.retrieveSecretMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKubernetesAPI) RetrieveSecretCallCount() int {
+	fake.retrieveSecretMutex.RLock()
+	defer fake.retrieveSecretMutex.RUnlock()
+	return len(fake.retrieveSecretArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) RetrieveSecretCalls(stub func(context.Context, string, string) (*v1a.Secret, error)) {
+	fake.retrieveSecretMutex.Lock()
+	defer fake.retrieveSecretMutex.Unlock()
+	fake.RetrieveSecretStub = stub
+}
+
+func (fake *FakeKubernetesAPI) RetrieveSecretArgsForCall(i int) (context.Context, string, string) {
+	fake.retrieveSecretMutex.RLock()
+	defer fake.retrieveSecretMutex.RUnlock()
+	argsForCall := fake.retrieveSecretArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeKubernetesAPI) RetrieveSecretReturns(result1 *v1a.Secret, result2 error) {
+	fake.retrieveSecretMutex.Lock()
+	defer fake.retrieveSecretMutex.Unlock()
+	fake.RetrieveSecretStub = nil
+	fake.retrieveSecretReturns = struct {
+		result1 *v1a.Secret
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) RetrieveSecretReturnsOnCall(i int, result1 *v1a.Secret, result2 error) {
+	fake.retrieveSecretMutex.Lock()
+	defer fake.retrieveSecretMutex.Unlock()
+	fake.RetrieveSecretStub = nil
+	if fake.retrieveSecretReturnsOnCall == nil {
+		fake.retrieveSecretReturnsOnCall = make(map[int]struct {
+			result1 *v1a.Secret
+			result2 error
+		})
+	}
+	fake.retrieveSecretReturnsOnCall[i] = struct {
+		result1 *v1a.Secret
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) StartBrokerCache(arg1 context.Context) error {
+	fake.startBrokerCacheMutex.Lock()
+	ret, specificReturn := fake.startBrokerCacheReturnsOnCall[len(fake.startBrokerCacheArgsForCall)]
+	fake.startBrokerCacheArgsForCall = append(fake.startBrokerCacheArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.StartBrokerCacheStub
+	fakeReturns := fake.startBrokerCacheReturns
+	fake.recordInvocation("StartBrokerCache", []interface{}{arg1})
+	fake.startBrokerCacheMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeKubernetesAPI) StartBrokerCacheCallCount() int {
+	fake.startBrokerCacheMutex.RLock()
+	defer fake.startBrokerCacheMutex.RUnlock()
+	return len(fake.startBrokerCacheArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) StartBrokerCacheCalls(stub func(context.Context) error) {
+	fake.startBrokerCacheMutex.Lock()
+	defer fake.startBrokerCacheMutex.Unlock()
+	fake.StartBrokerCacheStub = stub
+}
+
+func (fake *FakeKubernetesAPI) StartBrokerCacheArgsForCall(i int) context.Context {
+	fake.startBrokerCacheMutex.RLock()
+	defer fake.startBrokerCacheMutex.RUnlock()
+	argsForCall := fake.startBrokerCacheArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeKubernetesAPI) StartBrokerCacheReturns(result1 error) {
+	fake.startBrokerCacheMutex.Lock()
+	defer fake.startBrokerCacheMutex.Unlock()
+	fake.StartBrokerCacheStub = nil
+	fake.startBrokerCacheReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeKubernetesAPI) StartBrokerCacheReturnsOnCall(i int, result1 error) {
+	fake.startBrokerCacheMutex.Lock()
+	defer fake.startBrokerCacheMutex.Unlock()
+	fake.StartBrokerCacheStub = nil
+	if fake.startBrokerCacheReturnsOnCall == nil {
+		fake.startBrokerCacheReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.startBrokerCacheReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeKubernetesAPI) StartBrokerSyncs(arg1 context.Context, arg2 int, arg3 workqueue.RateLimiter) error {
+	fake.startBrokerSyncsMutex.Lock()
+	ret, specificReturn := fake.startBrokerSyncsReturnsOnCall[len(fake.startBrokerSyncsArgsForCall)]
+	fake.startBrokerSyncsArgsForCall = append(fake.startBrokerSyncsArgsForCall, struct {
+		arg1 context.Context
+		arg2 int
+		arg3 workqueue.RateLimiter
+	}{arg1, arg2, arg3})
+	stub := fake.StartBrokerSyncsStub
+	fakeReturns := fake.startBrokerSyncsReturns
+	fake.recordInvocation("StartBrokerSyncs", []interface{}{arg1, arg2, arg3})
+	fake.startBrokerSyncsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeKubernetesAPI) StartBrokerSyncsCallCount() int {
+	fake.startBrokerSyncsMutex.RLock()
+	defer fake.startBrokerSyncsMutex.RUnlock()
+	return len(fake.startBrokerSyncsArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) StartBrokerSyncsCalls(stub func(context.Context, int, workqueue.RateLimiter) error) {
+	fake.startBrokerSyncsMutex.Lock()
+	defer fake.startBrokerSyncsMutex.Unlock()
+	fake.StartBrokerSyncsStub = stub
+}
+
+func (fake *FakeKubernetesAPI) StartBrokerSyncsArgsForCall(i int) (context.Context, int, workqueue.RateLimiter) {
+	fake.startBrokerSyncsMutex.RLock()
+	defer fake.startBrokerSyncsMutex.RUnlock()
+	argsForCall := fake.startBrokerSyncsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeKubernetesAPI) StartBrokerSyncsReturns(result1 error) {
+	fake.startBrokerSyncsMutex.Lock()
+	defer fake.startBrokerSyncsMutex.Unlock()
+	fake.StartBrokerSyncsStub = nil
+	fake.startBrokerSyncsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeKubernetesAPI) StartBrokerSyncsReturnsOnCall(i int, result1 error) {
+	fake.startBrokerSyncsMutex.Lock()
+	defer fake.startBrokerSyncsMutex.Unlock()
+	fake.StartBrokerSyncsStub = nil
+	if fake.startBrokerSyncsReturnsOnCall == nil {
+		fake.startBrokerSyncsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.startBrokerSyncsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeKubernetesAPI) SyncClusterServiceBroker(arg1 context.Context, arg2 string, arg3 int) error {
 	fake.syncClusterServiceBrokerMutex.Lock()
 	ret, specificReturn := fake.syncClusterServiceBrokerReturnsOnCall[len(fake.syncClusterServiceBrokerArgsForCall)]
 	fake.syncClusterServiceBrokerArgsForCall = append(fake.syncClusterServiceBrokerArgsForCall, struct {
-		arg1 string
-		arg2 int
-	}{arg1, arg2})
-	fake.recordInvocation("SyncClusterServiceBroker", []interface{}{arg1, arg2})
+		arg1 context.Context
+		arg2 string
+		arg3 int
+	}{arg1, arg2, arg3})
+	stub := fake.SyncClusterServiceBrokerStub
+	fakeReturns := fake.syncClusterServiceBrokerReturns
+	fake.recordInvocation("SyncClusterServiceBroker", []interface{}{arg1, arg2, arg3})
 	fake.syncClusterServiceBrokerMutex.Unlock()
-	if fake.SyncClusterServiceBrokerStub != nil {
-		return fake.SyncClusterServiceBrokerStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.syncClusterServiceBrokerReturns
 	return fakeReturns.result1
 }
 
@@ -853,17 +2009,17 @@ func (fake *FakeKubernetesAPI) SyncClusterServiceBrokerCallCount() int {
 	return len(fake.syncClusterServiceBrokerArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) SyncClusterServiceBrokerCalls(stub func(string, int) error) {
+func (fake *FakeKubernetesAPI) SyncClusterServiceBrokerCalls(stub func(context.Context, string, int) error) {
 	fake.syncClusterServiceBrokerMutex.Lock()
 	defer fake.syncClusterServiceBrokerMutex.Unlock()
 	fake.SyncClusterServiceBrokerStub = stub
 }
 
-func (fake *FakeKubernetesAPI) SyncClusterServiceBrokerArgsForCall(i int) (string, int) {
+func (fake *FakeKubernetesAPI) SyncClusterServiceBrokerArgsForCall(i int) (context.Context, string, int) {
 	fake.syncClusterServiceBrokerMutex.RLock()
 	defer fake.syncClusterServiceBrokerMutex.RUnlock()
 	argsForCall := fake.syncClusterServiceBrokerArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeKubernetesAPI) SyncClusterServiceBrokerReturns(result1 error) {
@@ -889,23 +2045,25 @@ func (fake *FakeKubernetesAPI) SyncClusterServiceBrokerReturnsOnCall(i int, resu
 	}{result1}
 }
 
-func (fake *FakeKubernetesAPI) SyncNamespaceServiceBroker(arg1 string, arg2 string, arg3 int) error {
+func (fake *FakeKubernetesAPI) SyncNamespaceServiceBroker(arg1 context.Context, arg2 string, arg3 string, arg4 int) error {
 	fake.syncNamespaceServiceBrokerMutex.Lock()
 	ret, specificReturn := fake.syncNamespaceServiceBrokerReturnsOnCall[len(fake.syncNamespaceServiceBrokerArgsForCall)]
 	fake.syncNamespaceServiceBrokerArgsForCall = append(fake.syncNamespaceServiceBrokerArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 int
-	}{arg1, arg2, arg3})
-	fake.recordInvocation("SyncNamespaceServiceBroker", []interface{}{arg1, arg2, arg3})
+		arg3 string
+		arg4 int
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.SyncNamespaceServiceBrokerStub
+	fakeReturns := fake.syncNamespaceServiceBrokerReturns
+	fake.recordInvocation("SyncNamespaceServiceBroker", []interface{}{arg1, arg2, arg3, arg4})
 	fake.syncNamespaceServiceBrokerMutex.Unlock()
-	if fake.SyncNamespaceServiceBrokerStub != nil {
-		return fake.SyncNamespaceServiceBrokerStub(arg1, arg2, arg3)
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.syncNamespaceServiceBrokerReturns
 	return fakeReturns.result1
 }
 
@@ -915,17 +2073,17 @@ func (fake *FakeKubernetesAPI) SyncNamespaceServiceBrokerCallCount() int {
 	return len(fake.syncNamespaceServiceBrokerArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) SyncNamespaceServiceBrokerCalls(stub func(string, string, int) error) {
+func (fake *FakeKubernetesAPI) SyncNamespaceServiceBrokerCalls(stub func(context.Context, string, string, int) error) {
 	fake.syncNamespaceServiceBrokerMutex.Lock()
 	defer fake.syncNamespaceServiceBrokerMutex.Unlock()
 	fake.SyncNamespaceServiceBrokerStub = stub
 }
 
-func (fake *FakeKubernetesAPI) SyncNamespaceServiceBrokerArgsForCall(i int) (string, string, int) {
+func (fake *FakeKubernetesAPI) SyncNamespaceServiceBrokerArgsForCall(i int) (context.Context, string, string, int) {
 	fake.syncNamespaceServiceBrokerMutex.RLock()
 	defer fake.syncNamespaceServiceBrokerMutex.RUnlock()
 	argsForCall := fake.syncNamespaceServiceBrokerArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeKubernetesAPI) SyncNamespaceServiceBrokerReturns(result1 error) {
@@ -951,21 +2109,23 @@ func (fake *FakeKubernetesAPI) SyncNamespaceServiceBrokerReturnsOnCall(i int, re
 	}{result1}
 }
 
-func (fake *FakeKubernetesAPI) UpdateClusterServiceBroker(arg1 *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+func (fake *FakeKubernetesAPI) UpdateClusterServiceBroker(arg1 context.Context, arg2 *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
 	fake.updateClusterServiceBrokerMutex.Lock()
 	ret, specificReturn := fake.updateClusterServiceBrokerReturnsOnCall[len(fake.updateClusterServiceBrokerArgsForCall)]
 	fake.updateClusterServiceBrokerArgsForCall = append(fake.updateClusterServiceBrokerArgsForCall, struct {
-		arg1 *v1beta1.ClusterServiceBroker
-	}{arg1})
-	fake.recordInvocation("UpdateClusterServiceBroker", []interface{}{arg1})
+		arg1 context.Context
+		arg2 *v1beta1.ClusterServiceBroker
+	}{arg1, arg2})
+	stub := fake.UpdateClusterServiceBrokerStub
+	fakeReturns := fake.updateClusterServiceBrokerReturns
+	fake.recordInvocation("UpdateClusterServiceBroker", []interface{}{arg1, arg2})
 	fake.updateClusterServiceBrokerMutex.Unlock()
-	if fake.UpdateClusterServiceBrokerStub != nil {
-		return fake.UpdateClusterServiceBrokerStub(arg1)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.updateClusterServiceBrokerReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -975,17 +2135,17 @@ func (fake *FakeKubernetesAPI) UpdateClusterServiceBrokerCallCount() int {
 	return len(fake.updateClusterServiceBrokerArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) UpdateClusterServiceBrokerCalls(stub func(*v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error)) {
+func (fake *FakeKubernetesAPI) UpdateClusterServiceBrokerCalls(stub func(context.Context, *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error)) {
 	fake.updateClusterServiceBrokerMutex.Lock()
 	defer fake.updateClusterServiceBrokerMutex.Unlock()
 	fake.UpdateClusterServiceBrokerStub = stub
 }
 
-func (fake *FakeKubernetesAPI) UpdateClusterServiceBrokerArgsForCall(i int) *v1beta1.ClusterServiceBroker {
+func (fake *FakeKubernetesAPI) UpdateClusterServiceBrokerArgsForCall(i int) (context.Context, *v1beta1.ClusterServiceBroker) {
 	fake.updateClusterServiceBrokerMutex.RLock()
 	defer fake.updateClusterServiceBrokerMutex.RUnlock()
 	argsForCall := fake.updateClusterServiceBrokerArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeKubernetesAPI) UpdateClusterServiceBrokerReturns(result1 *v1beta1.ClusterServiceBroker, result2 error) {
@@ -1014,22 +2174,89 @@ func (fake *FakeKubernetesAPI) UpdateClusterServiceBrokerReturnsOnCall(i int, re
 	}{result1, result2}
 }
 
-func (fake *FakeKubernetesAPI) UpdateNamespaceServiceBroker(arg1 *v1beta1.ServiceBroker, arg2 string) (*v1beta1.ServiceBroker, error) {
+func (fake *FakeKubernetesAPI) UpdateLease(arg1 context.Context, arg2 *v1.Lease) (*v1.Lease, error) {
+	fake.updateLeaseMutex.Lock()
+	ret, specificReturn := fake.updateLeaseReturnsOnCall[len(fake.updateLeaseArgsForCall)]
+	fake.updateLeaseArgsForCall = append(fake.updateLeaseArgsForCall, struct {
+		arg1 context.Context
+		arg2 *v1.Lease
+	}{arg1, arg2})
+	stub := fake.UpdateLeaseStub
+	fakeReturns := fake.updateLeaseReturns
+	fake.recordInvocation("UpdateLease", []interface{}{arg1, arg2})
+	fake.updateLeaseMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeKubernetesAPI) UpdateLeaseCallCount() int {
+	fake.updateLeaseMutex.RLock()
+	defer fake.updateLeaseMutex.RUnlock()
+	return len(fake.updateLeaseArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) UpdateLeaseCalls(stub func(context.Context, *v1.Lease) (*v1.Lease, error)) {
+	fake.updateLeaseMutex.Lock()
+	defer fake.updateLeaseMutex.Unlock()
+	fake.UpdateLeaseStub = stub
+}
+
+func (fake *FakeKubernetesAPI) UpdateLeaseArgsForCall(i int) (context.Context, *v1.Lease) {
+	fake.updateLeaseMutex.RLock()
+	defer fake.updateLeaseMutex.RUnlock()
+	argsForCall := fake.updateLeaseArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeKubernetesAPI) UpdateLeaseReturns(result1 *v1.Lease, result2 error) {
+	fake.updateLeaseMutex.Lock()
+	defer fake.updateLeaseMutex.Unlock()
+	fake.UpdateLeaseStub = nil
+	fake.updateLeaseReturns = struct {
+		result1 *v1.Lease
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) UpdateLeaseReturnsOnCall(i int, result1 *v1.Lease, result2 error) {
+	fake.updateLeaseMutex.Lock()
+	defer fake.updateLeaseMutex.Unlock()
+	fake.UpdateLeaseStub = nil
+	if fake.updateLeaseReturnsOnCall == nil {
+		fake.updateLeaseReturnsOnCall = make(map[int]struct {
+			result1 *v1.Lease
+			result2 error
+		})
+	}
+	fake.updateLeaseReturnsOnCall[i] = struct {
+		result1 *v1.Lease
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKubernetesAPI) UpdateNamespaceServiceBroker(arg1 context.Context, arg2 *v1beta1.ServiceBroker, arg3 string) (*v1beta1.ServiceBroker, error) {
 	fake.updateNamespaceServiceBrokerMutex.Lock()
 	ret, specificReturn := fake.updateNamespaceServiceBrokerReturnsOnCall[len(fake.updateNamespaceServiceBrokerArgsForCall)]
 	fake.updateNamespaceServiceBrokerArgsForCall = append(fake.updateNamespaceServiceBrokerArgsForCall, struct {
-		arg1 *v1beta1.ServiceBroker
-		arg2 string
-	}{arg1, arg2})
-	fake.recordInvocation("UpdateNamespaceServiceBroker", []interface{}{arg1, arg2})
+		arg1 context.Context
+		arg2 *v1beta1.ServiceBroker
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.UpdateNamespaceServiceBrokerStub
+	fakeReturns := fake.updateNamespaceServiceBrokerReturns
+	fake.recordInvocation("UpdateNamespaceServiceBroker", []interface{}{arg1, arg2, arg3})
 	fake.updateNamespaceServiceBrokerMutex.Unlock()
-	if fake.UpdateNamespaceServiceBrokerStub != nil {
-		return fake.UpdateNamespaceServiceBrokerStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.updateNamespaceServiceBrokerReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1039,17 +2266,17 @@ func (fake *FakeKubernetesAPI) UpdateNamespaceServiceBrokerCallCount() int {
 	return len(fake.updateNamespaceServiceBrokerArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) UpdateNamespaceServiceBrokerCalls(stub func(*v1beta1.ServiceBroker, string) (*v1beta1.ServiceBroker, error)) {
+func (fake *FakeKubernetesAPI) UpdateNamespaceServiceBrokerCalls(stub func(context.Context, *v1beta1.ServiceBroker, string) (*v1beta1.ServiceBroker, error)) {
 	fake.updateNamespaceServiceBrokerMutex.Lock()
 	defer fake.updateNamespaceServiceBrokerMutex.Unlock()
 	fake.UpdateNamespaceServiceBrokerStub = stub
 }
 
-func (fake *FakeKubernetesAPI) UpdateNamespaceServiceBrokerArgsForCall(i int) (*v1beta1.ServiceBroker, string) {
+func (fake *FakeKubernetesAPI) UpdateNamespaceServiceBrokerArgsForCall(i int) (context.Context, *v1beta1.ServiceBroker, string) {
 	fake.updateNamespaceServiceBrokerMutex.RLock()
 	defer fake.updateNamespaceServiceBrokerMutex.RUnlock()
 	argsForCall := fake.updateNamespaceServiceBrokerArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeKubernetesAPI) UpdateNamespaceServiceBrokerReturns(result1 *v1beta1.ServiceBroker, result2 error) {
@@ -1078,21 +2305,23 @@ func (fake *FakeKubernetesAPI) UpdateNamespaceServiceBrokerReturnsOnCall(i int,
 	}{result1, result2}
 }
 
-func (fake *FakeKubernetesAPI) UpdateServiceBrokerCredentials(arg1 *v1.Secret) (*v1.Secret, error) {
+func (fake *FakeKubernetesAPI) UpdateServiceBrokerCredentials(arg1 context.Context, arg2 *v1a.Secret) (*v1a.Secret, error) {
 	fake.updateServiceBrokerCredentialsMutex.Lock()
 	ret, specificReturn := fake.updateServiceBrokerCredentialsReturnsOnCall[len(fake.updateServiceBrokerCredentialsArgsForCall)]
 	fake.updateServiceBrokerCredentialsArgsForCall = append(fake.updateServiceBrokerCredentialsArgsForCall, struct {
-		arg1 *v1.Secret
-	}{arg1})
-	fake.recordInvocation("UpdateServiceBrokerCredentials", []interface{}{arg1})
+		arg1 context.Context
+		arg2 *v1a.Secret
+	}{arg1, arg2})
+	stub := fake.UpdateServiceBrokerCredentialsStub
+	fakeReturns := fake.updateServiceBrokerCredentialsReturns
+	fake.recordInvocation("UpdateServiceBrokerCredentials", []interface{}{arg1, arg2})
 	fake.updateServiceBrokerCredentialsMutex.Unlock()
-	if fake.UpdateServiceBrokerCredentialsStub != nil {
-		return fake.UpdateServiceBrokerCredentialsStub(arg1)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.updateServiceBrokerCredentialsReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1102,78 +2331,173 @@ func (fake *FakeKubernetesAPI) UpdateServiceBrokerCredentialsCallCount() int {
 	return len(fake.updateServiceBrokerCredentialsArgsForCall)
 }
 
-func (fake *FakeKubernetesAPI) UpdateServiceBrokerCredentialsCalls(stub func(*v1.Secret) (*v1.Secret, error)) {
+func (fake *FakeKubernetesAPI) UpdateServiceBrokerCredentialsCalls(stub func(context.Context, *v1a.Secret) (*v1a.Secret, error)) {
 	fake.updateServiceBrokerCredentialsMutex.Lock()
 	defer fake.updateServiceBrokerCredentialsMutex.Unlock()
 	fake.UpdateServiceBrokerCredentialsStub = stub
 }
 
-func (fake *FakeKubernetesAPI) UpdateServiceBrokerCredentialsArgsForCall(i int) *v1.Secret {
+func (fake *FakeKubernetesAPI) UpdateServiceBrokerCredentialsArgsForCall(i int) (context.Context, *v1a.Secret) {
 	fake.updateServiceBrokerCredentialsMutex.RLock()
 	defer fake.updateServiceBrokerCredentialsMutex.RUnlock()
 	argsForCall := fake.updateServiceBrokerCredentialsArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeKubernetesAPI) UpdateServiceBrokerCredentialsReturns(result1 *v1.Secret, result2 error) {
+func (fake *FakeKubernetesAPI) UpdateServiceBrokerCredentialsReturns(result1 *v1a.Secret, result2 error) {
 	fake.updateServiceBrokerCredentialsMutex.Lock()
 	defer fake.updateServiceBrokerCredentialsMutex.Unlock()
 	fake.UpdateServiceBrokerCredentialsStub = nil
 	fake.updateServiceBrokerCredentialsReturns = struct {
-		result1 *v1.Secret
+		result1 *v1a.Secret
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeKubernetesAPI) UpdateServiceBrokerCredentialsReturnsOnCall(i int, result1 *v1.Secret, result2 error) {
+func (fake *FakeKubernetesAPI) UpdateServiceBrokerCredentialsReturnsOnCall(i int, result1 *v1a.Secret, result2 error) {
 	fake.updateServiceBrokerCredentialsMutex.Lock()
 	defer fake.updateServiceBrokerCredentialsMutex.Unlock()
 	fake.UpdateServiceBrokerCredentialsStub = nil
 	if fake.updateServiceBrokerCredentialsReturnsOnCall == nil {
 		fake.updateServiceBrokerCredentialsReturnsOnCall = make(map[int]struct {
-			result1 *v1.Secret
+			result1 *v1a.Secret
 			result2 error
 		})
 	}
 	fake.updateServiceBrokerCredentialsReturnsOnCall[i] = struct {
-		result1 *v1.Secret
+		result1 *v1a.Secret
 		result2 error
 	}{result1, result2}
 }
 
+func (fake *FakeKubernetesAPI) WatchNamespaces(arg1 context.Context, arg2 string, arg3 cache.ResourceEventHandler) error {
+	fake.watchNamespacesMutex.Lock()
+	ret, specificReturn := fake.watchNamespacesReturnsOnCall[len(fake.watchNamespacesArgsForCall)]
+	fake.watchNamespacesArgsForCall = append(fake.watchNamespacesArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 cache.ResourceEventHandler
+	}{arg1, arg2, arg3})
+	stub := fake.WatchNamespacesStub
+	fakeReturns := fake.watchNamespacesReturns
+	fake.recordInvocation("WatchNamespaces", []interface{}{arg1, arg2, arg3})
+	fake.watchNamespacesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeKubernetesAPI) WatchNamespacesCallCount() int {
+	fake.watchNamespacesMutex.RLock()
+	defer fake.watchNamespacesMutex.RUnlock()
+	return len(fake.watchNamespacesArgsForCall)
+}
+
+func (fake *FakeKubernetesAPI) WatchNamespacesCalls(stub func(context.Context, string, cache.ResourceEventHandler) error) {
+	fake.watchNamespacesMutex.Lock()
+	defer fake.watchNamespacesMutex.Unlock()
+	fake.WatchNamespacesStub = stub
+}
+
+func (fake *FakeKubernetesAPI) WatchNamespacesArgsForCall(i int) (context.Context, string, cache.ResourceEventHandler) {
+	fake.watchNamespacesMutex.RLock()
+	defer fake.watchNamespacesMutex.RUnlock()
+	argsForCall := fake.watchNamespacesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeKubernetesAPI) WatchNamespacesReturns(result1 error) {
+	fake.watchNamespacesMutex.Lock()
+	defer fake.watchNamespacesMutex.Unlock()
+	fake.WatchNamespacesStub = nil
+	fake.watchNamespacesReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeKubernetesAPI) WatchNamespacesReturnsOnCall(i int, result1 error) {
+	fake.watchNamespacesMutex.Lock()
+	defer fake.watchNamespacesMutex.Unlock()
+	fake.WatchNamespacesStub = nil
+	if fake.watchNamespacesReturnsOnCall == nil {
+		fake.watchNamespacesReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.watchNamespacesReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeKubernetesAPI) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.brokerCacheSyncedMutex.RLock()
+	defer fake.brokerCacheSyncedMutex.RUnlock()
 	fake.createClusterServiceBrokerMutex.RLock()
 	defer fake.createClusterServiceBrokerMutex.RUnlock()
+	fake.createLeaseMutex.RLock()
+	defer fake.createLeaseMutex.RUnlock()
 	fake.createNamespaceServiceBrokerMutex.RLock()
 	defer fake.createNamespaceServiceBrokerMutex.RUnlock()
 	fake.createSecretMutex.RLock()
 	defer fake.createSecretMutex.RUnlock()
 	fake.deleteClusterServiceBrokerMutex.RLock()
 	defer fake.deleteClusterServiceBrokerMutex.RUnlock()
+	fake.deleteLeaseMutex.RLock()
+	defer fake.deleteLeaseMutex.RUnlock()
 	fake.deleteNamespaceServiceBrokerMutex.RLock()
 	defer fake.deleteNamespaceServiceBrokerMutex.RUnlock()
 	fake.deleteSecretMutex.RLock()
 	defer fake.deleteSecretMutex.RUnlock()
+	fake.newLeaseLockMutex.RLock()
+	defer fake.newLeaseLockMutex.RUnlock()
+	fake.retrieveCachedClusterServiceBrokerByNameMutex.RLock()
+	defer fake.retrieveCachedClusterServiceBrokerByNameMutex.RUnlock()
+	fake.retrieveCachedClusterServiceBrokersMutex.RLock()
+	defer fake.retrieveCachedClusterServiceBrokersMutex.RUnlock()
+	fake.retrieveCachedNamespaceServiceBrokerByNameMutex.RLock()
+	defer fake.retrieveCachedNamespaceServiceBrokerByNameMutex.RUnlock()
+	fake.retrieveCachedNamespaceServiceBrokersMutex.RLock()
+	defer fake.retrieveCachedNamespaceServiceBrokersMutex.RUnlock()
 	fake.retrieveClusterServiceBrokerByNameMutex.RLock()
 	defer fake.retrieveClusterServiceBrokerByNameMutex.RUnlock()
 	fake.retrieveClusterServiceBrokersMutex.RLock()
 	defer fake.retrieveClusterServiceBrokersMutex.RUnlock()
+	fake.retrieveClusterServicePlansMutex.RLock()
+	defer fake.retrieveClusterServicePlansMutex.RUnlock()
+	fake.retrieveLeasesMutex.RLock()
+	defer fake.retrieveLeasesMutex.RUnlock()
 	fake.retrieveNamespaceServiceBrokerByNameMutex.RLock()
 	defer fake.retrieveNamespaceServiceBrokerByNameMutex.RUnlock()
 	fake.retrieveNamespaceServiceBrokersMutex.RLock()
 	defer fake.retrieveNamespaceServiceBrokersMutex.RUnlock()
+	fake.retrieveNamespaceServicePlansMutex.RLock()
+	defer fake.retrieveNamespaceServicePlansMutex.RUnlock()
+	fake.retrieveSecretMutex.RLock()
+	defer fake.retrieveSecretMutex.RUnlock()
+	fake.startBrokerCacheMutex.RLock()
+	defer fake.startBrokerCacheMutex.RUnlock()
+	fake.startBrokerSyncsMutex.RLock()
+	defer fake.startBrokerSyncsMutex.RUnlock()
 	fake.syncClusterServiceBrokerMutex.RLock()
 	defer fake.syncClusterServiceBrokerMutex.RUnlock()
 	fake.syncNamespaceServiceBrokerMutex.RLock()
 	defer fake.syncNamespaceServiceBrokerMutex.RUnlock()
 	fake.updateClusterServiceBrokerMutex.RLock()
 	defer fake.updateClusterServiceBrokerMutex.RUnlock()
+	fake.updateLeaseMutex.RLock()
+	defer fake.updateLeaseMutex.RUnlock()
 	fake.updateNamespaceServiceBrokerMutex.RLock()
 	defer fake.updateNamespaceServiceBrokerMutex.RUnlock()
 	fake.updateServiceBrokerCredentialsMutex.RLock()
 	defer fake.updateServiceBrokerCredentialsMutex.RUnlock()
+	fake.watchNamespacesMutex.RLock()
+	defer fake.watchNamespacesMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/Peripli/service-broker-proxy-k8s/pkg/k8s/api"
+	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	informers "github.com/kubernetes-sigs/service-catalog/pkg/client/informers_generated/externalversions"
+	listers "github.com/kubernetes-sigs/service-catalog/pkg/client/listers_generated/servicecatalog/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// BrokerCacheIndicatorName is the name of the health indicator which reports whether the broker cache has been synced
+const BrokerCacheIndicatorName = "broker_cache"
+
+// brokerCache holds the cluster service brokers and the service brokers of all namespaces in shared informers
+type brokerCache struct {
+	clusterBrokerInformer   cache.SharedIndexInformer
+	namespaceBrokerInformer cache.SharedIndexInformer
+	clusterBrokers          listers.ClusterServiceBrokerLister
+	namespaceBrokers        listers.ServiceBrokerLister
+}
+
+func (bc *brokerCache) hasSynced() bool {
+	return bc.clusterBrokerInformer.HasSynced() && bc.namespaceBrokerInformer.HasSynced()
+}
+
+// StartBrokerCache starts watching the service brokers until the context is done.
+// Cached reads are served by the API server until the cache has been synced.
+func (sca *ServiceCatalogAPI) StartBrokerCache(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactory(sca.ServiceCatalogClient, 0)
+	clusterBrokers := factory.Servicecatalog().V1beta1().ClusterServiceBrokers()
+	namespaceBrokers := factory.Servicecatalog().V1beta1().ServiceBrokers()
+
+	bc := &brokerCache{
+		clusterBrokerInformer:   clusterBrokers.Informer(),
+		namespaceBrokerInformer: namespaceBrokers.Informer(),
+		clusterBrokers:          clusterBrokers.Lister(),
+		namespaceBrokers:        namespaceBrokers.Lister(),
+	}
+	factory.Start(ctx.Done())
+
+	sca.lock.Lock()
+	defer sca.lock.Unlock()
+	sca.brokerCache = bc
+
+	return nil
+}
+
+// BrokerCacheSynced reports whether the broker cache has been started and synced
+func (sca *ServiceCatalogAPI) BrokerCacheSynced() bool {
+	return sca.syncedBrokerCache() != nil
+}
+
+// RetrieveCachedClusterServiceBrokers returns all cluster service brokers from the broker cache
+func (sca *ServiceCatalogAPI) RetrieveCachedClusterServiceBrokers(ctx context.Context) (*v1beta1.ClusterServiceBrokerList, error) {
+	bc := sca.syncedBrokerCache()
+	if bc == nil {
+		return sca.RetrieveClusterServiceBrokers(ctx)
+	}
+
+	brokers, err := bc.clusterBrokers.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(brokers, func(i, j int) bool {
+		return brokers[i].Name < brokers[j].Name
+	})
+
+	brokerList := &v1beta1.ClusterServiceBrokerList{Items: make([]v1beta1.ClusterServiceBroker, 0, len(brokers))}
+	for _, broker := range brokers {
+		brokerList.Items = append(brokerList.Items, *broker.DeepCopy())
+	}
+	return brokerList, nil
+}
+
+// RetrieveCachedClusterServiceBrokerByName returns a cluster service broker by name from the broker cache
+func (sca *ServiceCatalogAPI) RetrieveCachedClusterServiceBrokerByName(ctx context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+	bc := sca.syncedBrokerCache()
+	if bc == nil {
+		return sca.RetrieveClusterServiceBrokerByName(ctx, name)
+	}
+
+	broker, err := bc.clusterBrokers.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return broker.DeepCopy(), nil
+}
+
+// RetrieveCachedNamespaceServiceBrokers gets all service brokers in a namespace from the broker cache
+func (sca *ServiceCatalogAPI) RetrieveCachedNamespaceServiceBrokers(ctx context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+	bc := sca.syncedBrokerCache()
+	if bc == nil {
+		return sca.RetrieveNamespaceServiceBrokers(ctx, namespace)
+	}
+
+	brokers, err := bc.namespaceBrokers.ServiceBrokers(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(brokers, func(i, j int) bool {
+		if brokers[i].Namespace != brokers[j].Namespace {
+			return brokers[i].Namespace < brokers[j].Namespace
+		}
+		return brokers[i].Name < brokers[j].Name
+	})
+
+	brokerList := &v1beta1.ServiceBrokerList{Items: make([]v1beta1.ServiceBroker, 0, len(brokers))}
+	for _, broker := range brokers {
+		brokerList.Items = append(brokerList.Items, *broker.DeepCopy())
+	}
+	return brokerList, nil
+}
+
+// RetrieveCachedNamespaceServiceBrokerByName gets a service broker in a namespace from the broker cache
+func (sca *ServiceCatalogAPI) RetrieveCachedNamespaceServiceBrokerByName(ctx context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+	bc := sca.syncedBrokerCache()
+	if bc == nil {
+		return sca.RetrieveNamespaceServiceBrokerByName(ctx, name, namespace)
+	}
+
+	broker, err := bc.namespaceBrokers.ServiceBrokers(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return broker.DeepCopy(), nil
+}
+
+func (sca *ServiceCatalogAPI) syncedBrokerCache() *brokerCache {
+	sca.lock.Lock()
+	bc := sca.brokerCache
+	sca.lock.Unlock()
+
+	if bc == nil || !bc.hasSynced() {
+		return nil
+	}
+	return bc
+}
+
+// StartBrokerCache starts the broker cache if it is enabled
+func (pc *PlatformClient) StartBrokerCache(ctx context.Context) error {
+	if !pc.brokerCacheEnabled {
+		return nil
+	}
+	return pc.platformAPI.StartBrokerCache(ctx)
+}
+
+// HealthIndicators returns the health indicators of the platform client
+func (pc *PlatformClient) HealthIndicators() []health.Indicator {
+	if !pc.brokerCacheEnabled {
+		return nil
+	}
+	return []health.Indicator{&brokerCacheIndicator{platformAPI: pc.platformAPI}}
+}
+
+// brokerCacheIndicator reports the proxy as not ready until the broker cache has been synced
+type brokerCacheIndicator struct {
+	platformAPI api.KubernetesAPI
+}
+
+// Name returns the name of the indicator
+func (i *brokerCacheIndicator) Name() string {
+	return BrokerCacheIndicatorName
+}
+
+// Status returns an error until the broker cache has been synced
+func (i *brokerCacheIndicator) Status() (interface{}, error) {
+	if !i.platformAPI.BrokerCacheSynced() {
+		return nil, errors.New("broker cache has not been synced")
+	}
+	return "synced", nil
+}
+
+// retrieveClusterServiceBrokers lists the cluster service brokers from the broker cache if it is enabled
+func (pc *PlatformClient) retrieveClusterServiceBrokers(ctx context.Context) (*v1beta1.ClusterServiceBrokerList, error) {
+	if pc.brokerCacheEnabled {
+		return pc.platformAPI.RetrieveCachedClusterServiceBrokers(ctx)
+	}
+	return pc.platformAPI.RetrieveClusterServiceBrokers(ctx)
+}
+
+// retrieveClusterServiceBrokerByName gets a cluster service broker from the broker cache if it is enabled
+func (pc *PlatformClient) retrieveClusterServiceBrokerByName(ctx context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+	if pc.brokerCacheEnabled {
+		return pc.platformAPI.RetrieveCachedClusterServiceBrokerByName(ctx, name)
+	}
+	return pc.platformAPI.RetrieveClusterServiceBrokerByName(ctx, name)
+}
+
+// retrieveNamespaceServiceBrokers lists the service brokers in a namespace from the broker cache if it is enabled
+func (pc *PlatformClient) retrieveNamespaceServiceBrokers(ctx context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+	if pc.brokerCacheEnabled {
+		return pc.platformAPI.RetrieveCachedNamespaceServiceBrokers(ctx, namespace)
+	}
+	return pc.platformAPI.RetrieveNamespaceServiceBrokers(ctx, namespace)
+}
+
+// retrieveNamespaceServiceBrokerByName gets a service broker in a namespace from the broker cache if it is enabled
+func (pc *PlatformClient) retrieveNamespaceServiceBrokerByName(ctx context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+	if pc.brokerCacheEnabled {
+		return pc.platformAPI.RetrieveCachedNamespaceServiceBrokerByName(ctx, name, namespace)
+	}
+	return pc.platformAPI.RetrieveNamespaceServiceBrokerByName(ctx, name, namespace)
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	svcatfake "github.com/kubernetes-sigs/service-catalog/pkg/client/clientset_generated/clientset/fake"
+	servicecatalog "github.com/kubernetes-sigs/service-catalog/pkg/svcat/service-catalog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Broker cache", func() {
+	var (
+		ctx        context.Context
+		cancel     context.CancelFunc
+		svcatFake  *svcatfake.Clientset
+		catalogAPI *ServiceCatalogAPI
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		svcatFake = svcatfake.NewSimpleClientset(
+			&v1beta1.ClusterServiceBroker{ObjectMeta: v1.ObjectMeta{Name: "cluster-broker-b"}},
+			&v1beta1.ClusterServiceBroker{ObjectMeta: v1.ObjectMeta{Name: "cluster-broker-a"}},
+			&v1beta1.ServiceBroker{ObjectMeta: v1.ObjectMeta{Name: "namespace-broker", Namespace: "team-a"}},
+			&v1beta1.ServiceBroker{ObjectMeta: v1.ObjectMeta{Name: "namespace-broker", Namespace: "team-b"}},
+		)
+		catalogAPI = NewDefaultKubernetesAPI(&servicecatalog.SDK{ServiceCatalogClient: svcatFake})
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	startedCache := func() {
+		Expect(catalogAPI.StartBrokerCache(ctx)).To(Succeed())
+		Eventually(catalogAPI.BrokerCacheSynced).Should(BeTrue())
+		svcatFake.ClearActions()
+	}
+
+	It("is not synced before it is started", func() {
+		Expect(catalogAPI.BrokerCacheSynced()).To(BeFalse())
+	})
+
+	It("reads from the API server before it is started", func() {
+		brokers, err := catalogAPI.RetrieveCachedClusterServiceBrokers(ctx)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(brokers.Items).To(HaveLen(2))
+		Expect(svcatFake.Actions()).To(HaveLen(1))
+		Expect(svcatFake.Actions()[0].GetVerb()).To(Equal("list"))
+	})
+
+	It("lists the cluster service brokers sorted by name without calling the API server", func() {
+		startedCache()
+
+		brokers, err := catalogAPI.RetrieveCachedClusterServiceBrokers(ctx)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(brokers.Items).To(HaveLen(2))
+		Expect(brokers.Items[0].Name).To(Equal("cluster-broker-a"))
+		Expect(brokers.Items[1].Name).To(Equal("cluster-broker-b"))
+		Expect(svcatFake.Actions()).To(BeEmpty())
+	})
+
+	It("gets a cluster service broker without calling the API server", func() {
+		startedCache()
+
+		broker, err := catalogAPI.RetrieveCachedClusterServiceBrokerByName(ctx, "cluster-broker-a")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(broker.Name).To(Equal("cluster-broker-a"))
+		Expect(svcatFake.Actions()).To(BeEmpty())
+	})
+
+	It("returns not found for unknown cluster service brokers", func() {
+		startedCache()
+
+		_, err := catalogAPI.RetrieveCachedClusterServiceBrokerByName(ctx, "unknown")
+
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("lists the service brokers of a namespace", func() {
+		startedCache()
+
+		brokers, err := catalogAPI.RetrieveCachedNamespaceServiceBrokers(ctx, "team-b")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(brokers.Items).To(HaveLen(1))
+		Expect(brokers.Items[0].Namespace).To(Equal("team-b"))
+		Expect(svcatFake.Actions()).To(BeEmpty())
+	})
+
+	It("lists the service brokers of all namespaces", func() {
+		startedCache()
+
+		brokers, err := catalogAPI.RetrieveCachedNamespaceServiceBrokers(ctx, v1.NamespaceAll)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(brokers.Items).To(HaveLen(2))
+		Expect(brokers.Items[0].Namespace).To(Equal("team-a"))
+		Expect(brokers.Items[1].Namespace).To(Equal("team-b"))
+	})
+
+	It("gets a service broker in a namespace", func() {
+		startedCache()
+
+		broker, err := catalogAPI.RetrieveCachedNamespaceServiceBrokerByName(ctx, "namespace-broker", "team-a")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(broker.Namespace).To(Equal("team-a"))
+		Expect(svcatFake.Actions()).To(BeEmpty())
+	})
+
+	It("returns copies which do not modify the cache", func() {
+		startedCache()
+
+		broker, err := catalogAPI.RetrieveCachedClusterServiceBrokerByName(ctx, "cluster-broker-a")
+		Expect(err).ToNot(HaveOccurred())
+		broker.Spec.RelistRequests++
+
+		cachedBroker, err := catalogAPI.RetrieveCachedClusterServiceBrokerByName(ctx, "cluster-broker-a")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cachedBroker.Spec.RelistRequests).To(BeZero())
+	})
+
+	It("picks up brokers created after it has been synced", func() {
+		startedCache()
+
+		_, err := svcatFake.ServicecatalogV1beta1().ClusterServiceBrokers().Create(ctx,
+			&v1beta1.ClusterServiceBroker{ObjectMeta: v1.ObjectMeta{Name: "cluster-broker-c"}}, v1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func() error {
+			_, err := catalogAPI.RetrieveCachedClusterServiceBrokerByName(ctx, "cluster-broker-c")
+			return err
+		}).Should(Succeed())
+	})
+})
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	servicecatalog "github.com/kubernetes-sigs/service-catalog/pkg/svcat/service-catalog"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// brokerStatusPollInterval is the interval in which the status of a created or relisted broker is checked
+const brokerStatusPollInterval = time.Second
+
+// reconciledBroker is a broker whose status tells whether service-catalog has processed its current generation
+type reconciledBroker interface {
+	servicecatalog.Broker
+	GetGeneration() int64
+}
+
+// waitForClusterBrokerReady waits until service-catalog has fetched the catalog of the cluster-scoped broker,
+// if a broker ready timeout is configured
+func (pc *PlatformClient) waitForClusterBrokerReady(ctx context.Context, name string) error {
+	return pc.waitForBrokerReady(ctx, name, func() (reconciledBroker, error) {
+		return pc.platformAPI.RetrieveClusterServiceBrokerByName(ctx, name)
+	})
+}
+
+// waitForNamespaceBrokerReady waits until service-catalog has fetched the catalog of the broker in the namespace,
+// if a broker ready timeout is configured
+func (pc *PlatformClient) waitForNamespaceBrokerReady(ctx context.Context, name, namespace string) error {
+	return pc.waitForBrokerReady(ctx, name, func() (reconciledBroker, error) {
+		return pc.platformAPI.RetrieveNamespaceServiceBrokerByName(ctx, name, namespace)
+	})
+}
+
+func (pc *PlatformClient) waitForBrokerReady(ctx context.Context, name string, retrieveBroker func() (reconciledBroker, error)) error {
+	if pc.brokerReadyTimeout <= 0 {
+		return nil
+	}
+
+	lastBroker, err := pc.pollBroker(ctx, pc.brokerReadyTimeout, retrieveBroker, func(broker reconciledBroker) (bool, error) {
+		status := broker.GetStatus()
+		if status.ReconciledGeneration < broker.GetGeneration() {
+			return false, nil
+		}
+		return brokerReady(name, status)
+	})
+	if err == wait.ErrWaitTimeout {
+		return brokerTimeoutError(fmt.Sprintf("broker %s is not ready after %s", name, pc.brokerReadyTimeout), lastBroker)
+	}
+	return err
+}
+
+// pollBroker retrieves the broker until it has reached the state awaited by the check or the timeout expires.
+// The last retrieved broker is returned to explain a timeout.
+func (pc *PlatformClient) pollBroker(ctx context.Context, timeout time.Duration, retrieveBroker func() (reconciledBroker, error),
+	check func(broker reconciledBroker) (bool, error)) (reconciledBroker, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lastBroker reconciledBroker
+	err := wait.PollImmediateUntil(pc.brokerStatusPollInterval, func() (bool, error) {
+		broker, err := retrieveBroker()
+		if err != nil {
+			return false, err
+		}
+		lastBroker = broker
+		return check(broker)
+	}, waitCtx.Done())
+	return lastBroker, err
+}
+
+// brokerReady reports whether the reconciled broker is ready, or returns an error if service-catalog failed to fetch its catalog
+func brokerReady(name string, status v1beta1.CommonServiceBrokerStatus) (bool, error) {
+	if failed := brokerCondition(status, v1beta1.ServiceBrokerConditionFailed); failed != nil && failed.Status == v1beta1.ConditionTrue {
+		return false, brokerConditionError(name, failed)
+	}
+	ready := brokerCondition(status, v1beta1.ServiceBrokerConditionReady)
+	if ready == nil {
+		return false, nil
+	}
+	if ready.Status == v1beta1.ConditionTrue {
+		return true, nil
+	}
+	// the controller retries to fetch the catalog, but the failure of the current generation is reported right away
+	if ready.Status == v1beta1.ConditionFalse && len(ready.Reason) > 0 {
+		return false, brokerConditionError(name, ready)
+	}
+	return false, nil
+}
+
+// brokerReadiness returns the status of the Ready condition of the broker, which is unknown until service-catalog has set it
+func brokerReadiness(status v1beta1.CommonServiceBrokerStatus) v1beta1.ConditionStatus {
+	if ready := brokerCondition(status, v1beta1.ServiceBrokerConditionReady); ready != nil {
+		return ready.Status
+	}
+	return v1beta1.ConditionUnknown
+}
+
+func brokerCondition(status v1beta1.CommonServiceBrokerStatus, conditionType v1beta1.ServiceBrokerConditionType) *v1beta1.ServiceBrokerCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func brokerConditionError(name string, condition *v1beta1.ServiceBrokerCondition) error {
+	return fmt.Errorf("broker %s is not ready (%s): %s", name, condition.Reason, condition.Message)
+}
+
+// brokerTimeoutError adds the message of the ready condition of the last retrieved broker, unless it is ready
+func brokerTimeoutError(message string, lastBroker reconciledBroker) error {
+	if lastBroker != nil {
+		ready := brokerCondition(lastBroker.GetStatus(), v1beta1.ServiceBrokerConditionReady)
+		if ready != nil && ready.Status != v1beta1.ConditionTrue && len(ready.Message) > 0 {
+			return fmt.Errorf("%s: %s", message, ready.Message)
+		}
+	}
+	return errors.New(message)
+}
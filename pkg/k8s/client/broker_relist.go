@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// A relist only increments the relist requests of the broker, service-catalog fetches the catalog asynchronously.
+// If a relist timeout is configured, Fetch waits until service-catalog has reconciled the incremented generation and
+// retrieved the catalog again, so that failures to reload the catalog are reported instead of leaving a stale catalog.
+
+// syncClusterBroker requests a relist of the cluster-scoped broker and waits for the catalog to be fetched again,
+// if a relist timeout is configured
+func (pc *PlatformClient) syncClusterBroker(ctx context.Context, name string) error {
+	if pc.relistTimeout <= 0 {
+		return pc.platformAPI.SyncClusterServiceBroker(ctx, name, pc.syncRetries)
+	}
+
+	previousBroker, err := pc.platformAPI.RetrieveClusterServiceBrokerByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := pc.platformAPI.SyncClusterServiceBroker(ctx, name, pc.syncRetries); err != nil {
+		return err
+	}
+	return pc.waitForBrokerRelisted(ctx, name, previousBroker, func() (reconciledBroker, error) {
+		return pc.platformAPI.RetrieveClusterServiceBrokerByName(ctx, name)
+	})
+}
+
+// syncNamespaceBroker requests a relist of the broker in the namespace, which has been retrieved before, and waits
+// for the catalog to be fetched again, if a relist timeout is configured
+func (pc *PlatformClient) syncNamespaceBroker(ctx context.Context, name, namespace string, previousBroker *v1beta1.ServiceBroker) error {
+	if err := pc.platformAPI.SyncNamespaceServiceBroker(ctx, name, namespace, pc.syncRetries); err != nil {
+		return err
+	}
+	if pc.relistTimeout <= 0 {
+		return nil
+	}
+	return pc.waitForBrokerRelisted(ctx, name, previousBroker, func() (reconciledBroker, error) {
+		return pc.platformAPI.RetrieveNamespaceServiceBrokerByName(ctx, name, namespace)
+	})
+}
+
+// waitForBrokerRelisted waits until service-catalog has reconciled a generation newer than the one of the broker
+// retrieved before the relist request and has retrieved the catalog no earlier than the retrieval which the broker
+// reported then
+func (pc *PlatformClient) waitForBrokerRelisted(ctx context.Context, name string, previousBroker reconciledBroker, retrieveBroker func() (reconciledBroker, error)) error {
+	previousRetrievalTime := previousBroker.GetStatus().LastCatalogRetrievalTime
+
+	lastBroker, err := pc.pollBroker(ctx, pc.relistTimeout, retrieveBroker, func(broker reconciledBroker) (bool, error) {
+		status := broker.GetStatus()
+		if status.ReconciledGeneration <= previousBroker.GetGeneration() {
+			return false, nil
+		}
+		if ready, err := brokerReady(name, status); !ready || err != nil {
+			return ready, err
+		}
+		// the retrieval time has a precision of seconds, so a relist within the second of the previous retrieval
+		// reports the same time; the reconciled generation tells that it is a newer retrieval
+		return status.LastCatalogRetrievalTime != nil &&
+			(previousRetrievalTime == nil || !status.LastCatalogRetrievalTime.Before(previousRetrievalTime)), nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return brokerTimeoutError(fmt.Sprintf("catalog of broker %s has not been relisted after %s", name, pc.relistTimeout), lastBroker)
+	}
+	return err
+}
@@ -0,0 +1,340 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	servicecatalog "github.com/kubernetes-sigs/service-catalog/pkg/svcat/service-catalog"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// BrokerScopeLabelKey is the Service Manager broker label which overrides the scope in which the broker is registered.
+// Its value is either "cluster" or "namespace:<namespace>".
+const BrokerScopeLabelKey = "k8s-scope"
+
+const (
+	clusterScopeValue         = "cluster"
+	namespaceScopeValuePrefix = "namespace:"
+)
+
+// brokerScope is the scope in which a broker is registered. The zero value stands for the configured default scope,
+// i.e. cluster-wide or the target namespaces.
+type brokerScope struct {
+	cluster   bool
+	namespace string
+}
+
+func (s brokerScope) isDefault() bool {
+	return !s.cluster && len(s.namespace) == 0
+}
+
+// parseBrokerScope returns the scope selected by the labels of a Service Manager broker
+func parseBrokerScope(labels types.Labels) (brokerScope, error) {
+	values := labels[BrokerScopeLabelKey]
+	if len(values) == 0 {
+		return brokerScope{}, nil
+	}
+	if len(values) > 1 {
+		return brokerScope{}, fmt.Errorf("label %s has more than one value", BrokerScopeLabelKey)
+	}
+
+	value := values[0]
+	if value == clusterScopeValue {
+		return brokerScope{cluster: true}, nil
+	}
+	if strings.HasPrefix(value, namespaceScopeValuePrefix) {
+		namespace := strings.TrimPrefix(value, namespaceScopeValuePrefix)
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return brokerScope{}, fmt.Errorf("label %s has an invalid namespace %s: %s", BrokerScopeLabelKey, namespace, strings.Join(errs, "; "))
+		}
+		return brokerScope{namespace: namespace}, nil
+	}
+
+	return brokerScope{}, fmt.Errorf("label %s has an invalid value %s", BrokerScopeLabelKey, value)
+}
+
+// registrationScope returns whether brokers of the scope are registered cluster-wide or the namespaces they are registered in
+func (pc *PlatformClient) registrationScope(scope brokerScope) (bool, []string) {
+	switch {
+	case scope.cluster:
+		return true, nil
+	case len(scope.namespace) > 0:
+		return false, []string{scope.namespace}
+	case pc.isClusterScoped():
+		return true, nil
+	default:
+		return false, pc.namespaces()
+	}
+}
+
+// refreshBrokerScopes loads the scopes, the TLS settings and the relist settings of all brokers from their Service Manager
+// labels. The brokers listed by sbproxy for the current resync are used if there are any.
+func (pc *PlatformClient) refreshBrokerScopes(ctx context.Context) error {
+	brokers, err := pc.listSMBrokers(ctx)
+	if err != nil {
+		return err
+	}
+	return pc.loadBrokerScopes(ctx, brokers)
+}
+
+// loadBrokerScopes loads the scopes, the TLS settings and the relist settings of the Service Manager brokers from their labels
+func (pc *PlatformClient) loadBrokerScopes(ctx context.Context, brokers []*types.ServiceBroker) error {
+	scopes := make(map[string]brokerScope, len(brokers))
+	tlsOverrides := make(map[string]brokerTLSOverrides, len(brokers))
+	relistOverrides := make(map[string]brokerRelistOverrides, len(brokers))
+	for _, broker := range brokers {
+		scope, err := parseBrokerScope(broker.Labels)
+		if err != nil {
+			log.C(ctx).WithError(err).Errorf("Registering broker %s in the default scope", broker.Name)
+		}
+		scopes[broker.ID] = scope
+
+		overrides, err := parseBrokerTLSOverrides(broker.Labels)
+		if err != nil {
+			log.C(ctx).WithError(err).Errorf("Registering broker %s with the default TLS settings", broker.Name)
+		}
+		tlsOverrides[broker.ID] = overrides
+
+		relist, err := parseBrokerRelistOverrides(broker.Labels)
+		if err != nil {
+			log.C(ctx).WithError(err).Errorf("Registering broker %s with the default relist behavior", broker.Name)
+		}
+		relistOverrides[broker.ID] = relist
+	}
+
+	// the platform names of the brokers are not known to Service Manager, so the scopes of the platform brokers are
+	// found by the Service Manager ID in their labels, e.g. to delete a broker which has not been registered since a restart
+	brokerScopes, err := pc.platformBrokerScopes(ctx, scopes)
+	if err != nil {
+		return fmt.Errorf("unable to get broker scopes (%s)", err)
+	}
+
+	pc.scopesLock.Lock()
+	defer pc.scopesLock.Unlock()
+	pc.smBrokerScopes = scopes
+	pc.smBrokerTLSOverrides = tlsOverrides
+	pc.smBrokerRelistOverrides = relistOverrides
+	for name, scope := range brokerScopes {
+		pc.brokerScopes[name] = scope
+	}
+	pc.brokerScopesLoaded = true
+
+	return nil
+}
+
+// platformBrokerScopes returns the scopes of the platform brokers which are registered in the scope other than the
+// default scope selected by the labels of their Service Manager broker
+func (pc *PlatformClient) platformBrokerScopes(ctx context.Context, smScopes map[string]brokerScope) (map[string]brokerScope, error) {
+	scopes := make(map[string]brokerScope)
+	listed := make(map[brokerScope]bool)
+	for _, scope := range smScopes {
+		if scope.isDefault() || listed[scope] {
+			continue
+		}
+		listed[scope] = true
+
+		cluster, namespaces := pc.registrationScope(scope)
+		if cluster {
+			brokers, err := pc.retrieveClusterServiceBrokers(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, broker := range pc.ownedClusterBrokers(brokers).Items {
+				if id, found := broker.Labels[BrokerIDLabelKey]; found && smScopes[id] == scope {
+					scopes[broker.Name] = scope
+				}
+			}
+			continue
+		}
+		for _, namespace := range namespaces {
+			brokers, err := pc.retrieveNamespaceServiceBrokers(ctx, namespace)
+			if err != nil {
+				return nil, err
+			}
+			for _, broker := range pc.ownedNamespaceBrokers(brokers).Items {
+				if id, found := broker.Labels[BrokerIDLabelKey]; found && smScopes[id] == scope {
+					scopes[broker.Name] = scope
+				}
+			}
+		}
+	}
+	return scopes, nil
+}
+
+// brokerScopeByID returns the scope of the Service Manager broker and remembers it for the platform broker name
+func (pc *PlatformClient) brokerScopeByID(ctx context.Context, id, name string) (brokerScope, error) {
+	pc.scopesLock.RLock()
+	scope, found := pc.smBrokerScopes[id]
+	pc.scopesLock.RUnlock()
+
+	// the broker may have been created in Service Manager after the last list, so it is listed again
+	if !found {
+		brokers, err := pc.smClient.GetBrokers(ctx)
+		if err != nil {
+			return brokerScope{}, fmt.Errorf("unable to get broker scopes (%s)", err)
+		}
+		if err := pc.loadBrokerScopes(ctx, brokers); err != nil {
+			return brokerScope{}, err
+		}
+		pc.scopesLock.RLock()
+		scope = pc.smBrokerScopes[id]
+		pc.scopesLock.RUnlock()
+	}
+
+	pc.scopesLock.Lock()
+	defer pc.scopesLock.Unlock()
+	pc.brokerScopes[name] = scope
+
+	return scope, nil
+}
+
+// brokerScopeByName returns the scope of a platform broker. The scopes are loaded once if the broker has not been
+// registered or updated before.
+func (pc *PlatformClient) brokerScopeByName(ctx context.Context, name string) (brokerScope, error) {
+	pc.scopesLock.RLock()
+	scope, found := pc.brokerScopes[name]
+	loaded := pc.brokerScopesLoaded
+	pc.scopesLock.RUnlock()
+
+	if found || loaded {
+		return scope, nil
+	}
+	if err := pc.refreshBrokerScopes(ctx); err != nil {
+		return brokerScope{}, err
+	}
+
+	pc.scopesLock.RLock()
+	defer pc.scopesLock.RUnlock()
+	return pc.brokerScopes[name], nil
+}
+
+// movedBrokers returns the names of the brokers which are not registered in the scope selected by the labels of their
+// Service Manager broker, e.g. after the label has been changed, and remembers their registrations to delete them when
+// the brokers are registered again. A broker in a namespace does not count as registration of a cluster-scoped broker,
+// as it may make plans of the cluster-scoped broker visible in the namespace.
+func (pc *PlatformClient) movedBrokers(ctx context.Context, registrations []servicecatalog.Broker) sets.String {
+	pc.scopesLock.RLock()
+	smScopes := make(map[string]brokerScope, len(pc.smBrokerScopes))
+	for id, scope := range pc.smBrokerScopes {
+		smScopes[id] = scope
+	}
+	pc.scopesLock.RUnlock()
+
+	current := sets.NewString()
+	previous := make(map[string][]servicecatalog.Broker)
+	for _, broker := range registrations {
+		scope, found := smScopes[registrationBrokerID(broker)]
+		if !found || pc.isRegisteredIn(broker, scope) {
+			current.Insert(broker.GetName())
+			continue
+		}
+		previous[broker.GetName()] = append(previous[broker.GetName()], broker)
+	}
+
+	moved := sets.NewString()
+	movedRegistrations := make(map[string][]servicecatalog.Broker)
+	for name, brokers := range previous {
+		if current.Has(name) && !hasClusterRegistration(brokers) {
+			continue
+		}
+		log.C(ctx).Infof("Registering broker %s again, as it has been moved to another scope", name)
+		moved.Insert(name)
+		movedRegistrations[registrationBrokerID(brokers[0])] = brokers
+	}
+
+	pc.scopesLock.Lock()
+	defer pc.scopesLock.Unlock()
+	pc.movedRegistrations = movedRegistrations
+
+	return moved
+}
+
+// isRegisteredIn reports whether the platform broker is registered in the scope
+func (pc *PlatformClient) isRegisteredIn(broker servicecatalog.Broker, scope brokerScope) bool {
+	cluster, namespaces := pc.registrationScope(scope)
+	if cluster {
+		return len(broker.GetNamespace()) == 0
+	}
+	return sets.NewString(namespaces...).Has(broker.GetNamespace())
+}
+
+func hasClusterRegistration(brokers []servicecatalog.Broker) bool {
+	for _, broker := range brokers {
+		if len(broker.GetNamespace()) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func registrationBrokerID(broker servicecatalog.Broker) string {
+	if object, ok := broker.(v1.Object); ok {
+		return object.GetLabels()[BrokerIDLabelKey]
+	}
+	return ""
+}
+
+// deleteMovedRegistrations deletes the registrations of the broker in the scope it has been moved from
+func (pc *PlatformClient) deleteMovedRegistrations(ctx context.Context, id string) error {
+	pc.scopesLock.Lock()
+	registrations := pc.movedRegistrations[id]
+	delete(pc.movedRegistrations, id)
+	pc.scopesLock.Unlock()
+
+	for _, registration := range registrations {
+		switch broker := registration.(type) {
+		case *v1beta1.ClusterServiceBroker:
+			log.C(ctx).Infof("Deleting cluster-scoped broker %s, which has been moved to another scope", broker.Name)
+			if err := pc.deleteClusterBroker(ctx, broker.Name, id); err != nil {
+				return fmt.Errorf("unable to delete broker %s (%s)", broker.Name, err)
+			}
+		case *v1beta1.ServiceBroker:
+			log.C(ctx).Infof("Deleting broker %s in namespace %s, which has been moved to another scope", broker.Name, broker.Namespace)
+			if err := pc.deleteNamespaceBroker(ctx, broker); err != nil {
+				return fmt.Errorf("unable to delete broker %s in namespace %s (%s)", broker.Name, broker.Namespace, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (pc *PlatformClient) forgetBrokerScope(name string) {
+	pc.scopesLock.Lock()
+	defer pc.scopesLock.Unlock()
+
+	delete(pc.brokerScopes, name)
+}
+
+// extraBrokerScopes returns the scopes other than the default scope which brokers are registered in
+func (pc *PlatformClient) extraBrokerScopes() []brokerScope {
+	pc.scopesLock.RLock()
+	defer pc.scopesLock.RUnlock()
+
+	seen := make(map[brokerScope]bool)
+	scopes := make([]brokerScope, 0)
+	for _, scopesByKey := range []map[string]brokerScope{pc.smBrokerScopes, pc.brokerScopes} {
+		for _, scope := range scopesByKey {
+			if !scope.isDefault() && !seen[scope] {
+				seen[scope] = true
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	sort.Slice(scopes, func(i, j int) bool {
+		if scopes[i].cluster != scopes[j].cluster {
+			return scopes[i].cluster
+		}
+		return scopes[i].namespace < scopes[j].namespace
+	})
+
+	return scopes
+}
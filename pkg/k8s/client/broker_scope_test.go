@@ -0,0 +1,44 @@
+package client
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Broker scope", func() {
+	Describe("parseBrokerScope", func() {
+		It("returns the default scope without label", func() {
+			scope, err := parseBrokerScope(types.Labels{"other": {"value"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scope.isDefault()).To(BeTrue())
+		})
+
+		It("returns the cluster scope", func() {
+			scope, err := parseBrokerScope(types.Labels{BrokerScopeLabelKey: {"cluster"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scope).To(Equal(brokerScope{cluster: true}))
+		})
+
+		It("returns the namespace scope", func() {
+			scope, err := parseBrokerScope(types.Labels{BrokerScopeLabelKey: {"namespace:team-a"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scope).To(Equal(brokerScope{namespace: "team-a"}))
+		})
+
+		It("fails for invalid namespaces", func() {
+			_, err := parseBrokerScope(types.Labels{BrokerScopeLabelKey: {"namespace:Team_A"}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails for unknown values", func() {
+			_, err := parseBrokerScope(types.Labels{BrokerScopeLabelKey: {"global"}})
+			Expect(err).To(MatchError("label k8s-scope has an invalid value global"))
+		})
+
+		It("fails for several values", func() {
+			_, err := parseBrokerScope(types.Labels{BrokerScopeLabelKey: {"cluster", "namespace:team-a"}})
+			Expect(err).To(MatchError("label k8s-scope has more than one value"))
+		})
+	})
+})
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// brokerSyncTimeout bounds a sync, which runs apart from the cancellation of the callers sharing its result
+const brokerSyncTimeout = 5 * time.Minute
+
+// brokerSyncKey identifies the broker of a sync by its scope, namespace and name,
+// since namespace-scoped brokers of the same name may exist in several namespaces and next to a cluster-scoped one
+type brokerSyncKey struct {
+	clusterScoped bool
+	namespace     string
+	name          string
+}
+
+// brokerSync is a sync of a broker which is in flight or scheduled to follow the one in flight
+type brokerSync struct {
+	ctx  context.Context
+	sync func(ctx context.Context) error
+	done chan struct{}
+	err  error
+	next *brokerSync
+}
+
+// brokerSyncs deduplicates concurrent syncs of the same broker. A sync which is requested while another sync of the broker
+// is in flight cannot rely on its result, because the broker may have been read before the request. It schedules a single
+// follow-up sync instead, which all syncs requested in the meantime share and whose result they all receive.
+// A sync runs with the values of the context of the caller which has requested it, but is not aborted when one of the
+// callers sharing it stops waiting. It stops only when the shutdown context is done.
+type brokerSyncs struct {
+	lock     sync.Mutex
+	syncs    map[brokerSyncKey]*brokerSync
+	shutdown context.Context
+}
+
+func newBrokerSyncs() *brokerSyncs {
+	return &brokerSyncs{syncs: make(map[brokerSyncKey]*brokerSync), shutdown: context.Background()}
+}
+
+// stopWith stops the syncs when the context is done
+func (s *brokerSyncs) stopWith(ctx context.Context) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.shutdown = ctx
+}
+
+// do runs the sync of the broker, or schedules the follow-up of the sync in flight, and waits for the result
+// until the context is done
+func (s *brokerSyncs) do(ctx context.Context, key brokerSyncKey, sync func(ctx context.Context) error) error {
+	s.lock.Lock()
+	current, inFlight := s.syncs[key]
+	if !inFlight {
+		current = &brokerSync{ctx: ctx, sync: sync, done: make(chan struct{})}
+		s.syncs[key] = current
+		s.lock.Unlock()
+
+		go s.run(key, current)
+		return current.wait(ctx)
+	}
+
+	if current.next == nil {
+		current.next = &brokerSync{ctx: ctx, sync: sync, done: make(chan struct{})}
+	}
+	next := current.next
+	s.lock.Unlock()
+
+	return next.wait(ctx)
+}
+
+// wait returns the result of the sync, or the error of the context if it is done before
+func (b *brokerSync) wait(ctx context.Context) error {
+	select {
+	case <-b.done:
+		return b.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run runs the sync and starts its follow-up, if one has been scheduled in the meantime
+func (s *brokerSyncs) run(key brokerSyncKey, current *brokerSync) {
+	s.lock.Lock()
+	shutdown := s.shutdown
+	s.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(withoutCancel(current.ctx, shutdown), brokerSyncTimeout)
+	current.err = current.sync(ctx)
+	cancel()
+
+	s.lock.Lock()
+	next := current.next
+	if next == nil {
+		delete(s.syncs, key)
+	} else {
+		s.syncs[key] = next
+	}
+	s.lock.Unlock()
+
+	close(current.done)
+	if next != nil {
+		go s.run(key, next)
+	}
+}
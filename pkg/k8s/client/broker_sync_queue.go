@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	brokerSyncBaseDelay = 100 * time.Millisecond
+	brokerSyncMaxDelay  = 30 * time.Second
+)
+
+// brokerSyncRequest is a relist request of a broker which the sync workers process.
+// A failed relist is retried with an exponential backoff up to the given number of attempts.
+type brokerSyncRequest struct {
+	ctx           context.Context
+	retries       int
+	requestRelist func() error
+	result        chan error
+}
+
+// firstAttemptRateLimiter is a rate limiter which also limits the first attempts of the items
+type firstAttemptRateLimiter interface {
+	workqueue.RateLimiter
+	// FirstAttemptDelay returns the delay of the first attempt of an item, which does not count as a failure
+	FirstAttemptDelay() time.Duration
+}
+
+// brokerSyncRateLimiter delays the retries of a failed sync exponentially and limits all attempts of the syncs to the
+// rate limit per second, so that resyncing all brokers at once, e.g. after a Service Manager outage, does not flood
+// the API server
+type brokerSyncRateLimiter struct {
+	workqueue.RateLimiter
+	bucket *workqueue.BucketRateLimiter
+}
+
+func newBrokerSyncRateLimiter(rateLimit float64, burst int) *brokerSyncRateLimiter {
+	bucket := &workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(rateLimit), burst)}
+	return &brokerSyncRateLimiter{
+		RateLimiter: workqueue.NewMaxOfRateLimiter(
+			workqueue.NewItemExponentialFailureRateLimiter(brokerSyncBaseDelay, brokerSyncMaxDelay),
+			bucket,
+		),
+		bucket: bucket,
+	}
+}
+
+// FirstAttemptDelay takes a token of the rate limit for the first attempt of a sync and returns its delay
+func (r *brokerSyncRateLimiter) FirstAttemptDelay() time.Duration {
+	return r.bucket.When(nil)
+}
+
+// StartBrokerSyncs starts the workers which process the broker syncs until the context is done.
+// Syncs run on the goroutine of their caller until the workers have been started. The first attempts of the syncs are
+// delayed by the rate limiter as well if it limits them.
+func (sca *ServiceCatalogAPI) StartBrokerSyncs(ctx context.Context, workers int, rateLimiter workqueue.RateLimiter) error {
+	if workers <= 0 {
+		return fmt.Errorf("broker syncs need at least one worker, got %d", workers)
+	}
+
+	queue := workqueue.NewNamedRateLimitingQueue(rateLimiter, "broker_syncs")
+	for i := 0; i < workers; i++ {
+		go func() {
+			for processNextBrokerSync(queue) {
+			}
+		}()
+	}
+	go func() {
+		<-ctx.Done()
+		queue.ShutDown()
+	}()
+
+	sca.brokerSyncs.stopWith(ctx)
+
+	sca.lock.Lock()
+	defer sca.lock.Unlock()
+	sca.brokerSyncQueue = queue
+	sca.brokerSyncRateLimiter, _ = rateLimiter.(firstAttemptRateLimiter)
+
+	return nil
+}
+
+// relistBroker requests a relist of the broker through the sync workers and waits for the result
+func (sca *ServiceCatalogAPI) relistBroker(ctx context.Context, retries int, requestRelist func() error) error {
+	sca.lock.Lock()
+	queue := sca.brokerSyncQueue
+	rateLimiter := sca.brokerSyncRateLimiter
+	sca.lock.Unlock()
+
+	if queue == nil {
+		return relist(ctx, retries, requestRelist)
+	}
+	if queue.ShuttingDown() {
+		return errors.New("could not sync service broker (broker syncs have been stopped)")
+	}
+
+	request := &brokerSyncRequest{
+		ctx:           ctx,
+		retries:       retries,
+		requestRelist: requestRelist,
+		result:        make(chan error, 1),
+	}
+	if rateLimiter != nil {
+		queue.AddAfter(request, rateLimiter.FirstAttemptDelay())
+	} else {
+		queue.Add(request)
+	}
+
+	select {
+	case err := <-request.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// processNextBrokerSync processes the next sync of the queue and reports whether the queue is still running
+func processNextBrokerSync(queue workqueue.RateLimitingInterface) bool {
+	item, shutdown := queue.Get()
+	if shutdown {
+		return false
+	}
+	defer queue.Done(item)
+
+	request := item.(*brokerSyncRequest)
+	if err := request.ctx.Err(); err != nil {
+		queue.Forget(request)
+		request.result <- err
+		return true
+	}
+
+	err := request.requestRelist()
+	if err != nil && isRetriableSyncError(err) && queue.NumRequeues(request) < request.retries-1 {
+		queue.AddRateLimited(request)
+		return true
+	}
+
+	queue.Forget(request)
+	if err != nil {
+		err = fmt.Errorf("could not sync service broker (%s)", err)
+	}
+	request.result <- err
+	return true
+}
+
+// isRetriableSyncError reports whether a failed relist request may succeed later
+func isRetriableSyncError(err error) bool {
+	return apierrors.IsConflict(err) || apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) || apierrors.IsInternalError(err) || apierrors.IsServiceUnavailable(err)
+}
+
+// StartBrokerSyncs starts the configured number of workers which sync the brokers until the context is done.
+// Debounced plan access changes are applied until the context is done as well.
+func (pc *PlatformClient) StartBrokerSyncs(ctx context.Context) error {
+	pc.planAccess.stopWith(ctx)
+	rateLimiter := pc.metrics.countRetries(newBrokerSyncRateLimiter(pc.syncRateLimit, pc.syncBurst))
+	return pc.platformAPI.StartBrokerSyncs(ctx, pc.syncWorkers, rateLimiter)
+}
@@ -0,0 +1,272 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	svcatfake "github.com/kubernetes-sigs/service-catalog/pkg/client/clientset_generated/clientset/fake"
+	servicecatalog "github.com/kubernetes-sigs/service-catalog/pkg/svcat/service-catalog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("Broker sync", func() {
+	var (
+		ctx           context.Context
+		svcatFake     *svcatfake.Clientset
+		catalogAPI    *ServiceCatalogAPI
+		updates       int32
+		release       chan struct{}
+		followUpError error
+	)
+
+	syncInBackground := func(sync func() error) chan error {
+		result := make(chan error, 1)
+		go func() {
+			defer GinkgoRecover()
+			result <- sync()
+		}()
+		return result
+	}
+
+	syncClusterBroker := func() error {
+		return catalogAPI.SyncClusterServiceBroker(ctx, "broker", 1)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		svcatFake = svcatfake.NewSimpleClientset(
+			&v1beta1.ClusterServiceBroker{ObjectMeta: v1.ObjectMeta{Name: "broker"}},
+			&v1beta1.ServiceBroker{ObjectMeta: v1.ObjectMeta{Name: "broker", Namespace: "team-a"}},
+		)
+		catalogAPI = NewDefaultKubernetesAPI(&servicecatalog.SDK{ServiceCatalogClient: svcatFake})
+
+		// the first relist request blocks until it is released, the following ones fail with the follow-up error
+		updates = 0
+		release = make(chan struct{})
+		followUpError = nil
+		svcatFake.PrependReactor("update", "clusterservicebrokers", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if atomic.AddInt32(&updates, 1) == 1 {
+				<-release
+				return false, nil, nil
+			}
+			return followUpError != nil, nil, followUpError
+		})
+	})
+
+	It("requests a relist for each sync which is not concurrent to another one", func() {
+		close(release)
+
+		Expect(syncClusterBroker()).To(Succeed())
+		Expect(syncClusterBroker()).To(Succeed())
+
+		broker, err := svcatFake.ServicecatalogV1beta1().ClusterServiceBrokers().Get(ctx, "broker", v1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(broker.Spec.RelistRequests).To(Equal(int64(2)))
+	})
+
+	It("runs a single follow-up for the syncs requested while a sync is in flight and returns its result to all of them", func() {
+		followUpError = errors.New("update failed")
+		inFlight := syncInBackground(syncClusterBroker)
+		Eventually(func() int32 { return atomic.LoadInt32(&updates) }).Should(Equal(int32(1)))
+
+		followUp := syncInBackground(syncClusterBroker)
+		joinedFollowUp := syncInBackground(syncClusterBroker)
+		Consistently(followUp, 100*time.Millisecond).ShouldNot(Receive())
+		Consistently(joinedFollowUp, 10*time.Millisecond).ShouldNot(Receive())
+		close(release)
+
+		Eventually(inFlight).Should(Receive(BeNil()))
+		Eventually(followUp).Should(Receive(MatchError(ContainSubstring("update failed"))))
+		Eventually(joinedFollowUp).Should(Receive(MatchError(ContainSubstring("update failed"))))
+		Expect(atomic.LoadInt32(&updates)).To(Equal(int32(2)))
+	})
+
+	It("completes the follow-up for the other syncs when the sync which has scheduled it stops waiting", func() {
+		inFlight := syncInBackground(syncClusterBroker)
+		Eventually(func() int32 { return atomic.LoadInt32(&updates) }).Should(Equal(int32(1)))
+
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancelled := syncInBackground(func() error {
+			return catalogAPI.SyncClusterServiceBroker(cancelledCtx, "broker", 1)
+		})
+		Consistently(cancelled, 50*time.Millisecond).ShouldNot(Receive())
+		joinedFollowUp := syncInBackground(syncClusterBroker)
+		Consistently(joinedFollowUp, 10*time.Millisecond).ShouldNot(Receive())
+		cancel()
+		Eventually(cancelled).Should(Receive(Equal(context.Canceled)))
+		close(release)
+
+		Eventually(inFlight).Should(Receive(BeNil()))
+		Eventually(joinedFollowUp).Should(Receive(BeNil()))
+		Expect(atomic.LoadInt32(&updates)).To(Equal(int32(2)))
+	})
+
+	It("runs the sync with the values of its caller until the syncs are stopped", func() {
+		type valueKey struct{}
+		shutdownCtx, shutdown := context.WithCancel(ctx)
+		catalogAPI.brokerSyncs.stopWith(shutdownCtx)
+		callerCtx, cancel := context.WithCancel(context.WithValue(ctx, valueKey{}, "value"))
+		defer cancel()
+
+		err := catalogAPI.brokerSyncs.do(callerCtx, brokerSyncKey{clusterScoped: true, name: "broker"}, func(ctx context.Context) error {
+			Expect(ctx.Value(valueKey{})).To(Equal("value"))
+			shutdown()
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		Expect(err).To(Equal(context.Canceled))
+		Expect(callerCtx.Err()).ToNot(HaveOccurred())
+	})
+
+	It("does not deduplicate syncs of brokers with the same name in another scope", func() {
+		// the fake clientset serializes all requests, so the cluster-scoped sync is held without calling it
+		started := make(chan struct{})
+		inFlight := syncInBackground(func() error {
+			return catalogAPI.brokerSyncs.do(ctx, brokerSyncKey{clusterScoped: true, name: "broker"}, func(context.Context) error {
+				close(started)
+				<-release
+				return nil
+			})
+		})
+		Eventually(started).Should(BeClosed())
+
+		Expect(catalogAPI.SyncNamespaceServiceBroker(ctx, "broker", "team-a", 1)).To(Succeed())
+
+		close(release)
+		Eventually(inFlight).Should(Receive(BeNil()))
+		broker, err := svcatFake.ServicecatalogV1beta1().ServiceBrokers("team-a").Get(ctx, "broker", v1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(broker.Spec.RelistRequests).To(Equal(int64(1)))
+	})
+
+	It("stops waiting for the follow-up when the context is done", func() {
+		inFlight := syncInBackground(syncClusterBroker)
+		Eventually(func() int32 { return atomic.LoadInt32(&updates) }).Should(Equal(int32(1)))
+
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		Expect(catalogAPI.SyncClusterServiceBroker(cancelledCtx, "broker", 1)).To(Equal(context.Canceled))
+
+		close(release)
+		Eventually(inFlight).Should(Receive(BeNil()))
+	})
+
+	It("spreads the syncs exceeding the burst at the rate limit", func() {
+		close(release)
+		var (
+			lock     sync.Mutex
+			attempts []time.Time
+		)
+		svcatFake.PrependReactor("update", "clusterservicebrokers", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			attempts = append(attempts, time.Now())
+			return false, nil, nil
+		})
+		workerCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		Expect(catalogAPI.StartBrokerSyncs(workerCtx, 4, newBrokerSyncRateLimiter(20, 2))).To(Succeed())
+
+		results := make([]chan error, 0, 6)
+		for i := 0; i < cap(results); i++ {
+			name := fmt.Sprintf("broker-%d", i)
+			_, err := svcatFake.ServicecatalogV1beta1().ClusterServiceBrokers().Create(ctx, &v1beta1.ClusterServiceBroker{ObjectMeta: v1.ObjectMeta{Name: name}}, v1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			results = append(results, syncInBackground(func() error {
+				return catalogAPI.SyncClusterServiceBroker(ctx, name, 1)
+			}))
+		}
+		for _, result := range results {
+			Eventually(result).Should(Receive(BeNil()))
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		Expect(attempts).To(HaveLen(6))
+		sort.Slice(attempts, func(i, j int) bool { return attempts[i].Before(attempts[j]) })
+		// two syncs run right away, the following ones one every 50ms
+		Expect(attempts[1].Sub(attempts[0])).To(BeNumerically("<", 40*time.Millisecond))
+		Expect(attempts[5].Sub(attempts[0])).To(BeNumerically(">=", 180*time.Millisecond))
+	})
+
+	Context("with started workers", func() {
+		var (
+			cancel    context.CancelFunc
+			attempts  int32
+			gets      int32
+			conflicts int32
+		)
+
+		BeforeEach(func() {
+			close(release)
+			ctx, cancel = context.WithCancel(ctx)
+			attempts, gets, conflicts = 0, 0, 0
+			svcatFake.PrependReactor("update", "clusterservicebrokers", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				atomic.AddInt32(&attempts, 1)
+				if atomic.AddInt32(&conflicts, -1) >= 0 {
+					return true, nil, apierrors.NewConflict(v1beta1.Resource("clusterservicebrokers"), "broker", errors.New("conflict"))
+				}
+				return false, nil, nil
+			})
+			svcatFake.PrependReactor("get", "clusterservicebrokers", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				atomic.AddInt32(&gets, 1)
+				return false, nil, nil
+			})
+			rateLimiter := workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, 10*time.Millisecond)
+			Expect(catalogAPI.StartBrokerSyncs(ctx, 2, rateLimiter)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			cancel()
+		})
+
+		It("retries failed relists with a backoff", func() {
+			conflicts = 2
+
+			Expect(catalogAPI.SyncClusterServiceBroker(ctx, "broker", 3)).To(Succeed())
+
+			Expect(atomic.LoadInt32(&attempts)).To(Equal(int32(3)))
+			broker, err := svcatFake.ServicecatalogV1beta1().ClusterServiceBrokers().Get(ctx, "broker", v1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(broker.Spec.RelistRequests).To(Equal(int64(1)))
+		})
+
+		It("fails once the retry limit is reached", func() {
+			conflicts = 5
+
+			err := catalogAPI.SyncClusterServiceBroker(ctx, "broker", 2)
+
+			Expect(err).To(MatchError(ContainSubstring("could not sync service broker")))
+			Expect(atomic.LoadInt32(&attempts)).To(Equal(int32(2)))
+		})
+
+		It("does not retry relists of missing brokers", func() {
+			err := catalogAPI.SyncClusterServiceBroker(ctx, "missing-broker", 3)
+
+			Expect(err).To(MatchError(ContainSubstring("not found")))
+			Expect(atomic.LoadInt32(&gets)).To(Equal(int32(1)))
+		})
+
+		It("rejects syncs once the context is done", func() {
+			cancel()
+			Eventually(catalogAPI.brokerSyncQueue.ShuttingDown).Should(BeTrue())
+
+			err := catalogAPI.SyncClusterServiceBroker(context.Background(), "broker", 1)
+
+			Expect(err).To(MatchError(ContainSubstring("broker syncs have been stopped")))
+		})
+	})
+})
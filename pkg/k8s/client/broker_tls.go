@@ -0,0 +1,163 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// CABundleSecretLabelKey is the Service Manager broker label which overrides the secret holding the CA bundle
+	// which service-catalog uses to verify the broker URL. The secret is read from the namespace of the broker secrets.
+	CABundleSecretLabelKey = "k8s-ca-bundle-secret"
+	// InsecureSkipTLSVerifyLabelKey is the Service Manager broker label which overrides whether service-catalog
+	// skips the verification of the broker URL certificate. Its value is either "true" or "false".
+	InsecureSkipTLSVerifyLabelKey = "k8s-insecure-skip-tls-verify"
+	// CABundleSecretKey is the key of the CA bundle in a CA bundle secret
+	CABundleSecretKey = "ca.crt"
+)
+
+// brokerTLSOverrides are the TLS settings which a Service Manager broker selects with its labels
+type brokerTLSOverrides struct {
+	caBundleSecret        string
+	insecureSkipTLSVerify *bool
+}
+
+// brokerTLS is the TLS trust which service-catalog uses to call a broker.
+// A nil trust leaves the TLS settings of the brokers as they are, e.g. a CA bundle which has been set manually.
+type brokerTLS struct {
+	caBundle              []byte
+	insecureSkipTLSVerify bool
+}
+
+// apply sets the TLS trust in the broker spec and reports whether the spec has changed
+func (t *brokerTLS) apply(spec *v1beta1.CommonServiceBrokerSpec) bool {
+	if t == nil || bytes.Equal(spec.CABundle, t.caBundle) && spec.InsecureSkipTLSVerify == t.insecureSkipTLSVerify {
+		return false
+	}
+	spec.CABundle = t.caBundle
+	spec.InsecureSkipTLSVerify = t.insecureSkipTLSVerify
+	return true
+}
+
+// brokerTLSOf returns the TLS trust of a broker spec, e.g. to register a broker like an existing one
+func brokerTLSOf(spec v1beta1.CommonServiceBrokerSpec) *brokerTLS {
+	return &brokerTLS{caBundle: spec.CABundle, insecureSkipTLSVerify: spec.InsecureSkipTLSVerify}
+}
+
+// parseBrokerTLSOverrides returns the TLS settings selected by the labels of a Service Manager broker
+func parseBrokerTLSOverrides(labels types.Labels) (brokerTLSOverrides, error) {
+	var overrides brokerTLSOverrides
+
+	if values := labels[CABundleSecretLabelKey]; len(values) > 0 {
+		if len(values) > 1 {
+			return brokerTLSOverrides{}, fmt.Errorf("label %s has more than one value", CABundleSecretLabelKey)
+		}
+		if errs := validation.IsDNS1123Subdomain(values[0]); len(errs) > 0 {
+			return brokerTLSOverrides{}, fmt.Errorf("label %s has an invalid secret name %s: %s", CABundleSecretLabelKey, values[0], errs[0])
+		}
+		overrides.caBundleSecret = values[0]
+	}
+
+	if values := labels[InsecureSkipTLSVerifyLabelKey]; len(values) > 0 {
+		if len(values) > 1 {
+			return brokerTLSOverrides{}, fmt.Errorf("label %s has more than one value", InsecureSkipTLSVerifyLabelKey)
+		}
+		insecure, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return brokerTLSOverrides{}, fmt.Errorf("label %s has an invalid value %s", InsecureSkipTLSVerifyLabelKey, values[0])
+		}
+		overrides.insecureSkipTLSVerify = &insecure
+	}
+
+	return overrides, nil
+}
+
+// brokerTLS returns the TLS trust for the Service Manager broker, or nil if neither the configuration nor the labels
+// of the broker select one. The CA bundle is read each time, so that brokers are updated with a renewed bundle on
+// their next catalog fetch.
+func (pc *PlatformClient) brokerTLS(ctx context.Context, brokerID string) (*brokerTLS, error) {
+	pc.scopesLock.RLock()
+	overrides := pc.smBrokerTLSOverrides[brokerID]
+	pc.scopesLock.RUnlock()
+
+	insecure := pc.insecureSkipTLSVerify
+	if overrides.insecureSkipTLSVerify != nil {
+		insecure = *overrides.insecureSkipTLSVerify
+	}
+	if insecure {
+		return &brokerTLS{insecureSkipTLSVerify: true}, nil
+	}
+
+	caBundleSecret := pc.caBundleSecret
+	if len(overrides.caBundleSecret) > 0 {
+		caBundleSecret = overrides.caBundleSecret
+	}
+
+	switch {
+	case len(caBundleSecret) > 0:
+		secret, err := pc.platformAPI.RetrieveSecret(ctx, pc.secretNamespace, caBundleSecret)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get CA bundle secret %s in namespace %s (%s)", caBundleSecret, pc.secretNamespace, err)
+		}
+		caBundle, found := secret.Data[CABundleSecretKey]
+		if !found {
+			return nil, fmt.Errorf("CA bundle secret %s in namespace %s has no key %s", caBundleSecret, pc.secretNamespace, CABundleSecretKey)
+		}
+		return &brokerTLS{caBundle: caBundle}, nil
+	case len(pc.caBundleFile) > 0:
+		caBundle, err := ioutil.ReadFile(pc.caBundleFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA bundle file (%s)", err)
+		}
+		return &brokerTLS{caBundle: caBundle}, nil
+	case overrides.insecureSkipTLSVerify != nil:
+		// the broker explicitly verifies the URL certificate with the system trust
+		return &brokerTLS{}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// updateClusterBrokerTLS updates the TLS trust of the cluster-scoped broker if it has changed
+func (pc *PlatformClient) updateClusterBrokerTLS(ctx context.Context, name string, tls *brokerTLS) error {
+	if tls == nil {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		broker, err := pc.platformAPI.RetrieveClusterServiceBrokerByName(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !tls.apply(&broker.Spec.CommonServiceBrokerSpec) {
+			return nil
+		}
+		_, err = pc.platformAPI.UpdateClusterServiceBroker(ctx, broker)
+		return err
+	})
+}
+
+// updateNamespaceBrokerTLS updates the TLS trust of the broker in the namespace if it has changed
+func (pc *PlatformClient) updateNamespaceBrokerTLS(ctx context.Context, name, namespace string, tls *brokerTLS) error {
+	if tls == nil {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		broker, err := pc.platformAPI.RetrieveNamespaceServiceBrokerByName(ctx, name, namespace)
+		if err != nil {
+			return err
+		}
+		if !tls.apply(&broker.Spec.CommonServiceBrokerSpec) {
+			return nil
+		}
+		_, err = pc.platformAPI.UpdateNamespaceServiceBroker(ctx, broker, namespace)
+		return err
+	})
+}
@@ -2,157 +2,303 @@ package client
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/Peripli/service-broker-proxy-k8s/pkg/k8s/api"
 	"github.com/Peripli/service-broker-proxy-k8s/pkg/k8s/config"
 	servicecatalog "github.com/kubernetes-sigs/service-catalog/pkg/svcat/service-catalog"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Peripli/service-broker-proxy/pkg/platform"
+	"github.com/Peripli/service-broker-proxy/pkg/sm"
 	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/sirupsen/logrus"
 	v1core "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-const resyncBrokerRetryCount = 3
-
 // NewDefaultKubernetesAPI returns default kubernetes api interface
 func NewDefaultKubernetesAPI(cli *servicecatalog.SDK) *ServiceCatalogAPI {
 	return &ServiceCatalogAPI{
-		SDK:               cli,
-		brokersInProgress: make(map[string]bool),
-		lock:              &sync.Mutex{},
+		SDK:         cli,
+		brokerSyncs: newBrokerSyncs(),
+		lock:        &sync.Mutex{},
+		recorder:    newEventRecorder(cli.K8sClient),
 	}
 }
 
 // ServiceCatalogAPI uses service catalog SDK to interact with the kubernetes resources
 type ServiceCatalogAPI struct {
 	*servicecatalog.SDK
-	brokersInProgress map[string]bool
-	lock              *sync.Mutex
+	brokerSyncs           *brokerSyncs
+	brokerSyncQueue       workqueue.RateLimitingInterface
+	brokerSyncRateLimiter firstAttemptRateLimiter
+	lock                  *sync.Mutex
+	brokerCache           *brokerCache
+	recorder              record.EventRecorder
 }
 
 // CreateNamespaceServiceBroker creates namespace service broker
-func (sca *ServiceCatalogAPI) CreateNamespaceServiceBroker(broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
-	return sca.ServiceCatalog().ServiceBrokers(namespace).Create(context.Background(), broker, v1.CreateOptions{})
+func (sca *ServiceCatalogAPI) CreateNamespaceServiceBroker(ctx context.Context, broker *v1beta1.ServiceBroker, namespace string) (created *v1beta1.ServiceBroker, err error) {
+	defer logCall(ctx, "Creating service broker", namespaceBrokerLogFields(broker.Name, namespace), time.Now(), &err)
+	created, err = sca.ServiceCatalog().ServiceBrokers(namespace).Create(ctx, broker, v1.CreateOptions{})
+	reference := namespaceBrokerReference(broker.Name, namespace, "")
+	if err == nil {
+		reference.UID = created.UID
+	}
+	sca.recordBrokerEvent(ctx, reference, broker.Labels, createBrokerEvent, err)
+	return created, err
 }
 
 // CreateClusterServiceBroker creates a cluster service broker
-func (sca *ServiceCatalogAPI) CreateClusterServiceBroker(broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
-	return sca.ServiceCatalog().ClusterServiceBrokers().Create(context.Background(), broker, v1.CreateOptions{})
+func (sca *ServiceCatalogAPI) CreateClusterServiceBroker(ctx context.Context, broker *v1beta1.ClusterServiceBroker) (created *v1beta1.ClusterServiceBroker, err error) {
+	defer logCall(ctx, "Creating cluster service broker", clusterBrokerLogFields(broker.Name), time.Now(), &err)
+	created, err = sca.ServiceCatalog().ClusterServiceBrokers().Create(ctx, broker, v1.CreateOptions{})
+	reference := clusterBrokerReference(broker.Name, "")
+	if err == nil {
+		reference.UID = created.UID
+	}
+	sca.recordBrokerEvent(ctx, reference, broker.Labels, createBrokerEvent, err)
+	return created, err
 }
 
 // DeleteNamespaceServiceBroker deletes a service broker in a namespace
-func (sca *ServiceCatalogAPI) DeleteNamespaceServiceBroker(name string, namespace string, options *v1.DeleteOptions) error {
-	return sca.ServiceCatalog().ServiceBrokers(namespace).Delete(context.Background(), name, *options)
+func (sca *ServiceCatalogAPI) DeleteNamespaceServiceBroker(ctx context.Context, name string, namespace string, options *v1.DeleteOptions) (err error) {
+	defer logCall(ctx, "Deleting service broker", namespaceBrokerLogFields(name, namespace), time.Now(), &err)
+	err = sca.ServiceCatalog().ServiceBrokers(namespace).Delete(ctx, name, *options)
+	sca.recordBrokerEvent(ctx, namespaceBrokerReference(name, namespace, ""), nil, deleteBrokerEvent, err)
+	return err
 }
 
 // DeleteClusterServiceBroker deletes a cluster service broker
-func (sca *ServiceCatalogAPI) DeleteClusterServiceBroker(name string, options *v1.DeleteOptions) error {
-	return sca.ServiceCatalog().ClusterServiceBrokers().Delete(context.Background(), name, *options)
+func (sca *ServiceCatalogAPI) DeleteClusterServiceBroker(ctx context.Context, name string, options *v1.DeleteOptions) (err error) {
+	defer logCall(ctx, "Deleting cluster service broker", clusterBrokerLogFields(name), time.Now(), &err)
+	err = sca.ServiceCatalog().ClusterServiceBrokers().Delete(ctx, name, *options)
+	sca.recordBrokerEvent(ctx, clusterBrokerReference(name, ""), nil, deleteBrokerEvent, err)
+	return err
 }
 
 // RetrieveNamespaceServiceBrokers gets all service brokers in a namespace
-func (sca *ServiceCatalogAPI) RetrieveNamespaceServiceBrokers(namespace string) (*v1beta1.ServiceBrokerList, error) {
-	return sca.ServiceCatalog().ServiceBrokers(namespace).List(context.Background(), v1.ListOptions{})
+func (sca *ServiceCatalogAPI) RetrieveNamespaceServiceBrokers(ctx context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+	return sca.ServiceCatalog().ServiceBrokers(namespace).List(ctx, v1.ListOptions{})
 }
 
 // RetrieveClusterServiceBrokers returns all cluster service brokers
-func (sca *ServiceCatalogAPI) RetrieveClusterServiceBrokers() (*v1beta1.ClusterServiceBrokerList, error) {
-	return sca.ServiceCatalog().ClusterServiceBrokers().List(context.Background(), v1.ListOptions{})
+func (sca *ServiceCatalogAPI) RetrieveClusterServiceBrokers(ctx context.Context) (*v1beta1.ClusterServiceBrokerList, error) {
+	return sca.ServiceCatalog().ClusterServiceBrokers().List(ctx, v1.ListOptions{})
 }
 
 // RetrieveNamespaceServiceBrokerByName gets a service broker in a namespace
-func (sca *ServiceCatalogAPI) RetrieveNamespaceServiceBrokerByName(name, namespace string) (*v1beta1.ServiceBroker, error) {
-	return sca.ServiceCatalog().ServiceBrokers(namespace).Get(context.Background(), name, v1.GetOptions{})
+func (sca *ServiceCatalogAPI) RetrieveNamespaceServiceBrokerByName(ctx context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+	return sca.ServiceCatalog().ServiceBrokers(namespace).Get(ctx, name, v1.GetOptions{})
 }
 
 // RetrieveClusterServiceBrokerByName returns a cluster service broker by name
-func (sca *ServiceCatalogAPI) RetrieveClusterServiceBrokerByName(name string) (*v1beta1.ClusterServiceBroker, error) {
-	return sca.ServiceCatalog().ClusterServiceBrokers().Get(context.Background(), name, v1.GetOptions{})
+func (sca *ServiceCatalogAPI) RetrieveClusterServiceBrokerByName(ctx context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+	return sca.ServiceCatalog().ClusterServiceBrokers().Get(ctx, name, v1.GetOptions{})
 }
 
 // UpdateNamespaceServiceBroker updates a service broker in a namespace
-func (sca *ServiceCatalogAPI) UpdateNamespaceServiceBroker(broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
-	return sca.ServiceCatalog().ServiceBrokers(namespace).Update(context.Background(), broker, v1.UpdateOptions{})
+func (sca *ServiceCatalogAPI) UpdateNamespaceServiceBroker(ctx context.Context, broker *v1beta1.ServiceBroker, namespace string) (updated *v1beta1.ServiceBroker, err error) {
+	defer logCall(ctx, "Updating service broker", namespaceBrokerLogFields(broker.Name, namespace), time.Now(), &err)
+	updated, err = sca.ServiceCatalog().ServiceBrokers(namespace).Update(ctx, broker, v1.UpdateOptions{})
+	sca.recordBrokerEvent(ctx, namespaceBrokerReference(broker.Name, namespace, broker.UID), broker.Labels, brokerUpdateEvent(ctx), err)
+	return updated, err
 }
 
 // UpdateClusterServiceBroker updates a cluster service broker
-func (sca *ServiceCatalogAPI) UpdateClusterServiceBroker(broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
-	return sca.ServiceCatalog().ClusterServiceBrokers().Update(context.Background(), broker, v1.UpdateOptions{})
+func (sca *ServiceCatalogAPI) UpdateClusterServiceBroker(ctx context.Context, broker *v1beta1.ClusterServiceBroker) (updated *v1beta1.ClusterServiceBroker, err error) {
+	defer logCall(ctx, "Updating cluster service broker", clusterBrokerLogFields(broker.Name), time.Now(), &err)
+	updated, err = sca.ServiceCatalog().ClusterServiceBrokers().Update(ctx, broker, v1.UpdateOptions{})
+	sca.recordBrokerEvent(ctx, clusterBrokerReference(broker.Name, broker.UID), broker.Labels, brokerUpdateEvent(ctx), err)
+	return updated, err
 }
 
-// SyncNamespaceServiceBroker synchronize a service broker in a namespace
-func (sca *ServiceCatalogAPI) SyncNamespaceServiceBroker(name, namespace string, retries int) error {
-	if sca.setBrokerInProgress(name) {
-		defer sca.unsetBrokerInProgress(name)
-		err := sca.Sync(name, servicecatalog.ScopeOptions{
-			Scope:     servicecatalog.NamespaceScope,
-			Namespace: namespace,
-		}, retries)
+// SyncNamespaceServiceBroker synchronize a service broker in a namespace.
+// Concurrent syncs of the same broker share a single follow-up sync and receive its result.
+func (sca *ServiceCatalogAPI) SyncNamespaceServiceBroker(ctx context.Context, name, namespace string, retries int) (err error) {
+	defer logCall(ctx, "Syncing service broker", namespaceBrokerLogFields(name, namespace), time.Now(), &err)
+	return sca.brokerSyncs.do(ctx, brokerSyncKey{namespace: namespace, name: name}, func(ctx context.Context) error {
+		var relisted v1beta1.ServiceBroker
+		err := sca.relistBroker(ctx, retries, func() error {
+			broker, err := sca.ServiceCatalog().ServiceBrokers(namespace).Get(ctx, name, v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			relisted = *broker
+			broker.Spec.RelistRequests++
+			_, err = sca.ServiceCatalog().ServiceBrokers(namespace).Update(ctx, broker, v1.UpdateOptions{})
+			return err
+		})
+		sca.recordBrokerEvent(ctx, namespaceBrokerReference(name, namespace, relisted.UID), relisted.Labels, relistBrokerEvent, err)
 		return err
-	}
-	return nil
+	})
 }
 
-// SyncClusterServiceBroker synchronizes a cluster service broker including its catalog
-func (sca *ServiceCatalogAPI) SyncClusterServiceBroker(name string, retries int) error {
-	if sca.setBrokerInProgress(name) {
-		defer sca.unsetBrokerInProgress(name)
-		err := sca.Sync(name, servicecatalog.ScopeOptions{
-			Scope: servicecatalog.ClusterScope,
-		}, retries)
+// SyncClusterServiceBroker synchronizes a cluster service broker including its catalog.
+// Concurrent syncs of the same broker share a single follow-up sync and receive its result.
+func (sca *ServiceCatalogAPI) SyncClusterServiceBroker(ctx context.Context, name string, retries int) (err error) {
+	defer logCall(ctx, "Syncing cluster service broker", clusterBrokerLogFields(name), time.Now(), &err)
+	return sca.brokerSyncs.do(ctx, brokerSyncKey{clusterScoped: true, name: name}, func(ctx context.Context) error {
+		var relisted v1beta1.ClusterServiceBroker
+		err := sca.relistBroker(ctx, retries, func() error {
+			broker, err := sca.ServiceCatalog().ClusterServiceBrokers().Get(ctx, name, v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			relisted = *broker
+			broker.Spec.RelistRequests++
+			_, err = sca.ServiceCatalog().ClusterServiceBrokers().Update(ctx, broker, v1.UpdateOptions{})
+			return err
+		})
+		sca.recordBrokerEvent(ctx, clusterBrokerReference(name, relisted.UID), relisted.Labels, relistBrokerEvent, err)
 		return err
-	}
-	return nil
+	})
+}
+
+// RetrieveNamespaceServicePlans gets all service plans of a service broker in a namespace
+func (sca *ServiceCatalogAPI) RetrieveNamespaceServicePlans(ctx context.Context, brokerName, namespace string) (*v1beta1.ServicePlanList, error) {
+	return sca.ServiceCatalog().ServicePlans(namespace).List(ctx, v1.ListOptions{
+		LabelSelector: brokerPlansSelector(v1beta1.FilterSpecServiceBrokerName, brokerName),
+	})
+}
+
+// RetrieveClusterServicePlans returns all cluster service plans of a cluster service broker
+func (sca *ServiceCatalogAPI) RetrieveClusterServicePlans(ctx context.Context, brokerName string) (*v1beta1.ClusterServicePlanList, error) {
+	return sca.ServiceCatalog().ClusterServicePlans().List(ctx, v1.ListOptions{
+		LabelSelector: brokerPlansSelector(v1beta1.FilterSpecClusterServiceBrokerName, brokerName),
+	})
 }
 
 // UpdateServiceBrokerCredentials updates broker's credentials secret
-func (sca *ServiceCatalogAPI) UpdateServiceBrokerCredentials(secret *v1core.Secret) (*v1core.Secret, error) {
-	_, err := sca.K8sClient.CoreV1().Secrets(secret.Namespace).Get(context.Background(), secret.Name, v1.GetOptions{})
+func (sca *ServiceCatalogAPI) UpdateServiceBrokerCredentials(ctx context.Context, secret *v1core.Secret) (_ *v1core.Secret, err error) {
+	defer logCall(ctx, "Writing broker credentials secret", secretLogFields(secret.Namespace, secret.Name), time.Now(), &err)
+	_, err = sca.K8sClient.CoreV1().Secrets(secret.Namespace).Get(ctx, secret.Name, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return sca.CreateSecret(secret)
+			return sca.K8sClient.CoreV1().Secrets(secret.Namespace).Create(ctx, secret, v1.CreateOptions{})
 		}
 		return nil, err
 	}
-	return sca.K8sClient.CoreV1().Secrets(secret.Namespace).Update(context.Background(), secret, v1.UpdateOptions{})
+	return sca.K8sClient.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, v1.UpdateOptions{})
 }
 
 // CreateSecret creates a secret for broker's credentials
-func (sca *ServiceCatalogAPI) CreateSecret(secret *v1core.Secret) (*v1core.Secret, error) {
-	return sca.K8sClient.CoreV1().Secrets(secret.Namespace).Create(context.Background(), secret, v1.CreateOptions{})
+func (sca *ServiceCatalogAPI) CreateSecret(ctx context.Context, secret *v1core.Secret) (_ *v1core.Secret, err error) {
+	defer logCall(ctx, "Creating broker credentials secret", secretLogFields(secret.Namespace, secret.Name), time.Now(), &err)
+	return sca.K8sClient.CoreV1().Secrets(secret.Namespace).Create(ctx, secret, v1.CreateOptions{})
+}
+
+// RetrieveSecret gets broker credentials secret
+func (sca *ServiceCatalogAPI) RetrieveSecret(ctx context.Context, namespace, name string) (*v1core.Secret, error) {
+	return sca.K8sClient.CoreV1().Secrets(namespace).Get(ctx, name, v1.GetOptions{})
 }
 
 // DeleteSecret deletes broker credentials secret
-func (sca *ServiceCatalogAPI) DeleteSecret(namespace, name string) error {
-	return sca.K8sClient.CoreV1().Secrets(namespace).Delete(context.Background(), name, v1.DeleteOptions{})
+func (sca *ServiceCatalogAPI) DeleteSecret(ctx context.Context, namespace, name string) (err error) {
+	defer logCall(ctx, "Deleting broker credentials secret", secretLogFields(namespace, name), time.Now(), &err)
+	return sca.K8sClient.CoreV1().Secrets(namespace).Delete(ctx, name, v1.DeleteOptions{})
 }
 
-func (sca *ServiceCatalogAPI) setBrokerInProgress(name string) bool {
-	sca.lock.Lock()
-	defer sca.lock.Unlock()
-	if _, ok := sca.brokersInProgress[name]; !ok {
-		sca.brokersInProgress[name] = true
-		return true
+// WatchNamespaces notifies the handler about namespaces which start or stop matching the label selector
+func (sca *ServiceCatalogAPI) WatchNamespaces(ctx context.Context, labelSelector string, handler cache.ResourceEventHandler) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(sca.K8sClient, 0, informers.WithTweakListOptions(func(options *v1.ListOptions) {
+		options.LabelSelector = labelSelector
+	}))
+	informer := factory.Core().V1().Namespaces().Informer()
+	informer.AddEventHandler(handler)
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return fmt.Errorf("unable to list namespaces matching %s", labelSelector)
 	}
-	return false
+
+	return nil
 }
 
-func (sca *ServiceCatalogAPI) unsetBrokerInProgress(name string) {
-	sca.lock.Lock()
-	defer sca.lock.Unlock()
-	delete(sca.brokersInProgress, name)
+// brokerPlansSelector returns the label selector which service-catalog uses to relate plans to their broker
+func brokerPlansSelector(property, brokerName string) string {
+	// service-catalog labels the plans with the SHA224 of the broker name to fit into the label value length limit
+	brokerNameHash := sha256.Sum224([]byte(brokerName))
+	return labels.SelectorFromSet(labels.Set{
+		v1beta1.GroupName + "/" + property: hex.EncodeToString(brokerNameHash[:]),
+	}).String()
+}
+
+// relist requests a relist of the broker catalog and retries on conflicts like the service-catalog SDK does,
+// but stops as soon as the context is done
+func relist(ctx context.Context, retries int, requestRelist func() error) error {
+	var err error
+	for i := 0; i < retries; i++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		err = requestRelist()
+		if err == nil {
+			return nil
+		}
+		if !errors.IsConflict(err) {
+			return fmt.Errorf("could not sync service broker (%s)", err)
+		}
+	}
+	return fmt.Errorf("could not sync service broker (%s)", err)
 }
 
 // PlatformClient implements all broker, visibility and catalog specific operations for kubernetes
 type PlatformClient struct {
-	platformAPI     api.KubernetesAPI
-	secretNamespace string
-	targetNamespace string
+	platformAPI              api.KubernetesAPI
+	secretNamespace          string
+	targetNamespaces         []string
+	namespaceSelector        string
+	selectedNamespaces       sets.String
+	namespacesLock           *sync.RWMutex
+	smClient                 sm.Client
+	smBrokers                *smBrokerRecorder
+	smBrokerScopes           map[string]brokerScope
+	brokerScopes             map[string]brokerScope
+	scopesLock               *sync.RWMutex
+	brokerScopesLoaded       bool
+	movedRegistrations       map[string][]servicecatalog.Broker
+	brokerCacheEnabled       bool
+	instanceName             string
+	existingBrokerPolicy     string
+	brokerReadyTimeout       time.Duration
+	relistTimeout            time.Duration
+	caBundleFile             string
+	caBundleSecret           string
+	insecureSkipTLSVerify    bool
+	smBrokerTLSOverrides     map[string]brokerTLSOverrides
+	relistBehavior           v1beta1.ServiceBrokerRelistBehavior
+	relistDuration           time.Duration
+	smBrokerRelistOverrides  map[string]brokerRelistOverrides
+	brokerStatusPollInterval time.Duration
+	planAccess               *planAccessDebouncer
+	syncWorkers              int
+	syncRetries              int
+	syncRateLimit            float64
+	syncBurst                int
+	metrics                  *metrics
+	leaderElection           bool
+	leaseName                string
+	leaseDuration            time.Duration
+	leaseRenewDeadline       time.Duration
+	leaseRetryPeriod         time.Duration
+	leadership               *leadership
+	sharding                 bool
+	shards                   *shards
 }
 
 type brokersByUID map[types.UID]servicecatalog.Broker
@@ -168,10 +314,50 @@ func NewClient(settings *config.Settings) (*PlatformClient, error) {
 	if err != nil {
 		return nil, err
 	}
+	smClient, err := sm.NewClient(settings.Sm)
+	if err != nil {
+		return nil, err
+	}
+	metrics := newMetrics()
 	return &PlatformClient{
-		platformAPI:     NewDefaultKubernetesAPI(svcatSDK),
-		secretNamespace: settings.K8S.Secret.Namespace,
-		targetNamespace: settings.K8S.TargetNamespace,
+		platformAPI:              newInstrumentedKubernetesAPI(NewDefaultKubernetesAPI(svcatSDK), metrics),
+		secretNamespace:          settings.K8S.Secret.Namespace,
+		targetNamespaces:         settings.K8S.Namespaces(),
+		namespaceSelector:        settings.K8S.NamespaceSelector,
+		selectedNamespaces:       sets.NewString(),
+		namespacesLock:           &sync.RWMutex{},
+		smClient:                 smClient,
+		smBrokers:                newSMBrokerRecorder(),
+		smBrokerScopes:           make(map[string]brokerScope),
+		brokerScopes:             make(map[string]brokerScope),
+		scopesLock:               &sync.RWMutex{},
+		brokerCacheEnabled:       settings.K8S.BrokerCache,
+		instanceName:             settings.K8S.InstanceName,
+		existingBrokerPolicy:     settings.K8S.ExistingBrokerPolicy,
+		brokerReadyTimeout:       settings.K8S.BrokerReadyTimeout,
+		relistTimeout:            settings.K8S.RelistTimeout,
+		caBundleFile:             settings.K8S.CABundleFile,
+		caBundleSecret:           settings.K8S.CABundleSecret,
+		insecureSkipTLSVerify:    settings.K8S.InsecureSkipTLSVerify,
+		smBrokerTLSOverrides:     make(map[string]brokerTLSOverrides),
+		relistBehavior:           v1beta1.ServiceBrokerRelistBehavior(settings.K8S.RelistBehavior),
+		relistDuration:           settings.K8S.RelistDuration,
+		smBrokerRelistOverrides:  make(map[string]brokerRelistOverrides),
+		brokerStatusPollInterval: brokerStatusPollInterval,
+		planAccess:               newPlanAccessDebouncer(settings.K8S.VisibilityDebounce),
+		syncWorkers:              settings.K8S.SyncWorkers,
+		syncRetries:              settings.K8S.SyncRetries,
+		syncRateLimit:            settings.K8S.SyncRateLimit,
+		syncBurst:                settings.K8S.SyncBurst,
+		metrics:                  metrics,
+		leaderElection:           settings.K8S.LeaderElection,
+		leaseName:                settings.K8S.LeaseName,
+		leaseDuration:            settings.K8S.LeaseDuration,
+		leaseRenewDeadline:       settings.K8S.LeaseRenewDeadline,
+		leaseRetryPeriod:         settings.K8S.LeaseRetryPeriod,
+		leadership:               newLeadership(!settings.K8S.LeaderElection),
+		sharding:                 settings.K8S.Sharding,
+		shards:                   newShards(settings.K8S.LeaseDuration),
 	}, nil
 }
 
@@ -191,55 +377,125 @@ func (pc *PlatformClient) Visibility() platform.VisibilityClient {
 }
 
 // GetBrokers returns all service-brokers currently registered in kubernetes service-catalog.
-func (pc *PlatformClient) GetBrokers(ctx context.Context) ([]*platform.ServiceBroker, error) {
-	var clientBrokers = make([]*platform.ServiceBroker, 0)
-	var brokers brokersByUID
+// Brokers registered in several target namespaces are returned once, with the UID of the first target namespace they are found in.
+func (pc *PlatformClient) GetBrokers(ctx context.Context) (_ []*platform.ServiceBroker, err error) {
+	ctx, span := startSpan(ctx, "PlatformClient.GetBrokers")
+	defer endSpan(span, &err)
 
-	if pc.isClusterScoped() {
-		clusterBrokers, err := pc.platformAPI.RetrieveClusterServiceBrokers()
-		if err != nil {
-			return nil, fmt.Errorf("unable to list cluster-scoped brokers (%s)", err)
+	if err := pc.refreshBrokerScopes(ctx); err != nil {
+		return nil, err
+	}
+
+	registrations, err := pc.listBrokers(ctx, brokerScope{})
+	if err != nil {
+		return nil, err
+	}
+
+	// brokers which select another scope with their Service Manager labels
+	defaultCluster, defaultNamespaces := pc.registrationScope(brokerScope{})
+	for _, scope := range pc.extraBrokerScopes() {
+		if scope.cluster && defaultCluster || !scope.cluster && sets.NewString(defaultNamespaces...).Has(scope.namespace) {
+			continue
 		}
 
-		brokers = clusterBrokersToBrokers(clusterBrokers)
-	} else {
-		namespaceBrokers, err := pc.platformAPI.RetrieveNamespaceServiceBrokers(pc.targetNamespace)
+		scopeRegistrations, err := pc.listBrokers(ctx, scope)
 		if err != nil {
-			return nil, fmt.Errorf("unable to list namespace-scoped brokers (%s)", err)
+			return nil, err
 		}
-
-		brokers = namespaceBrokersToBrokers(namespaceBrokers)
+		registrations = append(registrations, scopeRegistrations...)
 	}
 
-	for uid, broker := range brokers {
-		serviceBroker := &platform.ServiceBroker{
-			GUID:      string(uid),
+	// brokers whose scope label has been changed are left out, so that they are registered again in their new scope
+	movedBrokers := pc.movedBrokers(ctx, registrations)
+
+	clientBrokers := make([]*platform.ServiceBroker, 0)
+	readiness := make(map[string]v1beta1.ConditionStatus)
+	for _, broker := range registrations {
+		if _, listed := readiness[broker.GetName()]; listed || movedBrokers.Has(broker.GetName()) {
+			continue
+		}
+		readiness[broker.GetName()] = brokerReadiness(broker.GetStatus())
+
+		pc.rememberShardBroker(broker)
+		clientBrokers = append(clientBrokers, &platform.ServiceBroker{
+			GUID:      string(broker.(v1.Object).GetUID()),
 			Name:      broker.GetName(),
 			BrokerURL: broker.GetURL(),
-		}
-		clientBrokers = append(clientBrokers, serviceBroker)
+		})
 	}
 
+	pc.metrics.setManagedBrokers(readiness)
 	return clientBrokers, nil
 }
 
+// listBrokers lists the registrations of the managed brokers in the scope, which contain a broker once for each
+// namespace it is registered in
+func (pc *PlatformClient) listBrokers(ctx context.Context, scope brokerScope) ([]servicecatalog.Broker, error) {
+	cluster, namespaces := pc.registrationScope(scope)
+	if cluster {
+		clusterBrokers, err := pc.retrieveClusterServiceBrokers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list cluster-scoped brokers (%s)", err)
+		}
+
+		brokers := make([]servicecatalog.Broker, 0, len(clusterBrokers.Items))
+		for _, broker := range clusterBrokersToBrokers(pc.ownedClusterBrokers(clusterBrokers)) {
+			brokers = append(brokers, broker)
+		}
+		return brokers, nil
+	}
+
+	brokers := make([]servicecatalog.Broker, 0)
+	var errs []error
+	for _, namespace := range namespaces {
+		namespaceBrokers, err := pc.retrieveNamespaceServiceBrokers(ctx, namespace)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to list namespace-scoped brokers in namespace %s (%s)", namespace, err))
+			continue
+		}
+
+		for _, broker := range namespaceBrokersToBrokers(pc.ownedNamespaceBrokers(namespaceBrokers)) {
+			brokers = append(brokers, broker)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	return brokers, nil
+}
+
 // GetBrokerByName returns the service-broker with the specified name currently registered in kubernetes service-catalog with.
-func (pc *PlatformClient) GetBrokerByName(ctx context.Context, name string) (*platform.ServiceBroker, error) {
+func (pc *PlatformClient) GetBrokerByName(ctx context.Context, name string) (_ *platform.ServiceBroker, err error) {
+	ctx, span := startSpan(ctx, "PlatformClient.GetBrokerByName", brokerAttributeKey.String(name))
+	defer endSpan(span, &err)
+
 	var broker servicecatalog.Broker
 	var brokerUID types.UID
 
-	if pc.isClusterScoped() {
-		clusterBroker, err := pc.platformAPI.RetrieveClusterServiceBrokerByName(name)
+	scope, err := pc.brokerScopeByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	cluster, namespaces := pc.registrationScope(scope)
+	if cluster {
+		clusterBroker, err := pc.retrieveClusterServiceBrokerByName(ctx, name)
 		if err != nil {
 			return nil, fmt.Errorf("unable to get cluster-scoped broker (%s)", err)
 		}
+		if !pc.isOwnedClusterBroker(clusterBroker) {
+			return nil, fmt.Errorf("unable to get cluster-scoped broker (%s)", notOwnedError(name))
+		}
 
 		broker, brokerUID = clusterBroker, clusterBroker.GetUID()
 	} else {
-		namespaceBroker, err := pc.platformAPI.RetrieveNamespaceServiceBrokerByName(name, pc.targetNamespace)
+		namespaceBroker, err := pc.retrieveNamespaceBroker(ctx, name, namespaces)
 		if err != nil {
 			return nil, fmt.Errorf("unable to get namespace-scoped broker (%s)", err)
 		}
+		if !pc.isOwnedNamespaceBroker(namespaceBroker) {
+			return nil, fmt.Errorf("unable to get namespace-scoped broker (%s)", notOwnedError(name))
+		}
 
 		broker, brokerUID = namespaceBroker, namespaceBroker.GetUID()
 	}
@@ -252,36 +508,80 @@ func (pc *PlatformClient) GetBrokerByName(ctx context.Context, name string) (*pl
 }
 
 // CreateBroker registers a new broker in kubernetes service-catalog.
-func (pc *PlatformClient) CreateBroker(ctx context.Context, r *platform.CreateServiceBrokerRequest) (*platform.ServiceBroker, error) {
-	if err := pc.updateBrokerPlatformSecret(r.ID, r.Username, r.Password); err != nil {
+func (pc *PlatformClient) CreateBroker(ctx context.Context, r *platform.CreateServiceBrokerRequest) (_ *platform.ServiceBroker, err error) {
+	ctx, span := startSpan(ctx, "PlatformClient.CreateBroker", brokerAttributeKey.String(r.Name), brokerIDAttributeKey.String(r.ID))
+	defer endSpan(span, &err)
+	ctx = withBrokerLogger(ctx, r.Name, r.ID)
+	if pc.skipped(ctx, r.Name, r.ID, "creating broker") {
+		return &platform.ServiceBroker{Name: r.Name, BrokerURL: r.BrokerURL}, nil
+	}
+	logFields := logrus.Fields{}
+	defer logOperation(ctx, "Creating broker", logFields, time.Now(), &err)
+
+	scope, err := pc.brokerScopeByID(ctx, r.ID, r.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	tls, err := pc.brokerTLS(ctx, r.ID)
+	if err != nil {
 		return nil, err
 	}
+
+	if err := pc.deleteMovedRegistrations(ctx, r.ID); err != nil {
+		return nil, err
+	}
+
 	var brokerUID types.UID
 
-	if pc.isClusterScoped() {
+	cluster, namespaces := pc.registrationScope(scope)
+	setScopeLogFields(logFields, cluster, namespaces)
+	if cluster {
+		if err := pc.updateBrokerPlatformSecret(ctx, pc.secretNamespace, r.ID, r.Username, r.Password); err != nil {
+			return nil, err
+		}
+
 		broker := newClusterServiceBroker(r.Name, r.BrokerURL, &v1beta1.ObjectReference{
 			Name:      r.ID,
 			Namespace: pc.secretNamespace,
-		})
+		}, pc.ownerLabels(r.ID))
 
-		broker.Spec.CommonServiceBrokerSpec.RelistBehavior = "Manual"
+		broker.Spec.CommonServiceBrokerSpec.CatalogRestrictions = restrictPlans(nil, sets.NewString())
+		pc.brokerRelist(ctx, r.ID).apply(&broker.Spec.CommonServiceBrokerSpec)
+		tls.apply(&broker.Spec.CommonServiceBrokerSpec)
 
-		csb, err := pc.platformAPI.CreateClusterServiceBroker(broker)
+		csb, err := pc.createClusterBroker(ctx, r.ID, broker)
 		if err != nil {
 			return nil, err
 		}
-		brokerUID = csb.GetUID()
-	} else {
-		broker := newNamespaceServiceBroker(r.Name, r.BrokerURL, &v1beta1.LocalObjectReference{
-			Name: r.ID,
-		})
-		broker.Spec.CommonServiceBrokerSpec.RelistBehavior = "Manual"
-
-		csb, err := pc.platformAPI.CreateNamespaceServiceBroker(broker, pc.targetNamespace)
-		if err != nil {
+		if err := pc.waitForClusterBrokerReady(ctx, r.Name); err != nil {
 			return nil, err
 		}
 		brokerUID = csb.GetUID()
+	} else {
+		var errs []error
+		for _, namespace := range namespaces {
+			if err := pc.updateBrokerPlatformSecret(ctx, namespace, r.ID, r.Username, r.Password); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			sb, err := pc.createNamespaceBroker(ctx, r.ID, r.ID, r.Name, r.BrokerURL, namespace, restrictPlans(nil, sets.NewString()), tls)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err := pc.waitForNamespaceBrokerReady(ctx, r.Name, namespace); err != nil {
+				errs = append(errs, fmt.Errorf("unable to create broker %s in namespace %s (%s)", r.Name, namespace, err))
+				continue
+			}
+			if len(brokerUID) == 0 {
+				brokerUID = sb.GetUID()
+			}
+		}
+		if len(errs) > 0 {
+			return nil, utilerrors.NewAggregate(errs)
+		}
 	}
 
 	return &platform.ServiceBroker{
@@ -293,57 +593,129 @@ func (pc *PlatformClient) CreateBroker(ctx context.Context, r *platform.CreateSe
 }
 
 // DeleteBroker deletes an existing broker in from kubernetes service-catalog.
-func (pc *PlatformClient) DeleteBroker(ctx context.Context, r *platform.DeleteServiceBrokerRequest) error {
-	if pc.isClusterScoped() {
-		if err := pc.platformAPI.DeleteSecret(pc.secretNamespace, r.ID); err != nil {
-			return fmt.Errorf("error deleting broker credentials secret: %v", err)
+func (pc *PlatformClient) DeleteBroker(ctx context.Context, r *platform.DeleteServiceBrokerRequest) (err error) {
+	ctx, span := startSpan(ctx, "PlatformClient.DeleteBroker", brokerAttributeKey.String(r.Name), brokerIDAttributeKey.String(r.ID))
+	defer endSpan(span, &err)
+	ctx = withBrokerLogger(ctx, r.Name, r.ID)
+	if pc.skipped(ctx, r.Name, r.ID, "deleting broker") {
+		return nil
+	}
+	logFields := logrus.Fields{}
+	defer logOperation(ctx, "Deleting broker", logFields, time.Now(), &err)
+
+	scope, err := pc.brokerScopeByName(ctx, r.Name)
+	if err != nil {
+		return err
+	}
+	cluster, namespaces := pc.registrationScope(scope)
+	setScopeLogFields(logFields, cluster, namespaces)
+	if cluster {
+		if err := pc.deleteClusterBroker(ctx, r.Name, r.ID); err != nil {
+			return err
 		}
-		return pc.platformAPI.DeleteClusterServiceBroker(r.Name, &v1.DeleteOptions{})
+		pc.forgetBrokerScope(r.Name)
+		pc.forgetShardBroker(r.Name)
+		return nil
 	}
 
-	if err := pc.platformAPI.DeleteSecret(pc.targetNamespace, r.ID); err != nil {
-		return fmt.Errorf("error deleting broker credentials secret in namespace %s: %v", pc.targetNamespace, err)
+	// the broker may be missing in some of the target namespaces if its registration failed partially
+	var errs []error
+	for _, namespace := range namespaces {
+		if err := pc.platformAPI.DeleteSecret(ctx, namespace, r.ID); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("error deleting broker credentials secret in namespace %s: %v", namespace, err))
+			continue
+		}
+		if err := pc.platformAPI.DeleteNamespaceServiceBroker(ctx, r.Name, namespace, &v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("unable to delete broker %s in namespace %s (%s)", r.Name, namespace, err))
+		}
+	}
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
 	}
-	return pc.platformAPI.DeleteNamespaceServiceBroker(r.Name, pc.targetNamespace, &v1.DeleteOptions{})
 
+	pc.forgetBrokerScope(r.Name)
+	pc.forgetShardBroker(r.Name)
+	return nil
+}
+
+// deleteClusterBroker deletes the cluster-scoped broker with its credentials secret and its namespace-scoped brokers
+func (pc *PlatformClient) deleteClusterBroker(ctx context.Context, name, id string) error {
+	if err := pc.deleteNamespaceVisibilityBrokers(ctx, name); err != nil {
+		return err
+	}
+	if err := pc.platformAPI.DeleteSecret(ctx, pc.secretNamespace, id); err != nil {
+		return fmt.Errorf("error deleting broker credentials secret: %v", err)
+	}
+	return pc.platformAPI.DeleteClusterServiceBroker(ctx, name, &v1.DeleteOptions{})
 }
 
 // UpdateBroker updates a service broker in the kubernetes service-catalog.
-func (pc *PlatformClient) UpdateBroker(ctx context.Context, r *platform.UpdateServiceBrokerRequest) (*platform.ServiceBroker, error) {
-	if r.Username != "" && r.Password != "" {
-		if err := pc.updateBrokerPlatformSecret(r.ID, r.Username, r.Password); err != nil {
-			return nil, err
-		}
+func (pc *PlatformClient) UpdateBroker(ctx context.Context, r *platform.UpdateServiceBrokerRequest) (_ *platform.ServiceBroker, err error) {
+	ctx, span := startSpan(ctx, "PlatformClient.UpdateBroker", brokerAttributeKey.String(r.Name), brokerIDAttributeKey.String(r.ID))
+	defer endSpan(span, &err)
+	ctx = withBrokerLogger(ctx, r.Name, r.ID)
+	if pc.skipped(ctx, r.Name, r.ID, "updating broker") {
+		return &platform.ServiceBroker{Name: r.Name, BrokerURL: r.BrokerURL}, nil
+	}
+	logFields := logrus.Fields{}
+	defer logOperation(ctx, "Updating broker", logFields, time.Now(), &err)
+
+	scope, err := pc.brokerScopeByID(ctx, r.ID, r.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	tls, err := pc.brokerTLS(ctx, r.ID)
+	if err != nil {
+		return nil, err
 	}
+	relistSpec := pc.configuredBrokerRelist(ctx, r.ID)
 
 	var updatedBrokerUID types.UID
 	var updatedBroker servicecatalog.Broker
 
-	if pc.isClusterScoped() {
-		// Only broker url and secret-references are updateable
-		broker := newClusterServiceBroker(r.Name, r.BrokerURL, &v1beta1.ObjectReference{
-			Name:      r.ID,
-			Namespace: pc.secretNamespace,
-		})
+	cluster, namespaces := pc.registrationScope(scope)
+	setScopeLogFields(logFields, cluster, namespaces)
+	if cluster {
+		if r.Username != "" && r.Password != "" {
+			if err := pc.updateBrokerPlatformSecret(ctx, pc.secretNamespace, r.ID, r.Username, r.Password); err != nil {
+				return nil, err
+			}
+		}
 
-		updatedClusterBroker, err := pc.platformAPI.UpdateClusterServiceBroker(broker)
+		updatedClusterBroker, err := pc.updateClusterBroker(ctx, r, tls, relistSpec)
 		if err != nil {
 			return nil, err
 		}
 
-		updatedBroker, updatedBrokerUID = updatedClusterBroker, updatedClusterBroker.GetUID()
-	} else {
-		// Only broker url and secret-references are updateable
-		broker := newNamespaceServiceBroker(r.Name, r.BrokerURL, &v1beta1.LocalObjectReference{
-			Name: r.ID,
-		})
-
-		updatedNamespaceBroker, err := pc.platformAPI.UpdateNamespaceServiceBroker(broker, pc.targetNamespace)
-		if err != nil {
+		if err := pc.updateNamespaceVisibilityBrokers(ctx, r.Name, r.Username != "" && r.Password != "", false); err != nil {
 			return nil, err
 		}
 
-		updatedBroker, updatedBrokerUID = updatedNamespaceBroker, updatedNamespaceBroker.GetUID()
+		updatedBroker, updatedBrokerUID = updatedClusterBroker, updatedClusterBroker.GetUID()
+	} else {
+		var errs []error
+		for _, namespace := range namespaces {
+			if r.Username != "" && r.Password != "" {
+				if err := pc.updateBrokerPlatformSecret(ctx, namespace, r.ID, r.Username, r.Password); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+			}
+
+			updatedNamespaceBroker, err := pc.updateNamespaceBroker(ctx, r, namespace, tls, relistSpec)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to update broker %s in namespace %s (%s)", r.Name, namespace, err))
+				continue
+			}
+
+			if updatedBroker == nil {
+				updatedBroker, updatedBrokerUID = updatedNamespaceBroker, updatedNamespaceBroker.GetUID()
+			}
+		}
+		if len(errs) > 0 {
+			return nil, utilerrors.NewAggregate(errs)
+		}
 	}
 
 	return &platform.ServiceBroker{
@@ -353,20 +725,152 @@ func (pc *PlatformClient) UpdateBroker(ctx context.Context, r *platform.UpdateSe
 	}, nil
 }
 
+// updateClusterBroker changes the URL, the TLS trust, the configured relist behavior and the credentials secret reference of the current
+// cluster-scoped broker and keeps all other fields
+func (pc *PlatformClient) updateClusterBroker(ctx context.Context, r *platform.UpdateServiceBrokerRequest, tls *brokerTLS, relistSpec *brokerRelist) (*v1beta1.ClusterServiceBroker, error) {
+	var updatedBroker *v1beta1.ClusterServiceBroker
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		broker, err := pc.platformAPI.RetrieveClusterServiceBrokerByName(ctx, r.Name)
+		if err != nil {
+			return err
+		}
+		broker.Labels = pc.ownedBrokerLabels(broker.Labels, pc.legacyClusterBrokerID(broker))
+
+		// Only broker url, TLS trust, relist behavior and secret-references are updateable
+		broker.Spec.URL = r.BrokerURL
+		tls.apply(&broker.Spec.CommonServiceBrokerSpec)
+		if relistSpec != nil {
+			relistSpec.apply(&broker.Spec.CommonServiceBrokerSpec)
+		}
+		broker.Spec.AuthInfo = &v1beta1.ClusterServiceBrokerAuthInfo{
+			Basic: &v1beta1.ClusterBasicAuthConfig{
+				SecretRef: &v1beta1.ObjectReference{
+					Name:      r.ID,
+					Namespace: pc.secretNamespace,
+				},
+			},
+		}
+
+		updatedBroker, err = pc.platformAPI.UpdateClusterServiceBroker(ctx, broker)
+		return err
+	})
+
+	return updatedBroker, err
+}
+
+// updateNamespaceBroker changes the URL, the TLS trust, the configured relist behavior and the credentials secret reference of the current
+// broker in the namespace and keeps all other fields
+func (pc *PlatformClient) updateNamespaceBroker(ctx context.Context, r *platform.UpdateServiceBrokerRequest, namespace string, tls *brokerTLS,
+	relistSpec *brokerRelist) (*v1beta1.ServiceBroker, error) {
+	var updatedBroker *v1beta1.ServiceBroker
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		broker, err := pc.platformAPI.RetrieveNamespaceServiceBrokerByName(ctx, r.Name, namespace)
+		if err != nil {
+			return err
+		}
+		broker.Labels = pc.ownedBrokerLabels(broker.Labels, pc.legacyNamespaceBrokerID(broker))
+
+		// Only broker url, TLS trust, relist behavior and secret-references are updateable
+		broker.Spec.URL = r.BrokerURL
+		tls.apply(&broker.Spec.CommonServiceBrokerSpec)
+		if relistSpec != nil {
+			relistSpec.apply(&broker.Spec.CommonServiceBrokerSpec)
+		}
+		broker.Spec.AuthInfo = &v1beta1.ServiceBrokerAuthInfo{
+			Basic: &v1beta1.BasicAuthConfig{
+				SecretRef: &v1beta1.LocalObjectReference{
+					Name: r.ID,
+				},
+			},
+		}
+
+		updatedBroker, err = pc.platformAPI.UpdateNamespaceServiceBroker(ctx, broker, namespace)
+		return err
+	})
+
+	return updatedBroker, err
+}
+
 // Fetch the new catalog information from reach service-broker registered in kubernetes,
 // so that it is visible in the kubernetes service-catalog.
-func (pc *PlatformClient) Fetch(ctx context.Context, r *platform.UpdateServiceBrokerRequest) error {
-	if r.Username != "" && r.Password != "" {
-		if err := pc.updateBrokerPlatformSecret(r.ID, r.Username, r.Password); err != nil {
+// Brokers which are missing in some of the target namespaces are registered there,
+// e.g. after a namespace has been added to the target namespaces.
+func (pc *PlatformClient) Fetch(ctx context.Context, r *platform.UpdateServiceBrokerRequest) (err error) {
+	ctx, span := startSpan(ctx, "PlatformClient.Fetch", brokerAttributeKey.String(r.Name), brokerIDAttributeKey.String(r.ID))
+	defer endSpan(span, &err)
+	ctx = withBrokerLogger(ctx, r.Name, r.ID)
+	if pc.skipped(ctx, r.Name, r.ID, "fetching catalog of broker") {
+		return nil
+	}
+	logFields := logrus.Fields{}
+	defer logOperation(ctx, "Fetching catalog of broker", logFields, time.Now(), &err)
+
+	scope, err := pc.brokerScopeByID(ctx, r.ID, r.Name)
+	if err != nil {
+		return err
+	}
+
+	tls, err := pc.brokerTLS(ctx, r.ID)
+	if err != nil {
+		return err
+	}
+
+	cluster, namespaces := pc.registrationScope(scope)
+	setScopeLogFields(logFields, cluster, namespaces)
+	if cluster {
+		if r.Username != "" && r.Password != "" {
+			if err := pc.updateBrokerPlatformSecret(ctx, pc.secretNamespace, r.ID, r.Username, r.Password); err != nil {
+				return err
+			}
+		}
+		if err := pc.updateClusterBrokerTLS(ctx, r.Name, tls); err != nil {
+			return fmt.Errorf("unable to update the TLS trust of broker %s (%s)", r.Name, err)
+		}
+		if err := pc.syncClusterBroker(ctx, r.Name); err != nil {
 			return err
 		}
+		return pc.updateNamespaceVisibilityBrokers(ctx, r.Name, r.Username != "" && r.Password != "", true)
 	}
 
-	if pc.isClusterScoped() {
-		return pc.platformAPI.SyncClusterServiceBroker(r.Name, resyncBrokerRetryCount)
+	var missingNamespaces []string
+	var registeredBroker *v1beta1.ServiceBroker
+	var errs []error
+	for _, namespace := range namespaces {
+		broker, err := pc.platformAPI.RetrieveNamespaceServiceBrokerByName(ctx, r.Name, namespace)
+		if errors.IsNotFound(err) {
+			missingNamespaces = append(missingNamespaces, namespace)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to get broker %s in namespace %s (%s)", r.Name, namespace, err))
+			continue
+		}
+		if registeredBroker == nil {
+			registeredBroker = broker
+		}
+
+		if r.Username != "" && r.Password != "" {
+			if err := pc.updateBrokerPlatformSecret(ctx, namespace, r.ID, r.Username, r.Password); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if err := pc.updateNamespaceBrokerTLS(ctx, r.Name, namespace, tls); err != nil {
+			errs = append(errs, fmt.Errorf("unable to update the TLS trust of broker %s in namespace %s (%s)", r.Name, namespace, err))
+			continue
+		}
+		if err := pc.syncNamespaceBroker(ctx, r.Name, namespace, broker); err != nil {
+			errs = append(errs, fmt.Errorf("unable to sync broker %s in namespace %s (%s)", r.Name, namespace, err))
+		}
 	}
 
-	return pc.platformAPI.SyncNamespaceServiceBroker(r.Name, pc.targetNamespace, resyncBrokerRetryCount)
+	for _, namespace := range missingNamespaces {
+		if err := pc.registerMissingNamespaceBroker(ctx, r, registeredBroker, namespace, tls); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
 }
 
 // GetBrokerPlatformName enforces broker names to be as k8s requires.
@@ -375,18 +879,12 @@ func (pc *PlatformClient) GetBrokerPlatformName(name string) string {
 	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
 }
 
-func (pc *PlatformClient) updateBrokerPlatformSecret(name, username, password string) error {
-	var secretNamespace string
-	if pc.isClusterScoped() {
-		secretNamespace = pc.secretNamespace
-	} else {
-		secretNamespace = pc.targetNamespace
-	}
-
-	secret := newServiceBrokerCredentialsSecret(secretNamespace, name, username, password)
-	_, err := pc.platformAPI.UpdateServiceBrokerCredentials(secret)
+// updateBrokerPlatformSecret creates or updates the credentials secret, which is named after the Service Manager broker ID
+func (pc *PlatformClient) updateBrokerPlatformSecret(ctx context.Context, namespace, name, username, password string) error {
+	secret := newServiceBrokerCredentialsSecret(namespace, name, username, password, pc.ownerLabels(name))
+	_, err := pc.platformAPI.UpdateServiceBrokerCredentials(ctx, secret)
 	if err != nil {
-		return fmt.Errorf("error updating broker credentials secret in namespace %s: %v", secretNamespace, err)
+		return fmt.Errorf("error updating broker credentials secret in namespace %s: %v", namespace, err)
 	}
 
 	return nil
@@ -414,10 +912,11 @@ func namespaceBrokersToBrokers(namespaceBrokers *v1beta1.ServiceBrokerList) brok
 	return brokers
 }
 
-func newClusterServiceBroker(name string, url string, secret *v1beta1.ObjectReference) *v1beta1.ClusterServiceBroker {
+func newClusterServiceBroker(name string, url string, secret *v1beta1.ObjectReference, labels map[string]string) *v1beta1.ClusterServiceBroker {
 	return &v1beta1.ClusterServiceBroker{
 		ObjectMeta: v1.ObjectMeta{
-			Name: name,
+			Name:   name,
+			Labels: labels,
 		},
 		Spec: v1beta1.ClusterServiceBrokerSpec{
 			CommonServiceBrokerSpec: v1beta1.CommonServiceBrokerSpec{
@@ -432,10 +931,11 @@ func newClusterServiceBroker(name string, url string, secret *v1beta1.ObjectRefe
 	}
 }
 
-func newNamespaceServiceBroker(name string, url string, secret *v1beta1.LocalObjectReference) *v1beta1.ServiceBroker {
+func newNamespaceServiceBroker(name string, url string, secret *v1beta1.LocalObjectReference, labels map[string]string) *v1beta1.ServiceBroker {
 	return &v1beta1.ServiceBroker{
 		ObjectMeta: v1.ObjectMeta{
-			Name: name,
+			Name:   name,
+			Labels: labels,
 		},
 		Spec: v1beta1.ServiceBrokerSpec{
 			CommonServiceBrokerSpec: v1beta1.CommonServiceBrokerSpec{
@@ -450,11 +950,12 @@ func newNamespaceServiceBroker(name string, url string, secret *v1beta1.LocalObj
 	}
 }
 
-func newServiceBrokerCredentialsSecret(namespace, name, username, password string) *v1core.Secret {
+func newServiceBrokerCredentialsSecret(namespace, name, username, password string, labels map[string]string) *v1core.Secret {
 	return &v1core.Secret{
 		ObjectMeta: v1.ObjectMeta{
 			Namespace: namespace,
 			Name:      name,
+			Labels:    labels,
 		},
 		Data: map[string][]byte{
 			"password": []byte(password),
@@ -463,35 +964,282 @@ func newServiceBrokerCredentialsSecret(namespace, name, username, password strin
 	}
 }
 
+// clusterBrokerSecretRef returns the credentials secret of a cluster-scoped broker, if any
+func clusterBrokerSecretRef(authInfo *v1beta1.ClusterServiceBrokerAuthInfo) *v1beta1.ObjectReference {
+	if authInfo == nil || authInfo.Basic == nil {
+		return nil
+	}
+	return authInfo.Basic.SecretRef
+}
+
+// namespaceBrokerSecretRef returns the credentials secret of a namespace-scoped broker, if any
+func namespaceBrokerSecretRef(authInfo *v1beta1.ServiceBrokerAuthInfo) *v1beta1.LocalObjectReference {
+	if authInfo == nil || authInfo.Basic == nil {
+		return nil
+	}
+	return authInfo.Basic.SecretRef
+}
+
 func (pc *PlatformClient) isClusterScoped() bool {
-	return len(pc.targetNamespace) == 0
+	return len(pc.targetNamespaces) == 0 && len(pc.namespaceSelector) == 0
 }
 
 // GetVisibilitiesByBrokers get currently available visibilities in the platform for specific broker names
-func (pc *PlatformClient) GetVisibilitiesByBrokers(ctx context.Context, brokers []string) ([]*platform.Visibility, error) {
-	// This will cause all brokers to re-fetch their catalogs
-	return nil, nil
+func (pc *PlatformClient) GetVisibilitiesByBrokers(ctx context.Context, brokers []string) (_ []*platform.Visibility, err error) {
+	ctx, span := startSpan(ctx, "PlatformClient.GetVisibilitiesByBrokers")
+	defer endSpan(span, &err)
+
+	visibilities := make([]*platform.Visibility, 0)
+
+	brokerNames := sets.NewString()
+	namespaceBrokerNames := make(map[string]sets.String)
+	for _, brokerName := range brokers {
+		scope, err := pc.brokerScopeByName(ctx, brokerName)
+		if err != nil {
+			return nil, err
+		}
+		cluster, namespaces := pc.registrationScope(scope)
+		if cluster {
+			brokerNames.Insert(brokerName)
+			continue
+		}
+		for _, namespace := range namespaces {
+			if _, found := namespaceBrokerNames[namespace]; !found {
+				namespaceBrokerNames[namespace] = sets.NewString()
+			}
+			namespaceBrokerNames[namespace].Insert(brokerName)
+		}
+	}
+
+	if brokerNames.Len() > 0 {
+		clusterBrokers, err := pc.platformAPI.RetrieveClusterServiceBrokers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list cluster-scoped brokers (%s)", err)
+		}
+
+		for _, broker := range clusterBrokers.Items {
+			if !brokerNames.Has(broker.Name) {
+				continue
+			}
+
+			planIDs, restricted := visiblePlanIDs(broker.Spec.CatalogRestrictions)
+			if !restricted {
+				plans, err := pc.platformAPI.RetrieveClusterServicePlans(ctx, broker.Name)
+				if err != nil {
+					return nil, fmt.Errorf("unable to list cluster-scoped plans of broker %s (%s)", broker.Name, err)
+				}
+				planIDs = clusterPlanIDs(plans)
+			}
+
+			visibilities = append(visibilities, publicVisibilities(broker.Name, planIDs)...)
+		}
+
+		namespaceVisibilities, err := pc.namespaceVisibilities(ctx, brokerNames)
+		if err != nil {
+			return nil, err
+		}
+
+		visibilities = append(visibilities, namespaceVisibilities...)
+	}
+
+	// a plan is visible only if it is visible in all target namespaces in which its broker is registered,
+	// so that plans which are enabled partially are enabled again
+	brokerPlanIDs := make(map[string]sets.String)
+	for _, namespace := range sets.StringKeySet(namespaceBrokerNames).List() {
+		brokerNames := namespaceBrokerNames[namespace]
+		namespaceBrokers, err := pc.platformAPI.RetrieveNamespaceServiceBrokers(ctx, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list namespace-scoped brokers in namespace %s (%s)", namespace, err)
+		}
+
+		for _, broker := range namespaceBrokers.Items {
+			if !brokerNames.Has(broker.Name) {
+				continue
+			}
+
+			planIDs, restricted := visiblePlanIDs(broker.Spec.CatalogRestrictions)
+			if !restricted {
+				plans, err := pc.platformAPI.RetrieveNamespaceServicePlans(ctx, broker.Name, namespace)
+				if err != nil {
+					return nil, fmt.Errorf("unable to list namespace-scoped plans of broker %s in namespace %s (%s)", broker.Name, namespace, err)
+				}
+				planIDs = namespacePlanIDs(plans)
+			}
+
+			if visiblePlans, found := brokerPlanIDs[broker.Name]; found {
+				planIDs = visiblePlans.Intersection(planIDs)
+			}
+			brokerPlanIDs[broker.Name] = planIDs
+		}
+	}
+
+	for _, brokerName := range sets.StringKeySet(brokerPlanIDs).List() {
+		visibilities = append(visibilities, publicVisibilities(brokerName, brokerPlanIDs[brokerName])...)
+	}
+
+	return visibilities, nil
 }
 
 // VisibilityScopeLabelKey returns a specific label key which should be used when converting SM visibilities to platform.Visibilities
+// Visibilities can be scoped to namespaces only if brokers are registered cluster-wide,
+// otherwise all visibilities apply to the target namespace.
 func (pc *PlatformClient) VisibilityScopeLabelKey() string {
+	if pc.isClusterScoped() {
+		return NamespacesLabelKey
+	}
 	return ""
 }
 
 // EnableAccessForPlan enables the access for the specified plan
-func (pc *PlatformClient) EnableAccessForPlan(ctx context.Context, request *platform.ModifyPlanAccessRequest) error {
-	if pc.isClusterScoped() {
-		return pc.platformAPI.SyncClusterServiceBroker(request.BrokerName, resyncBrokerRetryCount)
-	}
+func (pc *PlatformClient) EnableAccessForPlan(ctx context.Context, request *platform.ModifyPlanAccessRequest) (err error) {
+	ctx, span := startSpan(ctx, "PlatformClient.EnableAccessForPlan",
+		brokerAttributeKey.String(request.BrokerName), planIDAttributeKey.String(request.CatalogPlanID))
+	defer endSpan(span, &err)
 
-	return pc.platformAPI.SyncNamespaceServiceBroker(request.BrokerName, pc.targetNamespace, resyncBrokerRetryCount)
+	return pc.modifyAccess(ctx, request, true)
 }
 
 // DisableAccessForPlan disables the access for the specified plan
-func (pc *PlatformClient) DisableAccessForPlan(ctx context.Context, request *platform.ModifyPlanAccessRequest) error {
-	if pc.isClusterScoped() {
-		return pc.platformAPI.SyncClusterServiceBroker(request.BrokerName, resyncBrokerRetryCount)
+func (pc *PlatformClient) DisableAccessForPlan(ctx context.Context, request *platform.ModifyPlanAccessRequest) (err error) {
+	ctx, span := startSpan(ctx, "PlatformClient.DisableAccessForPlan",
+		brokerAttributeKey.String(request.BrokerName), planIDAttributeKey.String(request.CatalogPlanID))
+	defer endSpan(span, &err)
+
+	return pc.modifyAccess(ctx, request, false)
+}
+
+func (pc *PlatformClient) modifyAccess(ctx context.Context, request *platform.ModifyPlanAccessRequest, enabled bool) error {
+	ctx = withBrokerLogger(ctx, request.BrokerName, "")
+	if pc.skippedAsFollower(ctx, "modifying plan access") {
+		return nil
 	}
+	if pc.skipped(ctx, request.BrokerName, pc.planAccessBrokerID(ctx, request.BrokerName), "modifying plan access") {
+		return nil
+	}
+
+	scope, err := pc.brokerScopeByName(ctx, request.BrokerName)
+	if err != nil {
+		return err
+	}
+	cluster, brokerNamespaces := pc.registrationScope(scope)
+	namespaces := request.Labels[pc.VisibilityScopeLabelKey()]
+	if len(namespaces) == 0 {
+		return pc.modifyPlanAccess(ctx, request, cluster, brokerNamespaces, enabled)
+	}
+
+	if !cluster {
+		// brokers registered in namespaces expose their plans only in the namespaces of the visibility
+		return pc.modifyPlanAccess(ctx, request, false, sets.NewString(brokerNamespaces...).Intersection(sets.NewString(namespaces...)).List(), enabled)
+	}
+
+	if enabled {
+		// the brokers in the namespaces exist only to restrict the visible plans, so the plan ID must be usable in their
+		// restrictions; it is checked beforehand, so that it does not fail the changes which are applied together with it
+		if err := validateCatalogPlanID(request.CatalogPlanID); err != nil {
+			return err
+		}
+	}
+	for _, namespace := range namespaces {
+		if err := pc.modifyNamespacePlanAccess(ctx, request, namespace, enabled); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-	return pc.platformAPI.SyncNamespaceServiceBroker(request.BrokerName, pc.targetNamespace, resyncBrokerRetryCount)
+// modifyPlanAccess updates the catalog restrictions of the broker so that service-catalog relists
+// its catalog with the plan added or removed. Brokers without plan restrictions are restricted
+// to the plans which are currently in the cluster before the plan access is modified, unless one of the plans
+// cannot be used in catalog restrictions.
+// Changes of the same broker within the visibility debounce window are applied with a single update.
+func (pc *PlatformClient) modifyPlanAccess(ctx context.Context, request *platform.ModifyPlanAccessRequest, cluster bool, namespaces []string, enabled bool) error {
+	change := planAccessChange{catalogPlanID: request.CatalogPlanID, enabled: enabled}
+	if cluster {
+		err := pc.planAccess.modify(ctx, brokerSyncKey{clusterScoped: true, name: request.BrokerName}, change, func(ctx context.Context, changes []planAccessChange) error {
+			return pc.modifyClusterBrokerPlanAccess(ctx, request.BrokerName, changes)
+		})
+		if err != nil {
+			return fmt.Errorf("unable to modify access for plan %s of broker %s (%s)", request.CatalogPlanID, request.BrokerName, err)
+		}
+
+		return nil
+	}
+
+	var errs []error
+	for _, namespace := range namespaces {
+		namespace := namespace
+		err := pc.planAccess.modify(ctx, brokerSyncKey{namespace: namespace, name: request.BrokerName}, change, func(ctx context.Context, changes []planAccessChange) error {
+			return pc.modifyNamespaceBrokerPlanAccess(ctx, request.BrokerName, namespace, changes)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to modify access for plan %s of broker %s in namespace %s (%s)", request.CatalogPlanID, request.BrokerName, namespace, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// modifyClusterBrokerPlanAccess applies the plan access changes to the catalog restrictions of the cluster-scoped broker
+func (pc *PlatformClient) modifyClusterBrokerPlanAccess(ctx context.Context, brokerName string, changes []planAccessChange) error {
+	ctx = withPlanAccessChanges(ctx, changes)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		broker, err := pc.platformAPI.RetrieveClusterServiceBrokerByName(ctx, brokerName)
+		if err != nil {
+			return err
+		}
+
+		planIDs, restricted := visiblePlanIDs(broker.Spec.CatalogRestrictions)
+		if !restricted {
+			plans, err := pc.platformAPI.RetrieveClusterServicePlans(ctx, broker.Name)
+			if err != nil {
+				return err
+			}
+			planIDs = clusterPlanIDs(plans)
+		}
+
+		if !setPlansAccess(planIDs, changes) {
+			return nil
+		}
+		restrictions, changed := brokerPlanRestrictions(ctx, broker.Name, broker.Spec.CatalogRestrictions, planIDs, restricted)
+		if !changed {
+			return nil
+		}
+
+		broker.Spec.CatalogRestrictions = restrictions
+		_, err = pc.platformAPI.UpdateClusterServiceBroker(ctx, broker)
+		return err
+	})
+}
+
+// modifyNamespaceBrokerPlanAccess applies the plan access changes to the catalog restrictions of the broker in the namespace
+func (pc *PlatformClient) modifyNamespaceBrokerPlanAccess(ctx context.Context, brokerName, namespace string, changes []planAccessChange) error {
+	ctx = withPlanAccessChanges(ctx, changes)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		broker, err := pc.platformAPI.RetrieveNamespaceServiceBrokerByName(ctx, brokerName, namespace)
+		if err != nil {
+			return err
+		}
+
+		planIDs, restricted := visiblePlanIDs(broker.Spec.CatalogRestrictions)
+		if !restricted {
+			plans, err := pc.platformAPI.RetrieveNamespaceServicePlans(ctx, broker.Name, namespace)
+			if err != nil {
+				return err
+			}
+			planIDs = namespacePlanIDs(plans)
+		}
+
+		if !setPlansAccess(planIDs, changes) {
+			return nil
+		}
+		restrictions, changed := brokerPlanRestrictions(ctx, broker.Name, broker.Spec.CatalogRestrictions, planIDs, restricted)
+		if !changed {
+			return nil
+		}
+
+		broker.Spec.CatalogRestrictions = restrictions
+		_, err = pc.platformAPI.UpdateNamespaceServiceBroker(ctx, broker, namespace)
+		return err
+	})
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	v1core "k8s.io/api/core/v1"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Peripli/service-broker-proxy/pkg/sbproxy"
 
@@ -12,16 +14,40 @@ import (
 
 	"github.com/Peripli/service-broker-proxy-k8s/pkg/k8s/config"
 	"github.com/Peripli/service-broker-proxy/pkg/platform"
-
+	"github.com/Peripli/service-broker-proxy/pkg/sm"
+	"github.com/Peripli/service-broker-proxy/pkg/sm/smfakes"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 
 	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	svcatfake "github.com/kubernetes-sigs/service-catalog/pkg/client/clientset_generated/clientset/fake"
 	servicecatalog "github.com/kubernetes-sigs/service-catalog/pkg/svcat/service-catalog"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubernetesTypes "k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 )
 
 func TestClient(t *testing.T) {
@@ -40,12 +66,14 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 		settings      *config.Settings
 		ctx           context.Context
 		k8sApi        *apifakes.FakeKubernetesAPI
+		smClient      *smfakes.FakeClient
 	)
 
 	newDefaultPlatformClient := func() *PlatformClient {
 		client, err := NewClient(settings)
 		Expect(err).ToNot(HaveOccurred())
 		client.platformAPI = k8sApi
+		client.smClient = smClient
 		return client
 	}
 
@@ -79,6 +107,8 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 		}
 		ctx = context.TODO()
 		k8sApi = &apifakes.FakeKubernetesAPI{}
+		smClient = &smfakes.FakeClient{}
+		k8sApi.RetrieveNamespaceServiceBrokersReturns(&v1beta1.ServiceBrokerList{}, nil)
 	})
 
 	Describe("New Client", func() {
@@ -127,7 +157,8 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns broker", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.CreateClusterServiceBrokerStub = func(broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+					k8sApi.CreateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+						Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in ()"))
 						return &v1beta1.ClusterServiceBroker{
 							ObjectMeta: v1.ObjectMeta{
 								UID:  "1234",
@@ -149,7 +180,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 						Password:  "admin",
 					}
 
-					k8sApi.CreateSecretStub = func(secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
+					k8sApi.CreateSecretStub = func(_ context.Context, secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
 						Expect(secret2.Name).To(Equal(requestBroker.ID))
 						Expect(string(secret2.Data["username"])).To(Equal(requestBroker.Username))
 						Expect(string(secret2.Data["password"])).To(Equal(requestBroker.Password))
@@ -168,10 +199,10 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns error", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.CreateSecretStub = func(secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
+					k8sApi.CreateSecretStub = func(_ context.Context, secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
 						return secret2, nil
 					}
-					k8sApi.CreateClusterServiceBrokerStub = func(broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+					k8sApi.CreateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
 						return nil, errors.New("error from service-catalog")
 					}
 
@@ -189,7 +220,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns no error", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.DeleteClusterServiceBrokerStub = func(name string, options *v1.DeleteOptions) error {
+					k8sApi.DeleteClusterServiceBrokerStub = func(_ context.Context, name string, options *v1.DeleteOptions) error {
 						return nil
 					}
 
@@ -208,7 +239,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns the error", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.DeleteClusterServiceBrokerStub = func(name string, options *v1.DeleteOptions) error {
+					k8sApi.DeleteClusterServiceBrokerStub = func(_ context.Context, name string, options *v1.DeleteOptions) error {
 						return errors.New("error deleting clusterservicebroker")
 					}
 
@@ -225,12 +256,13 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns brokers", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.RetrieveClusterServiceBrokersStub = func() (*v1beta1.ClusterServiceBrokerList, error) {
+					k8sApi.RetrieveClusterServiceBrokersStub = func(_ context.Context) (*v1beta1.ClusterServiceBrokerList, error) {
 						brokers := make([]v1beta1.ClusterServiceBroker, 0)
 						brokers = append(brokers, v1beta1.ClusterServiceBroker{
 							ObjectMeta: v1.ObjectMeta{
-								UID:  "1234",
-								Name: fakeBrokerName,
+								UID:    "1234",
+								Name:   fakeBrokerName,
+								Labels: ownedLabels(),
 							},
 							Spec: v1beta1.ClusterServiceBrokerSpec{
 								CommonServiceBrokerSpec: v1beta1.CommonServiceBrokerSpec{
@@ -258,7 +290,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns empty array", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.RetrieveClusterServiceBrokersStub = func() (*v1beta1.ClusterServiceBrokerList, error) {
+					k8sApi.RetrieveClusterServiceBrokersStub = func(_ context.Context) (*v1beta1.ClusterServiceBrokerList, error) {
 						brokers := make([]v1beta1.ClusterServiceBroker, 0)
 						return &v1beta1.ClusterServiceBrokerList{
 							Items: brokers,
@@ -277,7 +309,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns the error", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.RetrieveClusterServiceBrokersStub = func() (*v1beta1.ClusterServiceBrokerList, error) {
+					k8sApi.RetrieveClusterServiceBrokersStub = func(_ context.Context) (*v1beta1.ClusterServiceBrokerList, error) {
 						return nil, errors.New("error getting clusterservicebrokers")
 					}
 
@@ -295,11 +327,12 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns the service broker", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.RetrieveClusterServiceBrokerByNameStub = func(name string) (*v1beta1.ClusterServiceBroker, error) {
+					k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
 						return &v1beta1.ClusterServiceBroker{
 							ObjectMeta: v1.ObjectMeta{
-								UID:  "1234",
-								Name: fakeBrokerName,
+								UID:    "1234",
+								Name:   fakeBrokerName,
+								Labels: ownedLabels(),
 							},
 							Spec: v1beta1.ClusterServiceBrokerSpec{
 								CommonServiceBrokerSpec: v1beta1.CommonServiceBrokerSpec{
@@ -321,7 +354,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns the error", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.RetrieveClusterServiceBrokerByNameStub = func(name string) (*v1beta1.ClusterServiceBroker, error) {
+					k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
 						return nil, errors.New("error getting clusterservicebroker")
 					}
 
@@ -338,8 +371,9 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 			Context("with no errors", func() {
 				It("returns updated broker", func() {
 					platformClient := newDefaultPlatformClient()
+					k8sApi.RetrieveClusterServiceBrokerByNameReturns(newRestrictedClusterServiceBroker(fakeBrokerName), nil)
 
-					k8sApi.UpdateClusterServiceBrokerStub = func(broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+					k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
 						// Return a new fake clusterservicebroker with the three attributes relevant for the OSBAPI guid, name and broker url.
 						// UID and name cannot be modified, url can be modified
 						return &v1beta1.ClusterServiceBroker{
@@ -364,7 +398,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 						Password:  "admin",
 					}
 
-					k8sApi.UpdateServiceBrokerCredentialsStub = func(secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
+					k8sApi.UpdateServiceBrokerCredentialsStub = func(_ context.Context, secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
 						Expect(secret2.Name).To(Equal(requestBroker.ID))
 						Expect(string(secret2.Data["username"])).To(Equal(requestBroker.Username))
 						Expect(string(secret2.Data["password"])).To(Equal(requestBroker.Password))
@@ -383,11 +417,12 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 			Context("with an error", func() {
 				It("returns the error", func() {
 					platformClient := newDefaultPlatformClient()
+					k8sApi.RetrieveClusterServiceBrokerByNameReturns(newRestrictedClusterServiceBroker(fakeBrokerName), nil)
 
-					k8sApi.UpdateServiceBrokerCredentialsStub = func(secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
+					k8sApi.UpdateServiceBrokerCredentialsStub = func(_ context.Context, secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
 						return secret2, nil
 					}
-					k8sApi.UpdateClusterServiceBrokerStub = func(broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+					k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
 						return nil, errors.New("error updating clusterservicebroker")
 					}
 
@@ -399,6 +434,65 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 					Expect(err).To(Equal(errors.New("error updating clusterservicebroker")))
 				})
 			})
+
+			Context("with an existing broker", func() {
+				It("changes only the URL, the configured relist behavior and the credentials secret reference", func() {
+					settings.K8S.RelistBehavior = config.ManualRelistBehavior
+					platformClient := newDefaultPlatformClient()
+					existingBroker := newRestrictedClusterServiceBroker(fakeBrokerName, "spec.externalID in (plan-1)")
+					existingBroker.ResourceVersion = "42"
+					existingBroker.Annotations = map[string]string{"team": "a"}
+					existingBroker.Spec.RelistBehavior = "Duration"
+					existingBroker.Spec.CABundle = []byte("ca")
+					k8sApi.RetrieveClusterServiceBrokerByNameReturns(existingBroker, nil)
+					k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+						return broker, nil
+					}
+
+					_, err := platformClient.UpdateBroker(ctx, &platform.UpdateServiceBrokerRequest{
+						ID:        "id-in-sm",
+						Name:      fakeBrokerName,
+						BrokerURL: fakeBrokerUrl,
+					})
+
+					Expect(err).ToNot(HaveOccurred())
+					_, broker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+					Expect(broker.ResourceVersion).To(Equal("42"))
+					Expect(broker.Labels).To(Equal(ownedLabels()))
+					Expect(broker.Annotations).To(HaveKeyWithValue("team", "a"))
+					Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorManual))
+					Expect(broker.Spec.RelistDuration).To(BeNil())
+					Expect(broker.Spec.CABundle).To(Equal([]byte("ca")))
+					Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-1)"))
+					Expect(broker.Spec.URL).To(Equal(fakeBrokerUrl))
+					Expect(*broker.Spec.AuthInfo.Basic.SecretRef).To(Equal(v1beta1.ObjectReference{Name: "id-in-sm", Namespace: "secretNamespace"}))
+				})
+
+				It("retries on conflicts with the current broker", func() {
+					platformClient := newDefaultPlatformClient()
+					k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+						return newRestrictedClusterServiceBroker(name), nil
+					}
+					k8sApi.UpdateClusterServiceBrokerReturnsOnCall(0, nil, apierrors.NewConflict(v1beta1.Resource("clusterservicebrokers"), fakeBrokerName, expectedError))
+					k8sApi.UpdateClusterServiceBrokerReturnsOnCall(1, newRestrictedClusterServiceBroker(fakeBrokerName), nil)
+
+					_, err := platformClient.UpdateBroker(ctx, &platform.UpdateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+					Expect(err).ToNot(HaveOccurred())
+					Expect(k8sApi.RetrieveClusterServiceBrokerByNameCallCount()).To(BeNumerically(">=", 2))
+					Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(2))
+				})
+
+				It("returns the error when the broker cannot be retrieved", func() {
+					platformClient := newDefaultPlatformClient()
+					k8sApi.RetrieveClusterServiceBrokerByNameReturns(nil, expectedError)
+
+					_, err := platformClient.UpdateBroker(ctx, &platform.UpdateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+					Expect(err).To(Equal(expectedError))
+					Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(BeZero())
+				})
+			})
 		})
 
 		Describe("Fetch the catalog information of a service broker", func() {
@@ -415,13 +509,14 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 						Password:  "admin",
 					}
 
-					k8sApi.UpdateServiceBrokerCredentialsStub = func(secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
+					k8sApi.UpdateServiceBrokerCredentialsStub = func(_ context.Context, secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
 						Expect(secret2.Name).To(Equal(requestBroker.ID))
 						Expect(string(secret2.Data["username"])).To(Equal(requestBroker.Username))
 						Expect(string(secret2.Data["password"])).To(Equal(requestBroker.Password))
 						return secret2, nil
 					}
-					k8sApi.SyncClusterServiceBrokerStub = func(name string, retries int) error {
+					k8sApi.RetrieveClusterServiceBrokerByNameReturns(newRestrictedClusterServiceBroker(fakeBrokerName), nil)
+					k8sApi.SyncClusterServiceBrokerStub = func(_ context.Context, name string, retries int) error {
 						return nil
 					}
 
@@ -436,10 +531,10 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 					platformClient := newDefaultPlatformClient()
 
 					requestBroker := &platform.UpdateServiceBrokerRequest{}
-					k8sApi.UpdateServiceBrokerCredentialsStub = func(secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
+					k8sApi.UpdateServiceBrokerCredentialsStub = func(_ context.Context, secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
 						return secret2, nil
 					}
-					k8sApi.SyncClusterServiceBrokerStub = func(name string, retries int) error {
+					k8sApi.SyncClusterServiceBrokerStub = func(_ context.Context, name string, retries int) error {
 						return errors.New("error syncing service broker")
 					}
 
@@ -451,36 +546,145 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 		})
 
 		Describe("EnableAccessForPlan", func() {
-			It("should call Fetch", func() {
-				platformClient := newDefaultPlatformClient()
-				k8sApi.SyncClusterServiceBrokerStub = func(name string, retries int) error {
-					return expectedError
+			var updatedBroker *v1beta1.ClusterServiceBroker
+
+			BeforeEach(func() {
+				updatedBroker = nil
+				k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+					updatedBroker = broker
+					return broker, nil
 				}
-				Expect(platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{})).To(Equal(expectedError))
+			})
+
+			Context("when the broker has plan restrictions", func() {
+				BeforeEach(func() {
+					k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+						return newRestrictedClusterServiceBroker(name, "spec.externalID in (plan-1)"), nil
+					}
+				})
+
+				It("adds the plan to the catalog restrictions", func() {
+					platformClient := newDefaultPlatformClient()
+					err := platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+						BrokerName:    fakeBrokerName,
+						CatalogPlanID: "plan-2",
+					})
+
+					Expect(err).ToNot(HaveOccurred())
+					Expect(updatedBroker.Name).To(Equal(fakeBrokerName))
+					Expect(updatedBroker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-1, plan-2)"))
+				})
+
+				It("does not update the broker if the plan is already enabled", func() {
+					platformClient := newDefaultPlatformClient()
+					err := platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+						BrokerName:    fakeBrokerName,
+						CatalogPlanID: "plan-1",
+					})
+
+					Expect(err).ToNot(HaveOccurred())
+					Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(0))
+				})
+
+				It("retries on conflict", func() {
+					platformClient := newDefaultPlatformClient()
+					k8sApi.UpdateClusterServiceBrokerReturnsOnCall(0, nil, apierrors.NewConflict(v1beta1.Resource("clusterservicebrokers"), fakeBrokerName, expectedError))
+					k8sApi.UpdateClusterServiceBrokerReturnsOnCall(1, &v1beta1.ClusterServiceBroker{}, nil)
+					k8sApi.UpdateClusterServiceBrokerStub = nil
+
+					err := platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+						BrokerName:    fakeBrokerName,
+						CatalogPlanID: "plan-2",
+					})
+
+					Expect(err).ToNot(HaveOccurred())
+					Expect(k8sApi.RetrieveClusterServiceBrokerByNameCallCount()).To(Equal(2))
+					Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(2))
+				})
+			})
+
+			Context("when the broker has no plan restrictions", func() {
+				It("restricts the broker to the plans in the cluster and the enabled plan", func() {
+					platformClient := newDefaultPlatformClient()
+					k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+						return newRestrictedClusterServiceBroker(name, "spec.free=true"), nil
+					}
+					k8sApi.RetrieveClusterServicePlansStub = func(_ context.Context, brokerName string) (*v1beta1.ClusterServicePlanList, error) {
+						Expect(brokerName).To(Equal(fakeBrokerName))
+						return newClusterServicePlanList("plan-1"), nil
+					}
+
+					err := platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+						BrokerName:    fakeBrokerName,
+						CatalogPlanID: "plan-2",
+					})
+
+					Expect(err).ToNot(HaveOccurred())
+					Expect(updatedBroker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.free=true", "spec.externalID in (plan-1, plan-2)"))
+				})
+			})
+
+			Context("when the broker cannot be retrieved", func() {
+				It("returns the error", func() {
+					platformClient := newDefaultPlatformClient()
+					k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+						return nil, expectedError
+					}
+
+					err := platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+						BrokerName:    fakeBrokerName,
+						CatalogPlanID: "plan-2",
+					})
+
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring(expectedError.Error()))
+					Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(0))
+				})
 			})
 		})
 
 		Describe("DisableAccessForPlan", func() {
+			var updatedBroker *v1beta1.ClusterServiceBroker
 
-			It("should call Fetch", func() {
+			BeforeEach(func() {
+				updatedBroker = nil
+				k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+					updatedBroker = broker
+					return broker, nil
+				}
+			})
+
+			It("removes the plan from the catalog restrictions", func() {
 				platformClient := newDefaultPlatformClient()
-				k8sApi.SyncClusterServiceBrokerStub = func(name string, retries int) error {
-					return expectedError
+				k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+					return newRestrictedClusterServiceBroker(name, "spec.externalID in (plan-1, plan-2)"), nil
 				}
-				Expect(platformClient.DisableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{})).To(Equal(expectedError))
+
+				err := platformClient.DisableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+					BrokerName:    fakeBrokerName,
+					CatalogPlanID: "plan-2",
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(updatedBroker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-1)"))
 			})
-		})
 
-		Describe("Concurrent Modification", func() {
-			It("visibility for the same broker", func() {
-				NewClient(settings)
-				platformClient, err := NewClient(settings)
+			It("hides all plans when the last plan is disabled", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+					return newRestrictedClusterServiceBroker(name), nil
+				}
+				k8sApi.RetrieveClusterServicePlansStub = func(_ context.Context, brokerName string) (*v1beta1.ClusterServicePlanList, error) {
+					return newClusterServicePlanList("plan-1"), nil
+				}
+
+				err := platformClient.DisableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+					BrokerName:    fakeBrokerName,
+					CatalogPlanID: "plan-1",
+				})
+
 				Expect(err).ToNot(HaveOccurred())
-				scat := platformClient.platformAPI.(*ServiceCatalogAPI)
-				scat.setBrokerInProgress("test")
-				expectedError := platformClient.platformAPI.SyncClusterServiceBroker("test", 1)
-				Expect(expectedError).NotTo(HaveOccurred())
-				scat.unsetBrokerInProgress("test")
+				Expect(updatedBroker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in ()"))
 			})
 		})
 	})
@@ -495,7 +699,8 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns broker", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.CreateNamespaceServiceBrokerStub = func(broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					k8sApi.CreateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+						Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in ()"))
 						return &v1beta1.ServiceBroker{
 							ObjectMeta: v1.ObjectMeta{
 								UID:  "1234",
@@ -517,7 +722,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 						Password:  "admin",
 					}
 
-					k8sApi.CreateSecretStub = func(secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
+					k8sApi.CreateSecretStub = func(_ context.Context, secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
 						Expect(secret2.Name).To(Equal(requestBroker.ID))
 						Expect(string(secret2.Data["username"])).To(Equal(requestBroker.Username))
 						Expect(string(secret2.Data["password"])).To(Equal(requestBroker.Password))
@@ -536,10 +741,10 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns error", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.CreateSecretStub = func(secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
+					k8sApi.CreateSecretStub = func(_ context.Context, secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
 						return secret2, nil
 					}
-					k8sApi.CreateNamespaceServiceBrokerStub = func(broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					k8sApi.CreateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
 						return nil, errors.New("error from service-catalog")
 					}
 
@@ -547,7 +752,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 					createdBroker, err := platformClient.CreateBroker(ctx, requestBroker)
 
 					Expect(createdBroker).To(BeNil())
-					Expect(err).To(Equal(errors.New("error from service-catalog")))
+					Expect(err).To(MatchError("unable to create broker  in namespace test-namespace (error from service-catalog)"))
 				})
 			})
 		})
@@ -557,7 +762,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns no error", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.DeleteNamespaceServiceBrokerStub = func(name, namespace string, options *v1.DeleteOptions) error {
+					k8sApi.DeleteNamespaceServiceBrokerStub = func(_ context.Context, name, namespace string, options *v1.DeleteOptions) error {
 						return nil
 					}
 
@@ -577,7 +782,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns the error", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.DeleteNamespaceServiceBrokerStub = func(name, namespace string, options *v1.DeleteOptions) error {
+					k8sApi.DeleteNamespaceServiceBrokerStub = func(_ context.Context, name, namespace string, options *v1.DeleteOptions) error {
 						return errors.New("error deleting servicebroker")
 					}
 
@@ -585,7 +790,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 
 					err := platformClient.DeleteBroker(ctx, requestBroker)
 
-					Expect(err).To(Equal(errors.New("error deleting servicebroker")))
+					Expect(err).To(MatchError("unable to delete broker  in namespace test-namespace (error deleting servicebroker)"))
 				})
 			})
 		})
@@ -595,12 +800,13 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns brokers", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.RetrieveNamespaceServiceBrokersStub = func(namespace string) (*v1beta1.ServiceBrokerList, error) {
+					k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
 						brokers := make([]v1beta1.ServiceBroker, 0)
 						brokers = append(brokers, v1beta1.ServiceBroker{
 							ObjectMeta: v1.ObjectMeta{
-								UID:  "1234",
-								Name: fakeBrokerName,
+								UID:    "1234",
+								Name:   fakeBrokerName,
+								Labels: ownedLabels(),
 							},
 							Spec: v1beta1.ServiceBrokerSpec{
 								CommonServiceBrokerSpec: v1beta1.CommonServiceBrokerSpec{
@@ -628,7 +834,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns empty array", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.RetrieveNamespaceServiceBrokersStub = func(namespace string) (*v1beta1.ServiceBrokerList, error) {
+					k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
 						brokers := make([]v1beta1.ServiceBroker, 0)
 						return &v1beta1.ServiceBrokerList{
 							Items: brokers,
@@ -647,7 +853,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns the error", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.RetrieveNamespaceServiceBrokersStub = func(namespace string) (*v1beta1.ServiceBrokerList, error) {
+					k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
 						return nil, errors.New("error getting servicebrokers")
 					}
 
@@ -665,11 +871,12 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns the service broker", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(name, namespace string) (*v1beta1.ServiceBroker, error) {
+					k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
 						return &v1beta1.ServiceBroker{
 							ObjectMeta: v1.ObjectMeta{
-								UID:  "1234",
-								Name: fakeBrokerName,
+								UID:    "1234",
+								Name:   fakeBrokerName,
+								Labels: ownedLabels(),
 							},
 							Spec: v1beta1.ServiceBrokerSpec{
 								CommonServiceBrokerSpec: v1beta1.CommonServiceBrokerSpec{
@@ -691,7 +898,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				It("returns the error", func() {
 					platformClient := newDefaultPlatformClient()
 
-					k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(name, namespace string) (*v1beta1.ServiceBroker, error) {
+					k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
 						return nil, errors.New("error getting servicebroker")
 					}
 
@@ -708,8 +915,9 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 			Context("with no errors", func() {
 				It("returns updated broker", func() {
 					platformClient := newDefaultPlatformClient()
+					k8sApi.RetrieveNamespaceServiceBrokerByNameReturns(newRestrictedNamespaceServiceBroker(fakeBrokerName, "team-a"), nil)
 
-					k8sApi.UpdateNamespaceServiceBrokerStub = func(broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					k8sApi.UpdateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
 						// Return a new fake clusterservicebroker with the three attributes relevant for the OSBAPI guid, name and broker url.
 						// UID and name cannot be modified, url can be modified
 						return &v1beta1.ServiceBroker{
@@ -734,7 +942,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 						Password:  "admin",
 					}
 
-					k8sApi.UpdateServiceBrokerCredentialsStub = func(secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
+					k8sApi.UpdateServiceBrokerCredentialsStub = func(_ context.Context, secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
 						Expect(secret2.Name).To(Equal(requestBroker.ID))
 						Expect(string(secret2.Data["username"])).To(Equal(requestBroker.Username))
 						Expect(string(secret2.Data["password"])).To(Equal(requestBroker.Password))
@@ -753,11 +961,12 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 			Context("with an error", func() {
 				It("returns the error", func() {
 					platformClient := newDefaultPlatformClient()
+					k8sApi.RetrieveNamespaceServiceBrokerByNameReturns(newRestrictedNamespaceServiceBroker(fakeBrokerName, "team-a"), nil)
 
-					k8sApi.UpdateServiceBrokerCredentialsStub = func(secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
+					k8sApi.UpdateServiceBrokerCredentialsStub = func(_ context.Context, secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
 						return secret2, nil
 					}
-					k8sApi.UpdateNamespaceServiceBrokerStub = func(broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					k8sApi.UpdateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
 						return nil, errors.New("error updating servicebroker")
 					}
 
@@ -766,7 +975,7 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 					broker, err := platformClient.UpdateBroker(ctx, requestBroker)
 
 					Expect(broker).To(BeNil())
-					Expect(err).To(Equal(errors.New("error updating servicebroker")))
+					Expect(err).To(MatchError("unable to update broker  in namespace test-namespace (error updating servicebroker)"))
 				})
 			})
 		})
@@ -785,13 +994,16 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 						Password:  "admin",
 					}
 
-					k8sApi.UpdateServiceBrokerCredentialsStub = func(secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
+					k8sApi.UpdateServiceBrokerCredentialsStub = func(_ context.Context, secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
 						Expect(secret2.Name).To(Equal(requestBroker.ID))
 						Expect(string(secret2.Data["username"])).To(Equal(requestBroker.Username))
 						Expect(string(secret2.Data["password"])).To(Equal(requestBroker.Password))
 						return secret2, nil
 					}
-					k8sApi.SyncNamespaceServiceBrokerStub = func(name, namespace string, retries int) error {
+					k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+						return newRestrictedNamespaceServiceBroker(name, namespace), nil
+					}
+					k8sApi.SyncNamespaceServiceBrokerStub = func(_ context.Context, name, namespace string, retries int) error {
 						return nil
 					}
 
@@ -806,76 +1018,2780 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 					platformClient := newDefaultPlatformClient()
 
 					requestBroker := &platform.UpdateServiceBrokerRequest{}
-					k8sApi.UpdateServiceBrokerCredentialsStub = func(secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
+					k8sApi.UpdateServiceBrokerCredentialsStub = func(_ context.Context, secret2 *v1core.Secret) (secret *v1core.Secret, err error) {
 						return secret2, nil
 					}
-					k8sApi.SyncNamespaceServiceBrokerStub = func(name, namespace string, retries int) error {
+					k8sApi.SyncNamespaceServiceBrokerStub = func(_ context.Context, name, namespace string, retries int) error {
 						return errors.New("error syncing service broker")
 					}
 
 					err := platformClient.Fetch(ctx, requestBroker)
 
-					Expect(err).To(Equal(errors.New("error syncing service broker")))
+					Expect(err).To(MatchError("unable to sync broker  in namespace test-namespace (error syncing service broker)"))
 				})
 			})
 		})
 
 		Describe("EnableAccessForPlan", func() {
-			It("should call Fetch", func() {
+			It("adds the plan to the catalog restrictions", func() {
 				platformClient := newDefaultPlatformClient()
-				k8sApi.SyncNamespaceServiceBrokerStub = func(name, namespace string, retries int) error {
-					return expectedError
+				k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+					Expect(namespace).To(Equal("test-namespace"))
+					return newRestrictedNamespaceServiceBroker(name, namespace), nil
+				}
+				k8sApi.RetrieveNamespaceServicePlansStub = func(_ context.Context, brokerName, namespace string) (*v1beta1.ServicePlanList, error) {
+					Expect(brokerName).To(Equal(fakeBrokerName))
+					Expect(namespace).To(Equal("test-namespace"))
+					return newServicePlanList("plan-1"), nil
 				}
-				Expect(platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{})).To(Equal(expectedError))
+				var updatedBroker *v1beta1.ServiceBroker
+				k8sApi.UpdateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					Expect(namespace).To(Equal("test-namespace"))
+					updatedBroker = broker
+					return broker, nil
+				}
+
+				err := platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+					BrokerName:    fakeBrokerName,
+					CatalogPlanID: "plan-2",
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(updatedBroker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-1, plan-2)"))
 			})
-		})
 
-		Describe("DisableAccessForPlan", func() {
+			It("lifts the plan restriction for plan IDs which cannot be used in restrictions", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+					return newRestrictedNamespaceServiceBroker(name, namespace, "spec.externalID in ()", "spec.free=true"), nil
+				}
+				planID := "ORG/8D4D4A1E-6C2E-4F57-A5B6-3C1F0A9E7B21-8D4D4A1E-6C2E-4F57-A5B6-3C1F0A9E7B21"
+
+				err := platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+					BrokerName:    fakeBrokerName,
+					CatalogPlanID: planID,
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.UpdateNamespaceServiceBrokerCallCount()).To(Equal(1))
+				_, broker, _ := k8sApi.UpdateNamespaceServiceBrokerArgsForCall(0)
+				Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.free=true"))
+			})
 
-			It("should call Fetch", func() {
+			It("leaves brokers with plan IDs which cannot be used in restrictions unrestricted", func() {
 				platformClient := newDefaultPlatformClient()
-				k8sApi.SyncNamespaceServiceBrokerStub = func(name, namespace string, retries int) error {
-					return expectedError
+				k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+					return newRestrictedNamespaceServiceBroker(name, namespace), nil
 				}
-				Expect(platformClient.DisableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{})).To(Equal(expectedError))
+				k8sApi.RetrieveNamespaceServicePlansReturns(newServicePlanList("plan-1", "plans/plan-2"), nil)
+
+				err := platformClient.DisableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+					BrokerName:    fakeBrokerName,
+					CatalogPlanID: "plan-1",
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.UpdateNamespaceServiceBrokerCallCount()).To(Equal(0))
 			})
 		})
 
-		Describe("Concurrent Modification", func() {
-			It("visibility for the same broker", func() {
-				NewClient(settings)
-				platformClient, err := NewClient(settings)
+		Describe("DisableAccessForPlan", func() {
+			It("removes the plan from the catalog restrictions", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+					return newRestrictedNamespaceServiceBroker(name, namespace, "spec.externalID in (plan-1, plan-2)"), nil
+				}
+				var updatedBroker *v1beta1.ServiceBroker
+				k8sApi.UpdateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					updatedBroker = broker
+					return broker, nil
+				}
+
+				err := platformClient.DisableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+					BrokerName:    fakeBrokerName,
+					CatalogPlanID: "plan-1",
+				})
+
 				Expect(err).ToNot(HaveOccurred())
-				scat := platformClient.platformAPI.(*ServiceCatalogAPI)
-				scat.setBrokerInProgress("test")
-				expectedError := platformClient.platformAPI.SyncNamespaceServiceBroker("test", "test-namespace", 1)
-				Expect(expectedError).NotTo(HaveOccurred())
-				scat.unsetBrokerInProgress("test")
+				Expect(updatedBroker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-2)"))
+			})
+
+			It("returns the error", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+					return newRestrictedNamespaceServiceBroker(name, namespace, "spec.externalID in (plan-1)"), nil
+				}
+				k8sApi.UpdateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					return nil, expectedError
+				}
+
+				err := platformClient.DisableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+					BrokerName:    fakeBrokerName,
+					CatalogPlanID: "plan-1",
+				})
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(expectedError.Error()))
 			})
 		})
 	})
 
 	Describe("GetVisibilitiesByBrokers", func() {
-		It("returns no visibilities", func() {
-			platformClient := newDefaultPlatformClient()
-			visibilities, err := platformClient.GetVisibilitiesByBrokers(ctx, []string{})
-			Expect(err).To(BeNil())
-			Expect(visibilities).To(BeNil())
+		Context("for cluster-scoped brokers", func() {
+			BeforeEach(func() {
+				k8sApi.RetrieveClusterServiceBrokersStub = func(_ context.Context) (*v1beta1.ClusterServiceBrokerList, error) {
+					return &v1beta1.ClusterServiceBrokerList{
+						Items: []v1beta1.ClusterServiceBroker{
+							*newRestrictedClusterServiceBroker("restricted-broker", "spec.externalID in (plan-1, plan-2)"),
+							*newRestrictedClusterServiceBroker("unrestricted-broker"),
+							*newRestrictedClusterServiceBroker("other-broker", "spec.externalID in (plan-4)"),
+						},
+					}, nil
+				}
+				k8sApi.RetrieveClusterServicePlansStub = func(_ context.Context, brokerName string) (*v1beta1.ClusterServicePlanList, error) {
+					Expect(brokerName).To(Equal("unrestricted-broker"))
+					return newClusterServicePlanList("plan-3"), nil
+				}
+			})
+
+			It("returns public visibilities for the plans visible through the requested brokers", func() {
+				platformClient := newDefaultPlatformClient()
+				visibilities, err := platformClient.GetVisibilitiesByBrokers(ctx, []string{"restricted-broker", "unrestricted-broker", "missing-broker"})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(visibilities).To(ConsistOf(
+					&platform.Visibility{Public: true, CatalogPlanID: "plan-1", PlatformBrokerName: "restricted-broker", Labels: map[string]string{}},
+					&platform.Visibility{Public: true, CatalogPlanID: "plan-2", PlatformBrokerName: "restricted-broker", Labels: map[string]string{}},
+					&platform.Visibility{Public: true, CatalogPlanID: "plan-3", PlatformBrokerName: "unrestricted-broker", Labels: map[string]string{}},
+				))
+			})
+
+			It("returns no visibilities when no brokers are requested", func() {
+				platformClient := newDefaultPlatformClient()
+				visibilities, err := platformClient.GetVisibilitiesByBrokers(ctx, []string{})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(visibilities).To(BeEmpty())
+			})
+
+			It("returns an error if the plans cannot be listed", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveClusterServicePlansStub = func(_ context.Context, brokerName string) (*v1beta1.ClusterServicePlanList, error) {
+					return nil, expectedError
+				}
+
+				visibilities, err := platformClient.GetVisibilitiesByBrokers(ctx, []string{"unrestricted-broker"})
+
+				Expect(visibilities).To(BeNil())
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(expectedError.Error()))
+			})
+
+			It("returns an error if the brokers cannot be listed", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveClusterServiceBrokersStub = func(_ context.Context) (*v1beta1.ClusterServiceBrokerList, error) {
+					return nil, expectedError
+				}
+
+				visibilities, err := platformClient.GetVisibilitiesByBrokers(ctx, []string{"restricted-broker"})
+
+				Expect(visibilities).To(BeNil())
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("for namespace-scoped brokers", func() {
+			BeforeEach(func() {
+				settings.K8S.TargetNamespace = "test-namespace"
+			})
+
+			It("returns public visibilities for the plans visible through the requested brokers", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+					Expect(namespace).To(Equal("test-namespace"))
+					return &v1beta1.ServiceBrokerList{
+						Items: []v1beta1.ServiceBroker{
+							*newRestrictedNamespaceServiceBroker("restricted-broker", namespace, "spec.externalID in (plan-1)"),
+							*newRestrictedNamespaceServiceBroker("unrestricted-broker", namespace),
+						},
+					}, nil
+				}
+				k8sApi.RetrieveNamespaceServicePlansStub = func(_ context.Context, brokerName, namespace string) (*v1beta1.ServicePlanList, error) {
+					Expect(brokerName).To(Equal("unrestricted-broker"))
+					Expect(namespace).To(Equal("test-namespace"))
+					return newServicePlanList("plan-2"), nil
+				}
+
+				visibilities, err := platformClient.GetVisibilitiesByBrokers(ctx, []string{"restricted-broker", "unrestricted-broker"})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(visibilities).To(ConsistOf(
+					&platform.Visibility{Public: true, CatalogPlanID: "plan-1", PlatformBrokerName: "restricted-broker", Labels: map[string]string{}},
+					&platform.Visibility{Public: true, CatalogPlanID: "plan-2", PlatformBrokerName: "unrestricted-broker", Labels: map[string]string{}},
+				))
+			})
 		})
 	})
 
 	Describe("VisibilityScopeLabelKey", func() {
-		It("returns empty string", func() {
+		It("returns the namespaces label for cluster-scoped brokers", func() {
+			Expect(newDefaultPlatformClient().VisibilityScopeLabelKey()).To(Equal(NamespacesLabelKey))
+		})
+
+		It("returns empty string for namespace-scoped brokers", func() {
+			settings.K8S.TargetNamespace = "test-namespace"
 			Expect(newDefaultPlatformClient().VisibilityScopeLabelKey()).To(BeEmpty())
 		})
 	})
 
-	Describe("Platform Broker Name", func() {
-		It("returns lower case and replaces underscores to hyphens", func() {
-			brokerNameWithUnderscoreAndCaps := "Fake_Broker-Name_1234"
-			expectedBrokerName := "fake-broker-name-1234"
-			platformClient := newDefaultPlatformClient()
-			Expect(platformClient.GetBrokerPlatformName(brokerNameWithUnderscoreAndCaps)).To(Equal(expectedBrokerName))
+	Describe("Namespace-scoped visibilities", func() {
+		var namespaceVisibilityBroker *v1beta1.ServiceBroker
+
+		newModifyPlanAccessRequest := func(catalogPlanID string, namespaces ...string) *platform.ModifyPlanAccessRequest {
+			return &platform.ModifyPlanAccessRequest{
+				BrokerName:    fakeBrokerName,
+				CatalogPlanID: catalogPlanID,
+				Labels:        types.Labels{NamespacesLabelKey: namespaces},
+			}
+		}
+
+		BeforeEach(func() {
+			namespaceVisibilityBroker = newRestrictedNamespaceServiceBroker(fakeBrokerName, "team-a", "spec.externalID in (plan-1)")
+			namespaceVisibilityBroker.Spec.URL = fakeBrokerUrl
+			namespaceVisibilityBroker.Spec.AuthInfo = &v1beta1.ServiceBrokerAuthInfo{
+				Basic: &v1beta1.BasicAuthConfig{
+					SecretRef: &v1beta1.LocalObjectReference{Name: "id-in-sm"},
+				},
+			}
+
+			k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+				broker := newRestrictedClusterServiceBroker(name, "spec.externalID in ()")
+				broker.Spec.URL = fakeBrokerUrl
+				broker.Spec.RelistBehavior = "Manual"
+				broker.Spec.AuthInfo = &v1beta1.ClusterServiceBrokerAuthInfo{
+					Basic: &v1beta1.ClusterBasicAuthConfig{
+						SecretRef: &v1beta1.ObjectReference{Name: "id-in-sm", Namespace: "secretNamespace"},
+					},
+				}
+				return broker, nil
+			}
+			k8sApi.RetrieveSecretStub = func(_ context.Context, namespace, name string) (*v1core.Secret, error) {
+				Expect(namespace).To(Equal("secretNamespace"))
+				Expect(name).To(Equal("id-in-sm"))
+				return newServiceBrokerCredentialsSecret(namespace, name, "admin", "admin", nil), nil
+			}
+		})
+
+		Describe("EnableAccessForPlan", func() {
+			It("creates a restricted broker in the namespace", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokerByNameReturns(nil, apierrors.NewNotFound(v1beta1.Resource("servicebrokers"), fakeBrokerName))
+				k8sApi.UpdateServiceBrokerCredentialsStub = func(_ context.Context, secret *v1core.Secret) (*v1core.Secret, error) {
+					Expect(secret.Namespace).To(Equal("team-a"))
+					Expect(secret.Name).To(Equal("id-in-sm"))
+					Expect(string(secret.Data["username"])).To(Equal("admin"))
+					return secret, nil
+				}
+				k8sApi.CreateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					Expect(namespace).To(Equal("team-a"))
+					Expect(broker.Name).To(Equal(fakeBrokerName))
+					Expect(broker.Spec.URL).To(Equal(fakeBrokerUrl))
+					Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorManual))
+					Expect(broker.Spec.AuthInfo.Basic.SecretRef.Name).To(Equal("id-in-sm"))
+					Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-2)"))
+					return broker, nil
+				}
+
+				err := platformClient.EnableAccessForPlan(ctx, newModifyPlanAccessRequest("plan-2", "team-a"))
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(1))
+				Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(0))
+			})
+
+			It("adds the plan to the existing broker in each namespace", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+					return newRestrictedNamespaceServiceBroker(name, namespace, "spec.externalID in (plan-1)"), nil
+				}
+				updatedNamespaces := make([]string, 0)
+				k8sApi.UpdateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-1, plan-2)"))
+					updatedNamespaces = append(updatedNamespaces, namespace)
+					return broker, nil
+				}
+
+				err := platformClient.EnableAccessForPlan(ctx, newModifyPlanAccessRequest("plan-2", "team-a", "team-b"))
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(updatedNamespaces).To(ConsistOf("team-a", "team-b"))
+				Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(0))
+			})
+
+			It("retries when the broker has been created concurrently", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokerByNameReturnsOnCall(0, nil, apierrors.NewNotFound(v1beta1.Resource("servicebrokers"), fakeBrokerName))
+				k8sApi.RetrieveNamespaceServiceBrokerByNameReturnsOnCall(1, namespaceVisibilityBroker, nil)
+				k8sApi.CreateNamespaceServiceBrokerReturns(nil, apierrors.NewAlreadyExists(v1beta1.Resource("servicebrokers"), fakeBrokerName))
+				k8sApi.UpdateNamespaceServiceBrokerReturns(namespaceVisibilityBroker, nil)
+
+				err := platformClient.EnableAccessForPlan(ctx, newModifyPlanAccessRequest("plan-2", "team-a"))
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.UpdateNamespaceServiceBrokerCallCount()).To(Equal(1))
+			})
+		})
+
+		Describe("DisableAccessForPlan", func() {
+			It("deletes the broker and its secret when the last plan is disabled", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokerByNameReturns(namespaceVisibilityBroker, nil)
+
+				err := platformClient.DisableAccessForPlan(ctx, newModifyPlanAccessRequest("plan-1", "team-a"))
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.DeleteNamespaceServiceBrokerCallCount()).To(Equal(1))
+				_, name, namespace, _ := k8sApi.DeleteNamespaceServiceBrokerArgsForCall(0)
+				Expect(name).To(Equal(fakeBrokerName))
+				Expect(namespace).To(Equal("team-a"))
+				Expect(k8sApi.DeleteSecretCallCount()).To(Equal(1))
+				_, namespace, name = k8sApi.DeleteSecretArgsForCall(0)
+				Expect(namespace).To(Equal("team-a"))
+				Expect(name).To(Equal("id-in-sm"))
+			})
+
+			It("does nothing when there is no broker in the namespace", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokerByNameReturns(nil, apierrors.NewNotFound(v1beta1.Resource("servicebrokers"), fakeBrokerName))
+
+				err := platformClient.DisableAccessForPlan(ctx, newModifyPlanAccessRequest("plan-1", "team-a"))
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.DeleteNamespaceServiceBrokerCallCount()).To(Equal(0))
+				Expect(k8sApi.UpdateNamespaceServiceBrokerCallCount()).To(Equal(0))
+			})
+		})
+
+		Describe("GetVisibilitiesByBrokers", func() {
+			It("returns visibilities scoped to the namespaces of the brokers", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveClusterServiceBrokersReturns(&v1beta1.ClusterServiceBrokerList{
+					Items: []v1beta1.ClusterServiceBroker{*newRestrictedClusterServiceBroker(fakeBrokerName, "spec.externalID in (plan-2)")},
+				}, nil)
+				k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+					Expect(namespace).To(Equal(v1.NamespaceAll))
+					return &v1beta1.ServiceBrokerList{
+						Items: []v1beta1.ServiceBroker{
+							*namespaceVisibilityBroker,
+							*newRestrictedNamespaceServiceBroker("other-broker", "team-a", "spec.externalID in (plan-3)"),
+						},
+					}, nil
+				}
+
+				visibilities, err := platformClient.GetVisibilitiesByBrokers(ctx, []string{fakeBrokerName})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(visibilities).To(ConsistOf(
+					&platform.Visibility{Public: true, CatalogPlanID: "plan-2", PlatformBrokerName: fakeBrokerName, Labels: map[string]string{}},
+					&platform.Visibility{Public: false, CatalogPlanID: "plan-1", PlatformBrokerName: fakeBrokerName, Labels: map[string]string{NamespacesLabelKey: "team-a"}},
+				))
+			})
+		})
+
+		Describe("Broker operations", func() {
+			BeforeEach(func() {
+				k8sApi.RetrieveNamespaceServiceBrokersReturns(&v1beta1.ServiceBrokerList{
+					Items: []v1beta1.ServiceBroker{*namespaceVisibilityBroker},
+				}, nil)
+			})
+
+			It("deletes the namespace-scoped brokers with the cluster-scoped broker", func() {
+				platformClient := newDefaultPlatformClient()
+
+				err := platformClient.DeleteBroker(ctx, &platform.DeleteServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.DeleteNamespaceServiceBrokerCallCount()).To(Equal(1))
+				Expect(k8sApi.DeleteClusterServiceBrokerCallCount()).To(Equal(1))
+				Expect(k8sApi.DeleteSecretCallCount()).To(Equal(2))
+			})
+
+			It("relists the namespace-scoped brokers and updates their credentials on fetch", func() {
+				platformClient := newDefaultPlatformClient()
+
+				err := platformClient.Fetch(ctx, &platform.UpdateServiceBrokerRequest{
+					ID:       "id-in-sm",
+					Name:     fakeBrokerName,
+					Username: "admin",
+					Password: "admin",
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.SyncClusterServiceBrokerCallCount()).To(Equal(1))
+				Expect(k8sApi.SyncNamespaceServiceBrokerCallCount()).To(Equal(1))
+				_, name, namespace, _ := k8sApi.SyncNamespaceServiceBrokerArgsForCall(0)
+				Expect(name).To(Equal(fakeBrokerName))
+				Expect(namespace).To(Equal("team-a"))
+				Expect(k8sApi.UpdateServiceBrokerCredentialsCallCount()).To(Equal(2))
+			})
+
+			It("propagates a changed broker URL", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+					return broker, nil
+				}
+				k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+					broker := newRestrictedClusterServiceBroker(name)
+					broker.Spec.URL = fakeBrokerUrl + "-updated"
+					return broker, nil
+				}
+
+				_, err := platformClient.UpdateBroker(ctx, &platform.UpdateServiceBrokerRequest{
+					ID:        "id-in-sm",
+					Name:      fakeBrokerName,
+					BrokerURL: fakeBrokerUrl + "-updated",
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.UpdateNamespaceServiceBrokerCallCount()).To(Equal(1))
+				_, broker, namespace := k8sApi.UpdateNamespaceServiceBrokerArgsForCall(0)
+				Expect(namespace).To(Equal("team-a"))
+				Expect(broker.Spec.URL).To(Equal(fakeBrokerUrl + "-updated"))
+			})
 		})
 	})
-})
+
+	Describe("Multiple target namespaces", func() {
+		notFound := apierrors.NewNotFound(v1beta1.Resource("servicebrokers"), fakeBrokerName)
+
+		BeforeEach(func() {
+			settings.K8S.TargetNamespaces = []string{"namespace-1", "namespace-2"}
+		})
+
+		Describe("CreateBroker", func() {
+			It("registers the broker with its own secret in every namespace", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.CreateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					broker.UID = kubernetesTypes.UID("uid-" + namespace)
+					return broker, nil
+				}
+
+				broker, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{
+					ID:        "id-in-sm",
+					Name:      fakeBrokerName,
+					BrokerURL: fakeBrokerUrl,
+					Username:  "admin",
+					Password:  "admin",
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(broker.GUID).To(Equal("uid-namespace-1"))
+				Expect(k8sApi.UpdateServiceBrokerCredentialsCallCount()).To(Equal(2))
+				_, secret := k8sApi.UpdateServiceBrokerCredentialsArgsForCall(0)
+				Expect(secret.Namespace).To(Equal("namespace-1"))
+				_, secret = k8sApi.UpdateServiceBrokerCredentialsArgsForCall(1)
+				Expect(secret.Namespace).To(Equal("namespace-2"))
+				Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(2))
+				_, _, namespace := k8sApi.CreateNamespaceServiceBrokerArgsForCall(1)
+				Expect(namespace).To(Equal("namespace-2"))
+			})
+
+			It("aggregates the errors of all namespaces", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.CreateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					if namespace == "namespace-1" {
+						return nil, expectedError
+					}
+					return broker, nil
+				}
+
+				broker, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{Name: fakeBrokerName})
+
+				Expect(broker).To(BeNil())
+				Expect(err).To(MatchError("unable to create broker fake-broker in namespace namespace-1 (expected)"))
+				Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(2))
+			})
+		})
+
+		Describe("GetBrokers", func() {
+			It("returns brokers registered in several namespaces once", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+					broker := newRestrictedNamespaceServiceBroker(fakeBrokerName, namespace)
+					broker.UID = kubernetesTypes.UID("uid-" + namespace)
+					brokers := &v1beta1.ServiceBrokerList{Items: []v1beta1.ServiceBroker{*broker}}
+					if namespace == "namespace-2" {
+						brokers.Items = append(brokers.Items, *newRestrictedNamespaceServiceBroker("other-broker", namespace))
+					}
+					return brokers, nil
+				}
+
+				brokers, err := platformClient.GetBrokers(ctx)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(brokers).To(HaveLen(2))
+				for _, broker := range brokers {
+					if broker.Name == fakeBrokerName {
+						Expect(broker.GUID).To(Equal("uid-namespace-1"))
+					}
+				}
+			})
+
+			It("fails if the brokers of a namespace cannot be listed", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+					if namespace == "namespace-2" {
+						return nil, expectedError
+					}
+					return &v1beta1.ServiceBrokerList{}, nil
+				}
+
+				brokers, err := platformClient.GetBrokers(ctx)
+
+				Expect(brokers).To(BeNil())
+				Expect(err).To(MatchError("unable to list namespace-scoped brokers in namespace namespace-2 (expected)"))
+			})
+		})
+
+		Describe("DeleteBroker", func() {
+			It("deletes the broker in every namespace and ignores missing brokers", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.DeleteNamespaceServiceBrokerStub = func(_ context.Context, name, namespace string, options *v1.DeleteOptions) error {
+					if namespace == "namespace-1" {
+						return notFound
+					}
+					return nil
+				}
+
+				err := platformClient.DeleteBroker(ctx, &platform.DeleteServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.DeleteSecretCallCount()).To(Equal(2))
+				Expect(k8sApi.DeleteNamespaceServiceBrokerCallCount()).To(Equal(2))
+			})
+		})
+
+		Describe("UpdateBroker", func() {
+			It("updates the broker in every namespace", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokerByNameReturns(newRestrictedNamespaceServiceBroker(fakeBrokerName, "team-a"), nil)
+				k8sApi.UpdateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					return broker, nil
+				}
+
+				broker, err := platformClient.UpdateBroker(ctx, &platform.UpdateServiceBrokerRequest{
+					ID:        "id-in-sm",
+					Name:      fakeBrokerName,
+					BrokerURL: fakeBrokerUrl,
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(broker.BrokerURL).To(Equal(fakeBrokerUrl))
+				Expect(k8sApi.UpdateNamespaceServiceBrokerCallCount()).To(Equal(2))
+			})
+		})
+
+		Describe("Fetch", func() {
+			It("registers the broker in namespaces in which it is missing", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+					if namespace == "namespace-2" {
+						return nil, notFound
+					}
+					broker := newRestrictedNamespaceServiceBroker(name, namespace, "spec.externalID in (plan-1)")
+					broker.Spec.AuthInfo = &v1beta1.ServiceBrokerAuthInfo{
+						Basic: &v1beta1.BasicAuthConfig{
+							SecretRef: &v1beta1.LocalObjectReference{Name: "id-in-sm"},
+						},
+					}
+					return broker, nil
+				}
+				k8sApi.RetrieveSecretStub = func(_ context.Context, namespace, name string) (*v1core.Secret, error) {
+					Expect(namespace).To(Equal("namespace-1"))
+					return newServiceBrokerCredentialsSecret(namespace, name, "admin", "admin", nil), nil
+				}
+
+				err := platformClient.Fetch(ctx, &platform.UpdateServiceBrokerRequest{
+					ID:        "id-in-sm",
+					Name:      fakeBrokerName,
+					BrokerURL: fakeBrokerUrl,
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.SyncNamespaceServiceBrokerCallCount()).To(Equal(1))
+				Expect(k8sApi.UpdateServiceBrokerCredentialsCallCount()).To(Equal(1))
+				_, secret := k8sApi.UpdateServiceBrokerCredentialsArgsForCall(0)
+				Expect(secret.Namespace).To(Equal("namespace-2"))
+				Expect(string(secret.Data["username"])).To(Equal("admin"))
+				Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(1))
+				_, broker, namespace := k8sApi.CreateNamespaceServiceBrokerArgsForCall(0)
+				Expect(namespace).To(Equal("namespace-2"))
+				Expect(broker.Spec.URL).To(Equal(fakeBrokerUrl))
+				Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-1)"))
+			})
+		})
+
+		Describe("GetVisibilitiesByBrokers", func() {
+			It("returns only plans which are visible in all namespaces", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+					restriction := "spec.externalID in (plan-1, plan-2)"
+					if namespace == "namespace-2" {
+						restriction = "spec.externalID in (plan-2)"
+					}
+					return &v1beta1.ServiceBrokerList{Items: []v1beta1.ServiceBroker{
+						*newRestrictedNamespaceServiceBroker(fakeBrokerName, namespace, restriction),
+					}}, nil
+				}
+
+				visibilities, err := platformClient.GetVisibilitiesByBrokers(ctx, []string{fakeBrokerName})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(visibilities).To(ConsistOf(&platform.Visibility{
+					Public:             true,
+					CatalogPlanID:      "plan-2",
+					PlatformBrokerName: fakeBrokerName,
+					Labels:             map[string]string{},
+				}))
+			})
+		})
+
+		Describe("EnableAccessForPlan", func() {
+			It("enables the plan in every namespace", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+					return newRestrictedNamespaceServiceBroker(name, namespace, "spec.externalID in ()"), nil
+				}
+				k8sApi.UpdateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+					if namespace == "namespace-1" {
+						return nil, expectedError
+					}
+					Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-1)"))
+					return broker, nil
+				}
+
+				err := platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{
+					BrokerName:    fakeBrokerName,
+					CatalogPlanID: "plan-1",
+				})
+
+				Expect(err).To(MatchError("unable to modify access for plan plan-1 of broker fake-broker in namespace namespace-1 (expected)"))
+				Expect(k8sApi.UpdateNamespaceServiceBrokerCallCount()).To(Equal(2))
+			})
+		})
+	})
+
+	Describe("Namespace selector", func() {
+		var handler cache.ResourceEventHandler
+
+		newNamespace := func(name string) *v1core.Namespace {
+			return &v1core.Namespace{ObjectMeta: v1.ObjectMeta{Name: name}}
+		}
+
+		newBrokerWithSecret := func(name, namespace string) v1beta1.ServiceBroker {
+			broker := newRestrictedNamespaceServiceBroker(name, namespace, "spec.externalID in (plan-1)")
+			broker.Spec.URL = fakeBrokerUrl
+			broker.Spec.AuthInfo = &v1beta1.ServiceBrokerAuthInfo{
+				Basic: &v1beta1.BasicAuthConfig{
+					SecretRef: &v1beta1.LocalObjectReference{Name: name + "-id"},
+				},
+			}
+			return *broker
+		}
+
+		watchNamespaces := func() *PlatformClient {
+			platformClient := newDefaultPlatformClient()
+			Expect(platformClient.WatchNamespaces(ctx)).To(Succeed())
+			Expect(k8sApi.WatchNamespacesCallCount()).To(Equal(1))
+			var labelSelector string
+			_, labelSelector, handler = k8sApi.WatchNamespacesArgsForCall(0)
+			Expect(labelSelector).To(Equal("sbproxy.peripli.io/enabled=true"))
+			return platformClient
+		}
+
+		BeforeEach(func() {
+			settings.K8S.NamespaceSelector = "sbproxy.peripli.io/enabled=true"
+			settings.K8S.TargetNamespaces = []string{"namespace-1"}
+
+			k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+				switch namespace {
+				case "namespace-1":
+					return &v1beta1.ServiceBrokerList{Items: []v1beta1.ServiceBroker{
+						newBrokerWithSecret(fakeBrokerName, namespace),
+						newBrokerWithSecret("other-broker", namespace),
+					}}, nil
+				case "team-a":
+					return &v1beta1.ServiceBrokerList{Items: []v1beta1.ServiceBroker{
+						newBrokerWithSecret("other-broker", namespace),
+					}}, nil
+				}
+				return &v1beta1.ServiceBrokerList{}, nil
+			}
+			k8sApi.RetrieveSecretStub = func(_ context.Context, namespace, name string) (*v1core.Secret, error) {
+				Expect(namespace).To(Equal("namespace-1"))
+				return newServiceBrokerCredentialsSecret(namespace, name, "admin", "admin", nil), nil
+			}
+		})
+
+		It("does not watch namespaces without a namespace selector", func() {
+			settings.K8S.NamespaceSelector = ""
+			platformClient := newDefaultPlatformClient()
+
+			Expect(platformClient.WatchNamespaces(ctx)).To(Succeed())
+			Expect(k8sApi.WatchNamespacesCallCount()).To(Equal(0))
+		})
+
+		It("registers the missing brokers in namespaces which start matching", func() {
+			platformClient := watchNamespaces()
+
+			handler.OnAdd(newNamespace("team-a"))
+
+			Expect(platformClient.namespaces()).To(Equal([]string{"namespace-1", "team-a"}))
+			Expect(k8sApi.UpdateServiceBrokerCredentialsCallCount()).To(Equal(1))
+			_, secret := k8sApi.UpdateServiceBrokerCredentialsArgsForCall(0)
+			Expect(secret.Namespace).To(Equal("team-a"))
+			Expect(secret.Name).To(Equal(fakeBrokerName + "-id"))
+			Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(1))
+			_, broker, namespace := k8sApi.CreateNamespaceServiceBrokerArgsForCall(0)
+			Expect(namespace).To(Equal("team-a"))
+			Expect(broker.Name).To(Equal(fakeBrokerName))
+			Expect(broker.Spec.URL).To(Equal(fakeBrokerUrl))
+			Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-1)"))
+		})
+
+		It("registers brokers in matching namespaces with the next operations", func() {
+			settings.K8S.TargetNamespaces = nil
+			platformClient := watchNamespaces()
+			Expect(platformClient.isClusterScoped()).To(BeFalse())
+
+			handler.OnAdd(newNamespace("team-a"))
+
+			Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(0))
+			k8sApi.CreateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+				return broker, nil
+			}
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+			Expect(err).ToNot(HaveOccurred())
+			_, _, namespace := k8sApi.CreateNamespaceServiceBrokerArgsForCall(0)
+			Expect(namespace).To(Equal("team-a"))
+		})
+
+		It("removes the brokers from namespaces which stop matching", func() {
+			platformClient := watchNamespaces()
+			handler.OnAdd(newNamespace("team-a"))
+
+			handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "team-a", Obj: newNamespace("team-a")})
+
+			Expect(platformClient.namespaces()).To(Equal([]string{"namespace-1"}))
+			Expect(k8sApi.DeleteNamespaceServiceBrokerCallCount()).To(Equal(1))
+			_, name, namespace, _ := k8sApi.DeleteNamespaceServiceBrokerArgsForCall(0)
+			Expect(name).To(Equal("other-broker"))
+			Expect(namespace).To(Equal("team-a"))
+			_, namespace, name = k8sApi.DeleteSecretArgsForCall(0)
+			Expect(namespace).To(Equal("team-a"))
+			Expect(name).To(Equal("other-broker-id"))
+		})
+
+		It("leaves the namespaces to the leader and reconciles them when it becomes the leader", func() {
+			settings.K8S.LeaderElection = true
+			platformClient := watchNamespaces()
+			k8sApi.NewLeaseLockStub = NewDefaultKubernetesAPI(&servicecatalog.SDK{K8sClient: k8sfake.NewSimpleClientset()}).NewLeaseLock
+
+			handler.OnAdd(newNamespace("team-a"))
+			handler.OnAdd(newNamespace("team-b"))
+			handler.OnDelete(newNamespace("team-a"))
+
+			Expect(platformClient.namespaces()).To(Equal([]string{"namespace-1", "team-b"}))
+			Expect(k8sApi.RetrieveNamespaceServiceBrokersCallCount()).To(Equal(0))
+
+			resyncs := make(chan struct{}, 1)
+			Expect(platformClient.StartLeaderElection(ctx, func(context.Context) { resyncs <- struct{}{} })).To(Succeed())
+
+			Eventually(resyncs).Should(Receive())
+			Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(2))
+			for i := 0; i < 2; i++ {
+				_, _, namespace := k8sApi.CreateNamespaceServiceBrokerArgsForCall(i)
+				Expect(namespace).To(Equal("team-b"))
+			}
+			Expect(k8sApi.DeleteNamespaceServiceBrokerCallCount()).To(Equal(1))
+			_, name, namespace, _ := k8sApi.DeleteNamespaceServiceBrokerArgsForCall(0)
+			Expect(name).To(Equal("other-broker"))
+			Expect(namespace).To(Equal("team-a"))
+		})
+
+		It("keeps the brokers of target namespaces which stop matching", func() {
+			platformClient := watchNamespaces()
+			handler.OnAdd(newNamespace("namespace-1"))
+
+			handler.OnDelete(newNamespace("namespace-1"))
+
+			Expect(platformClient.namespaces()).To(Equal([]string{"namespace-1"}))
+			Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(0))
+			Expect(k8sApi.DeleteNamespaceServiceBrokerCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("Broker scope labels", func() {
+		newSMBroker := func(id, scope string) *types.ServiceBroker {
+			broker := &types.ServiceBroker{Base: types.Base{ID: id}}
+			if scope != "" {
+				broker.Labels = types.Labels{BrokerScopeLabelKey: {scope}}
+			}
+			return broker
+		}
+
+		BeforeEach(func() {
+			settings.K8S.TargetNamespaces = []string{"namespace-1"}
+			smClient.GetBrokersReturns([]*types.ServiceBroker{
+				newSMBroker("default-id", ""),
+				newSMBroker("cluster-id", "cluster"),
+				newSMBroker("team-id", "namespace:team-a"),
+			}, nil)
+			k8sApi.RetrieveClusterServiceBrokersReturns(&v1beta1.ClusterServiceBrokerList{}, nil)
+			k8sApi.RetrieveNamespaceServiceBrokersReturns(&v1beta1.ServiceBrokerList{}, nil)
+			k8sApi.CreateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+			k8sApi.CreateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+				return broker, nil
+			}
+		})
+
+		It("registers brokers without scope label in the default scope", func() {
+			platformClient := newDefaultPlatformClient()
+
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "default-id", Name: "default-broker"})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sApi.CreateClusterServiceBrokerCallCount()).To(Equal(0))
+			_, _, namespace := k8sApi.CreateNamespaceServiceBrokerArgsForCall(0)
+			Expect(namespace).To(Equal("namespace-1"))
+		})
+
+		It("registers brokers labeled with the cluster scope cluster-wide", func() {
+			platformClient := newDefaultPlatformClient()
+
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "cluster-id", Name: "cluster-broker"})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(0))
+			Expect(k8sApi.CreateClusterServiceBrokerCallCount()).To(Equal(1))
+			_, secret := k8sApi.UpdateServiceBrokerCredentialsArgsForCall(0)
+			Expect(secret.Namespace).To(Equal("secretNamespace"))
+		})
+
+		It("registers brokers labeled with a namespace scope in that namespace", func() {
+			platformClient := newDefaultPlatformClient()
+
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "team-id", Name: "team-broker"})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(1))
+			_, _, namespace := k8sApi.CreateNamespaceServiceBrokerArgsForCall(0)
+			Expect(namespace).To(Equal("team-a"))
+		})
+
+		It("fails if the scope cannot be determined", func() {
+			platformClient := newDefaultPlatformClient()
+			smClient.GetBrokersReturns(nil, expectedError)
+
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "cluster-id", Name: "cluster-broker"})
+
+			Expect(err).To(MatchError("unable to get broker scopes (expected)"))
+			Expect(k8sApi.CreateClusterServiceBrokerCallCount()).To(Equal(0))
+		})
+
+		It("returns the brokers of all scopes", func() {
+			platformClient := newDefaultPlatformClient()
+			k8sApi.RetrieveClusterServiceBrokersReturns(&v1beta1.ClusterServiceBrokerList{Items: []v1beta1.ClusterServiceBroker{
+				*newRestrictedClusterServiceBroker("cluster-broker"),
+			}}, nil)
+			k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+				brokerName := map[string]string{"namespace-1": "default-broker", "team-a": "team-broker"}[namespace]
+				return &v1beta1.ServiceBrokerList{Items: []v1beta1.ServiceBroker{
+					*newRestrictedNamespaceServiceBroker(brokerName, namespace),
+				}}, nil
+			}
+
+			brokers, err := platformClient.GetBrokers(ctx)
+
+			Expect(err).ToNot(HaveOccurred())
+			brokerNames := make([]string, 0)
+			for _, broker := range brokers {
+				brokerNames = append(brokerNames, broker.Name)
+			}
+			Expect(brokerNames).To(ConsistOf("default-broker", "cluster-broker", "team-broker"))
+		})
+
+		It("deletes a broker in its scope without registering it before", func() {
+			teamBroker := newRestrictedNamespaceServiceBroker("team-broker", "team-a")
+			teamBroker.Labels[BrokerIDLabelKey] = "team-id"
+			k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+				if namespace == "team-a" {
+					return &v1beta1.ServiceBrokerList{Items: []v1beta1.ServiceBroker{*teamBroker}}, nil
+				}
+				return &v1beta1.ServiceBrokerList{}, nil
+			}
+			platformClient := newDefaultPlatformClient()
+
+			err := platformClient.DeleteBroker(ctx, &platform.DeleteServiceBrokerRequest{ID: "team-id", Name: "team-broker"})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sApi.DeleteNamespaceServiceBrokerCallCount()).To(Equal(1))
+			_, name, namespace, _ := k8sApi.DeleteNamespaceServiceBrokerArgsForCall(0)
+			Expect(name).To(Equal("team-broker"))
+			Expect(namespace).To(Equal("team-a"))
+			_, namespace, name = k8sApi.DeleteSecretArgsForCall(0)
+			Expect(namespace).To(Equal("team-a"))
+			Expect(name).To(Equal("team-id"))
+		})
+
+		It("modifies the plan access in the scope of the broker", func() {
+			platformClient := newDefaultPlatformClient()
+			k8sApi.UpdateServiceBrokerCredentialsReturns(nil, nil)
+			k8sApi.SyncClusterServiceBrokerReturns(nil)
+			Expect(platformClient.Fetch(ctx, &platform.UpdateServiceBrokerRequest{ID: "cluster-id", Name: "cluster-broker"})).To(Succeed())
+			k8sApi.RetrieveClusterServiceBrokerByNameReturns(newRestrictedClusterServiceBroker("cluster-broker", "spec.externalID in ()"), nil)
+
+			err := platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{BrokerName: "cluster-broker", CatalogPlanID: "plan-1"})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sApi.UpdateNamespaceServiceBrokerCallCount()).To(Equal(0))
+			Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(1))
+		})
+
+		It("registers a broker whose scope label has been changed again in its new scope", func() {
+			movedBroker := newRestrictedNamespaceServiceBroker("team-broker", "namespace-1")
+			movedBroker.Labels[BrokerIDLabelKey] = "team-id"
+			movedBroker.Spec.AuthInfo = &v1beta1.ServiceBrokerAuthInfo{
+				Basic: &v1beta1.BasicAuthConfig{SecretRef: &v1beta1.LocalObjectReference{Name: "team-id"}},
+			}
+			k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+				if namespace == "namespace-1" {
+					return &v1beta1.ServiceBrokerList{Items: []v1beta1.ServiceBroker{*movedBroker}}, nil
+				}
+				return &v1beta1.ServiceBrokerList{}, nil
+			}
+			platformClient := newDefaultPlatformClient()
+
+			brokers, err := platformClient.GetBrokers(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(brokers).To(BeEmpty())
+
+			_, err = platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "team-id", Name: "team-broker", Username: "user", Password: "pass"})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sApi.DeleteNamespaceServiceBrokerCallCount()).To(Equal(1))
+			_, name, namespace, _ := k8sApi.DeleteNamespaceServiceBrokerArgsForCall(0)
+			Expect(name).To(Equal("team-broker"))
+			Expect(namespace).To(Equal("namespace-1"))
+			_, namespace, name = k8sApi.DeleteSecretArgsForCall(0)
+			Expect(namespace).To(Equal("namespace-1"))
+			Expect(name).To(Equal("team-id"))
+			Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(1))
+			_, _, namespace = k8sApi.CreateNamespaceServiceBrokerArgsForCall(0)
+			Expect(namespace).To(Equal("team-a"))
+		})
+
+		It("does not register a cluster-scoped broker again for its brokers in namespaces", func() {
+			clusterBroker := newRestrictedClusterServiceBroker("cluster-broker")
+			clusterBroker.Labels[BrokerIDLabelKey] = "cluster-id"
+			k8sApi.RetrieveClusterServiceBrokersReturns(&v1beta1.ClusterServiceBrokerList{Items: []v1beta1.ClusterServiceBroker{*clusterBroker}}, nil)
+			visibilityBroker := newRestrictedNamespaceServiceBroker("cluster-broker", "namespace-1", "spec.externalID in (plan-1)")
+			visibilityBroker.Labels[BrokerIDLabelKey] = "cluster-id"
+			k8sApi.RetrieveNamespaceServiceBrokersReturns(&v1beta1.ServiceBrokerList{Items: []v1beta1.ServiceBroker{*visibilityBroker}}, nil)
+			platformClient := newDefaultPlatformClient()
+
+			brokers, err := platformClient.GetBrokers(ctx)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(brokers).To(HaveLen(1))
+			Expect(brokers[0].Name).To(Equal("cluster-broker"))
+		})
+
+		It("reads the labels of the brokers listed by the resync", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if len(r.URL.Query().Get("token")) == 0 {
+					_, _ = w.Write([]byte(`{"token": "page-2", "items": [{"id": "default-id"}]}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"items": [{"id": "team-id", "labels": {"k8s-scope": ["namespace:team-a"]}}]}`))
+			}))
+			defer server.Close()
+			k8sApi.RetrieveNamespaceServiceBrokersStub = func(_ context.Context, namespace string) (*v1beta1.ServiceBrokerList, error) {
+				if namespace == "team-a" {
+					return &v1beta1.ServiceBrokerList{Items: []v1beta1.ServiceBroker{*newRestrictedNamespaceServiceBroker("team-broker", namespace)}}, nil
+				}
+				return &v1beta1.ServiceBrokerList{}, nil
+			}
+			platformClient := newDefaultPlatformClient()
+			smSettings := sm.DefaultSettings()
+			smSettings.URL = server.URL
+			smSettings.User = "user"
+			smSettings.Password = "pass"
+			Expect(platformClient.RecordSMBrokers(smSettings)).To(Succeed())
+			resyncSMClient, err := sm.NewClient(smSettings)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = resyncSMClient.GetBrokers(ctx)
+			Expect(err).ToNot(HaveOccurred())
+
+			brokers, err := platformClient.GetBrokers(ctx)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(brokers).To(HaveLen(1))
+			Expect(brokers[0].Name).To(Equal("team-broker"))
+			Expect(smClient.GetBrokersCallCount()).To(Equal(0))
+
+			_, err = platformClient.GetBrokers(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(smClient.GetBrokersCallCount()).To(Equal(1))
+		})
+	})
+
+	Describe("Ownership labels", func() {
+		var unownedBroker *v1beta1.ClusterServiceBroker
+
+		BeforeEach(func() {
+			unownedBroker = newRestrictedClusterServiceBroker("manual-broker")
+			unownedBroker.Labels = nil
+		})
+
+		It("labels the brokers and secrets it creates", func() {
+			platformClient := newDefaultPlatformClient()
+			k8sApi.CreateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+			Expect(err).ToNot(HaveOccurred())
+			_, secret := k8sApi.UpdateServiceBrokerCredentialsArgsForCall(0)
+			Expect(secret.Labels).To(Equal(ownedLabels()))
+			_, broker := k8sApi.CreateClusterServiceBrokerArgsForCall(0)
+			Expect(broker.Labels).To(Equal(ownedLabels()))
+		})
+
+		It("keeps the labels when updating a broker", func() {
+			platformClient := newDefaultPlatformClient()
+			k8sApi.RetrieveClusterServiceBrokerByNameReturns(newRestrictedClusterServiceBroker(fakeBrokerName), nil)
+			k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+
+			_, err := platformClient.UpdateBroker(ctx, &platform.UpdateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+			Expect(err).ToNot(HaveOccurred())
+			_, broker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+			Expect(broker.Labels).To(Equal(ownedLabels()))
+		})
+
+		It("labels the brokers with the configured instance name", func() {
+			settings.K8S.InstanceName = "other-proxy"
+			platformClient := newDefaultPlatformClient()
+			k8sApi.CreateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+			Expect(err).ToNot(HaveOccurred())
+			_, broker := k8sApi.CreateClusterServiceBrokerArgsForCall(0)
+			Expect(broker.Labels).To(HaveKeyWithValue(ProxyInstanceLabelKey, "other-proxy"))
+		})
+
+		It("returns only the brokers it created", func() {
+			platformClient := newDefaultPlatformClient()
+			otherProxyBroker := newRestrictedClusterServiceBroker("other-proxy-broker")
+			otherProxyBroker.Labels[ProxyInstanceLabelKey] = "other-proxy"
+			k8sApi.RetrieveClusterServiceBrokersReturns(&v1beta1.ClusterServiceBrokerList{
+				Items: []v1beta1.ClusterServiceBroker{*newRestrictedClusterServiceBroker(fakeBrokerName), *unownedBroker, *otherProxyBroker},
+			}, nil)
+
+			brokers, err := platformClient.GetBrokers(ctx)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(brokers).To(HaveLen(1))
+			Expect(brokers[0].Name).To(Equal(fakeBrokerName))
+		})
+
+		It("does not return brokers it has not created by name", func() {
+			platformClient := newDefaultPlatformClient()
+			k8sApi.RetrieveClusterServiceBrokerByNameReturns(unownedBroker, nil)
+
+			_, err := platformClient.GetBrokerByName(ctx, "manual-broker")
+
+			Expect(err).To(MatchError("unable to get cluster-scoped broker (broker manual-broker is not managed by this proxy)"))
+		})
+
+		It("does not remove brokers it has not created from namespaces which stop matching", func() {
+			settings.K8S.NamespaceSelector = "sbproxy.peripli.io/enabled=true"
+			platformClient := newDefaultPlatformClient()
+			unownedNamespaceBroker := newRestrictedNamespaceServiceBroker("manual-broker", "team-a")
+			unownedNamespaceBroker.Labels = nil
+			k8sApi.RetrieveNamespaceServiceBrokersReturns(&v1beta1.ServiceBrokerList{
+				Items: []v1beta1.ServiceBroker{*newRestrictedNamespaceServiceBroker(fakeBrokerName, "team-a"), *unownedNamespaceBroker},
+			}, nil)
+
+			Expect(platformClient.deleteNamespaceBrokers(ctx, "team-a")).To(Succeed())
+
+			Expect(k8sApi.DeleteNamespaceServiceBrokerCallCount()).To(Equal(1))
+			_, name, _, _ := k8sApi.DeleteNamespaceServiceBrokerArgsForCall(0)
+			Expect(name).To(Equal(fakeBrokerName))
+		})
+
+		Context("with brokers registered by a proxy without ownership labels", func() {
+			var legacyBroker *v1beta1.ClusterServiceBroker
+
+			BeforeEach(func() {
+				legacyBroker = newRestrictedClusterServiceBroker(fakeBrokerName)
+				legacyBroker.Labels = nil
+				legacyBroker.Spec.URL = "https://sbproxy.example.com/v1/osb/id-in-sm"
+				legacyBroker.Spec.AuthInfo = &v1beta1.ClusterServiceBrokerAuthInfo{
+					Basic: &v1beta1.ClusterBasicAuthConfig{
+						SecretRef: &v1beta1.ObjectReference{
+							Name:      "id-in-sm",
+							Namespace: "secretNamespace",
+						},
+					},
+				}
+			})
+
+			It("returns them", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveClusterServiceBrokersReturns(&v1beta1.ClusterServiceBrokerList{
+					Items: []v1beta1.ClusterServiceBroker{*legacyBroker, *unownedBroker},
+				}, nil)
+
+				brokers, err := platformClient.GetBrokers(ctx)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(brokers).To(HaveLen(1))
+				Expect(brokers[0].Name).To(Equal(fakeBrokerName))
+			})
+
+			It("adopts them when they are registered again with the fail policy", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.CreateClusterServiceBrokerReturnsOnCall(0, nil, apierrors.NewAlreadyExists(v1beta1.Resource("clusterservicebrokers"), fakeBrokerName))
+				k8sApi.RetrieveClusterServiceBrokerByNameReturns(legacyBroker, nil)
+				k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+					return broker, nil
+				}
+
+				_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName, BrokerURL: fakeBrokerUrl})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(1))
+				_, broker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+				Expect(broker.Labels).To(HaveKeyWithValue(ManagedByLabelKey, ManagedByLabelValue))
+				Expect(broker.Labels).To(HaveKeyWithValue(BrokerIDLabelKey, "id-in-sm"))
+			})
+
+			It("labels them when they are updated", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveClusterServiceBrokerByNameReturns(legacyBroker, nil)
+				k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+					return broker, nil
+				}
+
+				_, err := platformClient.UpdateBroker(ctx, &platform.UpdateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName, BrokerURL: fakeBrokerUrl})
+
+				Expect(err).ToNot(HaveOccurred())
+				_, broker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+				Expect(broker.Labels).To(HaveKeyWithValue(ProxyInstanceLabelKey, "default"))
+				Expect(broker.Labels).To(HaveKeyWithValue(BrokerIDLabelKey, "id-in-sm"))
+			})
+
+			It("does not take over brokers whose secret is not named after the broker URL", func() {
+				platformClient := newDefaultPlatformClient()
+				legacyBroker.Spec.URL = "https://manual.broker.example.com"
+				k8sApi.RetrieveClusterServiceBrokersReturns(&v1beta1.ClusterServiceBrokerList{
+					Items: []v1beta1.ClusterServiceBroker{*legacyBroker},
+				}, nil)
+
+				brokers, err := platformClient.GetBrokers(ctx)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(brokers).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Existing brokers", func() {
+		var existingBroker *v1beta1.ClusterServiceBroker
+
+		BeforeEach(func() {
+			existingBroker = newRestrictedClusterServiceBroker(fakeBrokerName)
+			existingBroker.Labels = map[string]string{"team": "a"}
+			existingBroker.Spec.URL = "http://manual.broker.url"
+			k8sApi.CreateClusterServiceBrokerReturnsOnCall(0, nil, apierrors.NewAlreadyExists(v1beta1.Resource("clusterservicebrokers"), fakeBrokerName))
+			k8sApi.RetrieveClusterServiceBrokerByNameReturns(existingBroker, nil)
+		})
+
+		createBroker := func(platformClient *PlatformClient) (*platform.ServiceBroker, error) {
+			return platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{
+				ID:        "id-in-sm",
+				Name:      fakeBrokerName,
+				BrokerURL: fakeBrokerUrl,
+			})
+		}
+
+		Context("with the fail policy", func() {
+			It("reports the conflict and deletes the credentials secret", func() {
+				platformClient := newDefaultPlatformClient()
+
+				_, err := createBroker(platformClient)
+
+				Expect(err).To(MatchError("broker fake-broker already exists and is not managed by a service broker proxy"))
+				Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(BeZero())
+				Expect(k8sApi.DeleteSecretCallCount()).To(Equal(1))
+				_, namespace, name := k8sApi.DeleteSecretArgsForCall(0)
+				Expect(namespace).To(Equal("secretNamespace"))
+				Expect(name).To(Equal("id-in-sm"))
+			})
+
+			It("names the proxy instance which manages the existing broker", func() {
+				existingBroker.Labels = ownedLabels()
+				existingBroker.Labels[ProxyInstanceLabelKey] = "other-proxy"
+				platformClient := newDefaultPlatformClient()
+
+				_, err := createBroker(platformClient)
+
+				Expect(err).To(MatchError("broker fake-broker already exists and is managed by service broker proxy instance other-proxy for Service Manager broker id-in-sm"))
+			})
+
+			It("keeps the credentials secret if the existing broker uses it", func() {
+				existingBroker.Spec.AuthInfo = &v1beta1.ClusterServiceBrokerAuthInfo{
+					Basic: &v1beta1.ClusterBasicAuthConfig{
+						SecretRef: &v1beta1.ObjectReference{Name: "id-in-sm", Namespace: "secretNamespace"},
+					},
+				}
+				platformClient := newDefaultPlatformClient()
+
+				_, err := createBroker(platformClient)
+
+				Expect(err).To(HaveOccurred())
+				Expect(k8sApi.DeleteSecretCallCount()).To(BeZero())
+			})
+
+			It("adopts brokers which it has created for the same Service Manager broker", func() {
+				existingBroker.Labels = ownedLabels()
+				platformClient := newDefaultPlatformClient()
+				k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+					return broker, nil
+				}
+
+				_, err := createBroker(platformClient)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("with the adopt policy", func() {
+			BeforeEach(func() {
+				settings.K8S.ExistingBrokerPolicy = config.AdoptExistingBroker
+			})
+
+			It("takes over the existing broker", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+					return broker, nil
+				}
+
+				broker, err := createBroker(platformClient)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(broker.BrokerURL).To(Equal(fakeBrokerUrl))
+				_, adoptedBroker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+				Expect(adoptedBroker.Labels).To(HaveKeyWithValue("team", "a"))
+				for key, value := range ownedLabels() {
+					Expect(adoptedBroker.Labels).To(HaveKeyWithValue(key, value))
+				}
+				Expect(adoptedBroker.Spec.URL).To(Equal(fakeBrokerUrl))
+				Expect(adoptedBroker.Spec.AuthInfo.Basic.SecretRef.Name).To(Equal("id-in-sm"))
+				Expect(adoptedBroker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehavior("Manual")))
+				Expect(k8sApi.DeleteSecretCallCount()).To(BeZero())
+			})
+		})
+
+		Context("with the replace policy", func() {
+			BeforeEach(func() {
+				settings.K8S.ExistingBrokerPolicy = config.ReplaceExistingBroker
+			})
+
+			It("deletes the existing broker and creates it again", func() {
+				platformClient := newDefaultPlatformClient()
+				platformClient.brokerStatusPollInterval = time.Millisecond
+				k8sApi.CreateClusterServiceBrokerReturnsOnCall(1, nil, apierrors.NewAlreadyExists(v1beta1.Resource("clusterservicebrokers"), fakeBrokerName))
+				k8sApi.CreateClusterServiceBrokerReturnsOnCall(2, newRestrictedClusterServiceBroker(fakeBrokerName), nil)
+
+				_, err := createBroker(platformClient)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.DeleteClusterServiceBrokerCallCount()).To(Equal(1))
+				Expect(k8sApi.CreateClusterServiceBrokerCallCount()).To(Equal(3))
+			})
+
+			It("waits until the deleted broker has disappeared", func() {
+				platformClient := newDefaultPlatformClient()
+				platformClient.brokerStatusPollInterval = time.Millisecond
+				k8sApi.CreateClusterServiceBrokerStub = func(context.Context, *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+					if k8sApi.CreateClusterServiceBrokerCallCount() <= 50 {
+						return nil, apierrors.NewAlreadyExists(v1beta1.Resource("clusterservicebrokers"), fakeBrokerName)
+					}
+					return newRestrictedClusterServiceBroker(fakeBrokerName), nil
+				}
+
+				_, err := createBroker(platformClient)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.CreateClusterServiceBrokerCallCount()).To(Equal(51))
+			})
+
+			It("fails if the deleted broker does not disappear within the broker ready timeout", func() {
+				platformClient := newDefaultPlatformClient()
+				platformClient.brokerStatusPollInterval = time.Millisecond
+				platformClient.brokerReadyTimeout = 50 * time.Millisecond
+				k8sApi.CreateClusterServiceBrokerReturns(nil, apierrors.NewAlreadyExists(v1beta1.Resource("clusterservicebrokers"), fakeBrokerName))
+
+				_, err := createBroker(platformClient)
+
+				Expect(err).To(MatchError(ContainSubstring("replaced broker %s still exists after 50ms", fakeBrokerName)))
+			})
+		})
+
+		Context("in namespaces", func() {
+			BeforeEach(func() {
+				settings.K8S.TargetNamespace = "team-a"
+				existingNamespaceBroker := newRestrictedNamespaceServiceBroker(fakeBrokerName, "team-a")
+				existingNamespaceBroker.Labels = nil
+				k8sApi.CreateNamespaceServiceBrokerReturns(nil, apierrors.NewAlreadyExists(v1beta1.Resource("servicebrokers"), fakeBrokerName))
+				k8sApi.RetrieveNamespaceServiceBrokerByNameReturns(existingNamespaceBroker, nil)
+			})
+
+			It("reports the conflict with the fail policy", func() {
+				platformClient := newDefaultPlatformClient()
+
+				_, err := createBroker(platformClient)
+
+				Expect(err).To(MatchError("unable to create broker fake-broker in namespace team-a (broker fake-broker already exists and is not managed by a service broker proxy)"))
+				_, namespace, _ := k8sApi.DeleteSecretArgsForCall(0)
+				Expect(namespace).To(Equal("team-a"))
+			})
+
+			It("takes over the existing broker with the adopt policy", func() {
+				settings.K8S.ExistingBrokerPolicy = config.AdoptExistingBroker
+				platformClient := newDefaultPlatformClient()
+				k8sApi.UpdateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, _ string) (*v1beta1.ServiceBroker, error) {
+					return broker, nil
+				}
+
+				_, err := createBroker(platformClient)
+
+				Expect(err).ToNot(HaveOccurred())
+				_, adoptedBroker, namespace := k8sApi.UpdateNamespaceServiceBrokerArgsForCall(0)
+				Expect(namespace).To(Equal("team-a"))
+				Expect(adoptedBroker.Labels).To(Equal(ownedLabels()))
+			})
+		})
+	})
+
+	Describe("Broker readiness", func() {
+		var brokerConditions []v1beta1.ServiceBrokerCondition
+
+		newReadyPlatformClient := func() *PlatformClient {
+			platformClient := newDefaultPlatformClient()
+			platformClient.brokerStatusPollInterval = time.Millisecond
+			return platformClient
+		}
+
+		createBroker := func(platformClient *PlatformClient) error {
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+			return err
+		}
+
+		BeforeEach(func() {
+			settings.K8S.BrokerReadyTimeout = time.Second
+			brokerConditions = nil
+			k8sApi.CreateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+			k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+				broker := newRestrictedClusterServiceBroker(name)
+				broker.Generation = 1
+				broker.Status.ReconciledGeneration = 1
+				broker.Status.Conditions = brokerConditions
+				return broker, nil
+			}
+		})
+
+		It("does not wait without a broker ready timeout", func() {
+			settings.K8S.BrokerReadyTimeout = 0
+			platformClient := newReadyPlatformClient()
+
+			Expect(createBroker(platformClient)).To(Succeed())
+			Expect(k8sApi.RetrieveClusterServiceBrokerByNameCallCount()).To(BeZero())
+		})
+
+		It("succeeds once the broker is ready", func() {
+			platformClient := newReadyPlatformClient()
+			brokerConditions = []v1beta1.ServiceBrokerCondition{{Type: v1beta1.ServiceBrokerConditionReady, Status: v1beta1.ConditionTrue}}
+
+			Expect(createBroker(platformClient)).To(Succeed())
+			Expect(k8sApi.RetrieveClusterServiceBrokerByNameCallCount()).To(Equal(1))
+		})
+
+		It("waits until the controller has processed the current generation", func() {
+			platformClient := newReadyPlatformClient()
+			brokerConditions = []v1beta1.ServiceBrokerCondition{{Type: v1beta1.ServiceBrokerConditionReady, Status: v1beta1.ConditionTrue}}
+			k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+				broker := newRestrictedClusterServiceBroker(name)
+				broker.Generation = 1
+				if k8sApi.RetrieveClusterServiceBrokerByNameCallCount() > 1 {
+					broker.Status.ReconciledGeneration = 1
+					broker.Status.Conditions = brokerConditions
+				}
+				return broker, nil
+			}
+
+			Expect(createBroker(platformClient)).To(Succeed())
+			Expect(k8sApi.RetrieveClusterServiceBrokerByNameCallCount()).To(Equal(2))
+		})
+
+		It("returns the reason and message of a failed catalog fetch", func() {
+			platformClient := newReadyPlatformClient()
+			brokerConditions = []v1beta1.ServiceBrokerCondition{{
+				Type:    v1beta1.ServiceBrokerConditionReady,
+				Status:  v1beta1.ConditionFalse,
+				Reason:  "ErrorFetchingCatalog",
+				Message: "Error fetching catalog: connection refused",
+			}}
+
+			err := createBroker(platformClient)
+
+			Expect(err).To(MatchError("broker fake-broker is not ready (ErrorFetchingCatalog): Error fetching catalog: connection refused"))
+		})
+
+		It("returns the reason and message of a failed broker", func() {
+			platformClient := newReadyPlatformClient()
+			brokerConditions = []v1beta1.ServiceBrokerCondition{{
+				Type:    v1beta1.ServiceBrokerConditionFailed,
+				Status:  v1beta1.ConditionTrue,
+				Reason:  "ReconciliationRetryTimeout",
+				Message: "Stopped retrying to fetch the catalog",
+			}}
+
+			err := createBroker(platformClient)
+
+			Expect(err).To(MatchError("broker fake-broker is not ready (ReconciliationRetryTimeout): Stopped retrying to fetch the catalog"))
+		})
+
+		It("fails when the broker does not become ready in time", func() {
+			settings.K8S.BrokerReadyTimeout = 20 * time.Millisecond
+			platformClient := newReadyPlatformClient()
+
+			err := createBroker(platformClient)
+
+			Expect(err).To(MatchError("broker fake-broker is not ready after 20ms"))
+		})
+
+		It("waits for the broker in every target namespace", func() {
+			settings.K8S.TargetNamespaces = []string{"team-a", "team-b"}
+			platformClient := newReadyPlatformClient()
+			k8sApi.CreateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, _ string) (*v1beta1.ServiceBroker, error) {
+				return broker, nil
+			}
+			k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+				broker := newRestrictedNamespaceServiceBroker(name, namespace)
+				status := v1beta1.ConditionTrue
+				if namespace == "team-b" {
+					status = v1beta1.ConditionFalse
+				}
+				broker.Status.Conditions = []v1beta1.ServiceBrokerCondition{{
+					Type:    v1beta1.ServiceBrokerConditionReady,
+					Status:  status,
+					Reason:  "ErrorFetchingCatalog",
+					Message: "connection refused",
+				}}
+				return broker, nil
+			}
+
+			err := createBroker(platformClient)
+
+			Expect(err).To(MatchError("unable to create broker fake-broker in namespace team-b (broker fake-broker is not ready (ErrorFetchingCatalog): connection refused)"))
+			Expect(k8sApi.RetrieveNamespaceServiceBrokerByNameCallCount()).To(Equal(2))
+		})
+	})
+
+	Describe("Relist confirmation", func() {
+		var (
+			relistedConditions []v1beta1.ServiceBrokerCondition
+			retrievalTime      v1.Time
+		)
+
+		newRelistPlatformClient := func() *PlatformClient {
+			platformClient := newDefaultPlatformClient()
+			platformClient.brokerStatusPollInterval = time.Millisecond
+			return platformClient
+		}
+
+		fetch := func(platformClient *PlatformClient) error {
+			return platformClient.Fetch(ctx, &platform.UpdateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+		}
+
+		BeforeEach(func() {
+			settings.K8S.RelistTimeout = time.Second
+			retrievalTime = v1.NewTime(time.Date(2020, 5, 4, 12, 0, 0, 0, time.UTC))
+			relistedConditions = []v1beta1.ServiceBrokerCondition{{Type: v1beta1.ServiceBrokerConditionReady, Status: v1beta1.ConditionTrue}}
+			// the broker is relisted with its second retrieval
+			k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+				broker := newRestrictedClusterServiceBroker(name)
+				broker.Generation = 1
+				broker.Status.ReconciledGeneration = 1
+				broker.Status.LastCatalogRetrievalTime = &retrievalTime
+				broker.Status.Conditions = []v1beta1.ServiceBrokerCondition{{Type: v1beta1.ServiceBrokerConditionReady, Status: v1beta1.ConditionTrue}}
+				if k8sApi.RetrieveClusterServiceBrokerByNameCallCount() > 2 {
+					relistTime := v1.NewTime(retrievalTime.Add(time.Minute))
+					broker.Generation = 2
+					broker.Status.ReconciledGeneration = 2
+					broker.Status.LastCatalogRetrievalTime = &relistTime
+					broker.Status.Conditions = relistedConditions
+				}
+				return broker, nil
+			}
+		})
+
+		It("does not wait without a relist timeout", func() {
+			settings.K8S.RelistTimeout = 0
+			platformClient := newRelistPlatformClient()
+
+			Expect(fetch(platformClient)).To(Succeed())
+			Expect(k8sApi.SyncClusterServiceBrokerCallCount()).To(Equal(1))
+			Expect(k8sApi.RetrieveClusterServiceBrokerByNameCallCount()).To(BeZero())
+		})
+
+		It("succeeds once the catalog has been retrieved for the relist request", func() {
+			platformClient := newRelistPlatformClient()
+
+			Expect(fetch(platformClient)).To(Succeed())
+			Expect(k8sApi.SyncClusterServiceBrokerCallCount()).To(Equal(1))
+			Expect(k8sApi.RetrieveClusterServiceBrokerByNameCallCount()).To(Equal(3))
+		})
+
+		It("returns the reason and message if the catalog could not be reloaded", func() {
+			platformClient := newRelistPlatformClient()
+			relistedConditions = []v1beta1.ServiceBrokerCondition{{
+				Type:    v1beta1.ServiceBrokerConditionReady,
+				Status:  v1beta1.ConditionFalse,
+				Reason:  "ErrorFetchingCatalog",
+				Message: "Error fetching catalog: connection refused",
+			}}
+
+			err := fetch(platformClient)
+
+			Expect(err).To(MatchError("broker fake-broker is not ready (ErrorFetchingCatalog): Error fetching catalog: connection refused"))
+		})
+
+		It("fails when the catalog is not relisted in time", func() {
+			settings.K8S.RelistTimeout = 20 * time.Millisecond
+			platformClient := newRelistPlatformClient()
+			k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+				broker := newRestrictedClusterServiceBroker(name)
+				broker.Generation = 2
+				broker.Status.ReconciledGeneration = 1
+				return broker, nil
+			}
+
+			err := fetch(platformClient)
+
+			Expect(err).To(MatchError("catalog of broker fake-broker has not been relisted after 20ms"))
+		})
+
+		It("confirms a relist whose catalog retrieval time falls in the second of the previous retrieval", func() {
+			settings.K8S.RelistTimeout = 20 * time.Millisecond
+			platformClient := newRelistPlatformClient()
+			k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+				broker := newRestrictedClusterServiceBroker(name)
+				broker.Generation = 1
+				broker.Status.ReconciledGeneration = 1
+				broker.Status.LastCatalogRetrievalTime = &retrievalTime
+				broker.Status.Conditions = relistedConditions
+				if k8sApi.RetrieveClusterServiceBrokerByNameCallCount() > 1 {
+					// the time of the relist is stored with a precision of seconds
+					relistTime := v1.NewTime(retrievalTime.Add(400 * time.Millisecond).Truncate(time.Second))
+					broker.Generation = 2
+					broker.Status.ReconciledGeneration = 2
+					broker.Status.LastCatalogRetrievalTime = &relistTime
+				}
+				return broker, nil
+			}
+
+			err := fetch(platformClient)
+
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does not treat a retrieval of the previous generation as a relist", func() {
+			settings.K8S.RelistTimeout = 20 * time.Millisecond
+			platformClient := newRelistPlatformClient()
+			k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+				broker := newRestrictedClusterServiceBroker(name)
+				broker.Generation = 1
+				broker.Status.ReconciledGeneration = 1
+				broker.Status.LastCatalogRetrievalTime = &retrievalTime
+				broker.Status.Conditions = relistedConditions
+				if k8sApi.RetrieveClusterServiceBrokerByNameCallCount() > 1 {
+					broker.Generation = 2
+				}
+				return broker, nil
+			}
+
+			err := fetch(platformClient)
+
+			Expect(err).To(MatchError("catalog of broker fake-broker has not been relisted after 20ms"))
+		})
+
+		It("waits for the relist in every target namespace", func() {
+			settings.K8S.TargetNamespaces = []string{"team-a", "team-b"}
+			settings.K8S.RelistTimeout = 20 * time.Millisecond
+			platformClient := newRelistPlatformClient()
+			k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+				broker := newRestrictedNamespaceServiceBroker(name, namespace)
+				broker.Generation = 1
+				broker.Status.ReconciledGeneration = 1
+				if k8sApi.SyncNamespaceServiceBrokerCallCount() > 0 && namespace == "team-a" {
+					broker.Generation = 2
+					broker.Status.ReconciledGeneration = 2
+					broker.Status.LastCatalogRetrievalTime = &retrievalTime
+					broker.Status.Conditions = relistedConditions
+				}
+				return broker, nil
+			}
+
+			err := fetch(platformClient)
+
+			Expect(err).To(MatchError("unable to sync broker fake-broker in namespace team-b (catalog of broker fake-broker has not been relisted after 20ms)"))
+			Expect(k8sApi.SyncNamespaceServiceBrokerCallCount()).To(Equal(2))
+		})
+	})
+
+	Describe("Broker TLS trust", func() {
+		var caBundleSecret *v1core.Secret
+
+		createClusterBroker := func(platformClient *PlatformClient, id string) *v1beta1.ClusterServiceBroker {
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: id, Name: fakeBrokerName})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sApi.CreateClusterServiceBrokerCallCount()).To(Equal(1))
+			_, broker := k8sApi.CreateClusterServiceBrokerArgsForCall(0)
+			return broker
+		}
+
+		BeforeEach(func() {
+			caBundleSecret = &v1core.Secret{Data: map[string][]byte{CABundleSecretKey: []byte("ca-from-secret")}}
+			k8sApi.RetrieveSecretStub = func(_ context.Context, namespace, name string) (*v1core.Secret, error) {
+				Expect(namespace).To(Equal(settings.K8S.Secret.Namespace))
+				if name != "broker-ca" {
+					return nil, apierrors.NewNotFound(v1core.Resource("secrets"), name)
+				}
+				return caBundleSecret, nil
+			}
+			k8sApi.CreateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+			smClient.GetBrokersReturns([]*types.ServiceBroker{
+				{Base: types.Base{ID: "default-id"}},
+				{Base: types.Base{ID: "insecure-id", Labels: types.Labels{InsecureSkipTLSVerifyLabelKey: {"true"}}}},
+				{Base: types.Base{ID: "secret-id", Labels: types.Labels{CABundleSecretLabelKey: {"broker-ca"}}}},
+			}, nil)
+		})
+
+		It("leaves the TLS settings unset if no TLS trust is configured", func() {
+			broker := createClusterBroker(newDefaultPlatformClient(), "default-id")
+
+			Expect(broker.Spec.CABundle).To(BeEmpty())
+			Expect(broker.Spec.InsecureSkipTLSVerify).To(BeFalse())
+		})
+
+		It("sets the CA bundle from the configured file", func() {
+			caBundleFile, err := ioutil.TempFile("", "ca-bundle")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.Remove(caBundleFile.Name())
+			_, err = caBundleFile.WriteString("ca-from-file")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(caBundleFile.Close()).To(Succeed())
+			settings.K8S.CABundleFile = caBundleFile.Name()
+
+			broker := createClusterBroker(newDefaultPlatformClient(), "default-id")
+
+			Expect(string(broker.Spec.CABundle)).To(Equal("ca-from-file"))
+		})
+
+		It("sets the CA bundle from the configured secret", func() {
+			settings.K8S.CABundleSecret = "broker-ca"
+
+			broker := createClusterBroker(newDefaultPlatformClient(), "default-id")
+
+			Expect(string(broker.Spec.CABundle)).To(Equal("ca-from-secret"))
+		})
+
+		It("fails if the CA bundle secret has no CA bundle", func() {
+			settings.K8S.CABundleSecret = "broker-ca"
+			caBundleSecret.Data = nil
+
+			_, err := newDefaultPlatformClient().CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "default-id", Name: fakeBrokerName})
+
+			Expect(err).To(MatchError("CA bundle secret broker-ca in namespace " + settings.K8S.Secret.Namespace + " has no key ca.crt"))
+			Expect(k8sApi.CreateClusterServiceBrokerCallCount()).To(BeZero())
+		})
+
+		It("skips the TLS verification if configured", func() {
+			settings.K8S.InsecureSkipTLSVerify = true
+
+			broker := createClusterBroker(newDefaultPlatformClient(), "default-id")
+
+			Expect(broker.Spec.InsecureSkipTLSVerify).To(BeTrue())
+			Expect(broker.Spec.CABundle).To(BeEmpty())
+		})
+
+		It("skips the TLS verification of brokers labeled in Service Manager", func() {
+			settings.K8S.CABundleSecret = "broker-ca"
+
+			broker := createClusterBroker(newDefaultPlatformClient(), "insecure-id")
+
+			Expect(broker.Spec.InsecureSkipTLSVerify).To(BeTrue())
+			Expect(broker.Spec.CABundle).To(BeEmpty())
+		})
+
+		It("takes the CA bundle secret of brokers from their Service Manager labels", func() {
+			broker := createClusterBroker(newDefaultPlatformClient(), "secret-id")
+
+			Expect(string(broker.Spec.CABundle)).To(Equal("ca-from-secret"))
+			Expect(broker.Spec.InsecureSkipTLSVerify).To(BeFalse())
+		})
+
+		It("updates the CA bundle of existing brokers when it has changed", func() {
+			settings.K8S.CABundleSecret = "broker-ca"
+			platformClient := newDefaultPlatformClient()
+			existingBroker := newRestrictedClusterServiceBroker(fakeBrokerName)
+			existingBroker.Spec.CABundle = []byte("ca-from-secret")
+			k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+				return existingBroker.DeepCopy(), nil
+			}
+			fetch := func() error {
+				return platformClient.Fetch(ctx, &platform.UpdateServiceBrokerRequest{ID: "default-id", Name: fakeBrokerName})
+			}
+
+			Expect(fetch()).To(Succeed())
+			Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(BeZero())
+
+			caBundleSecret.Data[CABundleSecretKey] = []byte("renewed-ca")
+			Expect(fetch()).To(Succeed())
+
+			Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(1))
+			_, updatedBroker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+			Expect(string(updatedBroker.Spec.CABundle)).To(Equal("renewed-ca"))
+			Expect(k8sApi.SyncClusterServiceBrokerCallCount()).To(Equal(2))
+		})
+
+		It("updates the CA bundle of existing brokers in the target namespaces", func() {
+			settings.K8S.TargetNamespaces = []string{"team-a"}
+			settings.K8S.CABundleSecret = "broker-ca"
+			platformClient := newDefaultPlatformClient()
+			k8sApi.RetrieveNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+				return newRestrictedNamespaceServiceBroker(name, namespace), nil
+			}
+
+			err := platformClient.Fetch(ctx, &platform.UpdateServiceBrokerRequest{ID: "default-id", Name: fakeBrokerName})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sApi.UpdateNamespaceServiceBrokerCallCount()).To(Equal(1))
+			_, updatedBroker, namespace := k8sApi.UpdateNamespaceServiceBrokerArgsForCall(0)
+			Expect(namespace).To(Equal("team-a"))
+			Expect(string(updatedBroker.Spec.CABundle)).To(Equal("ca-from-secret"))
+		})
+	})
+
+	Describe("Relist behavior", func() {
+		createClusterBroker := func(platformClient *PlatformClient, id string) *v1beta1.ClusterServiceBroker {
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: id, Name: fakeBrokerName})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sApi.CreateClusterServiceBrokerCallCount()).To(Equal(1))
+			_, broker := k8sApi.CreateClusterServiceBrokerArgsForCall(0)
+			return broker
+		}
+
+		BeforeEach(func() {
+			k8sApi.CreateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+			k8sApi.CreateNamespaceServiceBrokerStub = func(_ context.Context, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+				return broker, nil
+			}
+			smClient.GetBrokersReturns([]*types.ServiceBroker{
+				{Base: types.Base{ID: "default-id"}},
+				{Base: types.Base{ID: "manual-id", Labels: types.Labels{RelistBehaviorLabelKey: {"Manual"}}}},
+				{Base: types.Base{ID: "duration-id", Labels: types.Labels{
+					RelistBehaviorLabelKey: {"Duration"},
+					RelistDurationLabelKey: {"30m"},
+				}}},
+				{Base: types.Base{ID: "no-duration-id", Labels: types.Labels{RelistBehaviorLabelKey: {"Duration"}}}},
+				{Base: types.Base{ID: "invalid-id", Labels: types.Labels{RelistBehaviorLabelKey: {"Never"}}}},
+			}, nil)
+		})
+
+		It("registers brokers with the Manual relist behavior by default", func() {
+			broker := createClusterBroker(newDefaultPlatformClient(), "default-id")
+
+			Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorManual))
+			Expect(broker.Spec.RelistDuration).To(BeNil())
+		})
+
+		It("registers brokers with the configured relist behavior and duration", func() {
+			settings.K8S.RelistBehavior = config.DurationRelistBehavior
+			settings.K8S.RelistDuration = time.Hour
+
+			broker := createClusterBroker(newDefaultPlatformClient(), "default-id")
+
+			Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorDuration))
+			Expect(broker.Spec.RelistDuration.Duration).To(Equal(time.Hour))
+		})
+
+		It("registers namespace-scoped brokers with the configured relist behavior", func() {
+			settings.K8S.TargetNamespaces = []string{"team-a"}
+			settings.K8S.RelistBehavior = config.DurationRelistBehavior
+			settings.K8S.RelistDuration = time.Hour
+			platformClient := newDefaultPlatformClient()
+
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "default-id", Name: fakeBrokerName})
+
+			Expect(err).ToNot(HaveOccurred())
+			_, broker, _ := k8sApi.CreateNamespaceServiceBrokerArgsForCall(0)
+			Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorDuration))
+			Expect(broker.Spec.RelistDuration.Duration).To(Equal(time.Hour))
+		})
+
+		It("overrides the configured relist behavior with the labels of the broker", func() {
+			settings.K8S.RelistBehavior = config.DurationRelistBehavior
+			settings.K8S.RelistDuration = time.Hour
+
+			broker := createClusterBroker(newDefaultPlatformClient(), "manual-id")
+
+			Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorManual))
+			Expect(broker.Spec.RelistDuration).To(BeNil())
+		})
+
+		It("overrides the configured relist duration with the labels of the broker", func() {
+			broker := createClusterBroker(newDefaultPlatformClient(), "duration-id")
+
+			Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorDuration))
+			Expect(broker.Spec.RelistDuration.Duration).To(Equal(30 * time.Minute))
+		})
+
+		It("falls back to the Manual relist behavior if a broker selects Duration without a relist duration", func() {
+			broker := createClusterBroker(newDefaultPlatformClient(), "no-duration-id")
+
+			Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorManual))
+			Expect(broker.Spec.RelistDuration).To(BeNil())
+		})
+
+		It("ignores invalid relist behavior labels", func() {
+			broker := createClusterBroker(newDefaultPlatformClient(), "invalid-id")
+
+			Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorManual))
+		})
+
+		It("reconciles the relist behavior when the broker is updated", func() {
+			settings.K8S.RelistBehavior = config.DurationRelistBehavior
+			settings.K8S.RelistDuration = time.Hour
+			platformClient := newDefaultPlatformClient()
+			existingBroker := newRestrictedClusterServiceBroker(fakeBrokerName)
+			existingBroker.Spec.RelistBehavior = v1beta1.ServiceBrokerRelistBehaviorManual
+			k8sApi.RetrieveClusterServiceBrokerByNameReturns(existingBroker, nil)
+			k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+
+			_, err := platformClient.UpdateBroker(ctx, &platform.UpdateServiceBrokerRequest{
+				ID: "default-id", Name: fakeBrokerName, BrokerURL: fakeBrokerUrl,
+			})
+
+			Expect(err).ToNot(HaveOccurred())
+			_, broker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+			Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorDuration))
+			Expect(broker.Spec.RelistDuration.Duration).To(Equal(time.Hour))
+		})
+
+		It("keeps a relist behavior set by hand when the broker is updated without a configured relist behavior", func() {
+			platformClient := newDefaultPlatformClient()
+			existingBroker := newRestrictedClusterServiceBroker(fakeBrokerName)
+			existingBroker.Spec.RelistBehavior = v1beta1.ServiceBrokerRelistBehaviorDuration
+			existingBroker.Spec.RelistDuration = &v1.Duration{Duration: 10 * time.Minute}
+			k8sApi.RetrieveClusterServiceBrokerByNameReturns(existingBroker, nil)
+			k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+
+			_, err := platformClient.UpdateBroker(ctx, &platform.UpdateServiceBrokerRequest{
+				ID: "default-id", Name: fakeBrokerName, BrokerURL: fakeBrokerUrl,
+			})
+
+			Expect(err).ToNot(HaveOccurred())
+			_, broker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+			Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorDuration))
+			Expect(broker.Spec.RelistDuration.Duration).To(Equal(10 * time.Minute))
+		})
+
+		It("reconciles the relist behavior selected by the labels of the broker when it is updated", func() {
+			platformClient := newDefaultPlatformClient()
+			existingBroker := newRestrictedClusterServiceBroker(fakeBrokerName)
+			existingBroker.Spec.RelistBehavior = v1beta1.ServiceBrokerRelistBehaviorDuration
+			existingBroker.Spec.RelistDuration = &v1.Duration{Duration: 10 * time.Minute}
+			k8sApi.RetrieveClusterServiceBrokerByNameReturns(existingBroker, nil)
+			k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+
+			_, err := platformClient.UpdateBroker(ctx, &platform.UpdateServiceBrokerRequest{
+				ID: "manual-id", Name: fakeBrokerName, BrokerURL: fakeBrokerUrl,
+			})
+
+			Expect(err).ToNot(HaveOccurred())
+			_, broker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+			Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorManual))
+			Expect(broker.Spec.RelistDuration).To(BeNil())
+		})
+
+		It("adopts existing brokers with the configured relist behavior", func() {
+			settings.K8S.ExistingBrokerPolicy = config.AdoptExistingBroker
+			settings.K8S.RelistBehavior = config.DurationRelistBehavior
+			settings.K8S.RelistDuration = time.Hour
+			platformClient := newDefaultPlatformClient()
+			k8sApi.CreateClusterServiceBrokerReturns(nil, apierrors.NewAlreadyExists(v1beta1.Resource("clusterservicebrokers"), fakeBrokerName))
+			k8sApi.RetrieveClusterServiceBrokerByNameReturns(newRestrictedClusterServiceBroker(fakeBrokerName), nil)
+			k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "default-id", Name: fakeBrokerName})
+
+			Expect(err).ToNot(HaveOccurred())
+			_, broker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+			Expect(broker.Spec.RelistBehavior).To(Equal(v1beta1.ServiceBrokerRelistBehaviorDuration))
+			Expect(broker.Spec.RelistDuration.Duration).To(Equal(time.Hour))
+		})
+	})
+
+	Describe("Visibility debounce", func() {
+		modifyConcurrently := func(platformClient *PlatformClient, requests ...*platform.ModifyPlanAccessRequest) []error {
+			errs := make([]error, len(requests))
+			var wg sync.WaitGroup
+			for i, request := range requests {
+				wg.Add(1)
+				go func(i int, request *platform.ModifyPlanAccessRequest) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					errs[i] = platformClient.EnableAccessForPlan(ctx, request)
+				}(i, request)
+			}
+			wg.Wait()
+			return errs
+		}
+
+		BeforeEach(func() {
+			settings.K8S.VisibilityDebounce = 50 * time.Millisecond
+			k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+				return newRestrictedClusterServiceBroker(name, "spec.externalID in ()"), nil
+			}
+			k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+		})
+
+		It("applies concurrent plan access changes of a broker with a single update", func() {
+			platformClient := newDefaultPlatformClient()
+
+			errs := modifyConcurrently(platformClient,
+				&platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-1"},
+				&platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-2"},
+				&platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-3"},
+			)
+
+			Expect(errs).To(ConsistOf(BeNil(), BeNil(), BeNil()))
+			Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(1))
+			_, broker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+			Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-1, plan-2, plan-3)"))
+		})
+
+		It("returns the error of the update to all changes which it covers", func() {
+			platformClient := newDefaultPlatformClient()
+			k8sApi.UpdateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return nil, expectedError
+			}
+
+			errs := modifyConcurrently(platformClient,
+				&platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-1"},
+				&platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-2"},
+			)
+
+			Expect(errs).To(HaveLen(2))
+			for _, err := range errs {
+				Expect(err).To(MatchError(ContainSubstring(expectedError.Error())))
+			}
+			Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(1))
+		})
+
+		It("applies the changes of the batch when the change which has opened it stops waiting", func() {
+			platformClient := newDefaultPlatformClient()
+			k8sApi.UpdateClusterServiceBrokerStub = func(ctx context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, ctx.Err()
+			}
+			pendingChanges := func() int {
+				platformClient.planAccess.lock.Lock()
+				defer platformClient.planAccess.lock.Unlock()
+				batch, found := platformClient.planAccess.batches[brokerSyncKey{clusterScoped: true, name: fakeBrokerName}]
+				if !found {
+					return 0
+				}
+				return len(batch.changes)
+			}
+
+			firstCtx, cancelFirst := context.WithCancel(ctx)
+			first := make(chan error, 1)
+			go func() {
+				first <- platformClient.EnableAccessForPlan(firstCtx, &platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-1"})
+			}()
+			Eventually(pendingChanges).Should(Equal(1))
+			second := make(chan error, 1)
+			go func() {
+				second <- platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-2"})
+			}()
+			Eventually(pendingChanges).Should(Equal(2))
+			cancelFirst()
+
+			Eventually(first).Should(Receive(MatchError(ContainSubstring(context.Canceled.Error()))))
+			Eventually(second).Should(Receive(BeNil()))
+			Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(1))
+			_, broker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+			Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-1, plan-2)"))
+		})
+
+		It("lifts the plan restriction for a batch with an invalid plan ID", func() {
+			platformClient := newDefaultPlatformClient()
+
+			errs := modifyConcurrently(platformClient,
+				&platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-1"},
+				&platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "invalid plan"},
+			)
+
+			Expect(errs).To(ConsistOf(BeNil(), BeNil()))
+			Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(1))
+			_, broker := k8sApi.UpdateClusterServiceBrokerArgsForCall(0)
+			Expect(broker.Spec.CatalogRestrictions).To(BeNil())
+		})
+
+		It("does not fail the other changes for a plan ID which cannot be restricted in a namespace", func() {
+			platformClient := newDefaultPlatformClient()
+			k8sApi.RetrieveNamespaceServiceBrokerByNameReturns(nil, apierrors.NewNotFound(v1beta1.Resource("servicebrokers"), fakeBrokerName))
+			clusterBroker := newRestrictedClusterServiceBroker(fakeBrokerName)
+			clusterBroker.Spec.AuthInfo = &v1beta1.ClusterServiceBrokerAuthInfo{
+				Basic: &v1beta1.ClusterBasicAuthConfig{SecretRef: &v1beta1.ObjectReference{Name: "id-in-sm", Namespace: "secretNamespace"}},
+			}
+			k8sApi.RetrieveClusterServiceBrokerByNameReturns(clusterBroker, nil)
+			k8sApi.RetrieveSecretReturns(newServiceBrokerCredentialsSecret("secretNamespace", "id-in-sm", "admin", "admin", nil), nil)
+			namespaces := map[string][]string{NamespacesLabelKey: {"team-a"}}
+
+			errs := modifyConcurrently(platformClient,
+				&platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-1", Labels: namespaces},
+				&platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "invalid plan", Labels: namespaces},
+			)
+
+			Expect(errs[0]).ToNot(HaveOccurred())
+			Expect(errs[1]).To(MatchError(ContainSubstring("catalog plan ID invalid plan cannot be used in catalog restrictions")))
+			_, broker, _ := k8sApi.CreateNamespaceServiceBrokerArgsForCall(0)
+			Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-1)"))
+		})
+
+		It("updates different brokers separately", func() {
+			platformClient := newDefaultPlatformClient()
+
+			errs := modifyConcurrently(platformClient,
+				&platform.ModifyPlanAccessRequest{BrokerName: "broker-a", CatalogPlanID: "plan-1"},
+				&platform.ModifyPlanAccessRequest{BrokerName: "broker-b", CatalogPlanID: "plan-2"},
+			)
+
+			Expect(errs).To(ConsistOf(BeNil(), BeNil()))
+			Expect(k8sApi.UpdateClusterServiceBrokerCallCount()).To(Equal(2))
+		})
+
+		It("creates a namespace-scoped broker with all plans enabled in the namespace", func() {
+			platformClient := newDefaultPlatformClient()
+			k8sApi.RetrieveNamespaceServiceBrokerByNameReturns(nil, apierrors.NewNotFound(v1beta1.Resource("servicebrokers"), fakeBrokerName))
+			k8sApi.RetrieveClusterServiceBrokerByNameStub = func(_ context.Context, name string) (*v1beta1.ClusterServiceBroker, error) {
+				broker := newRestrictedClusterServiceBroker(name)
+				broker.Spec.AuthInfo = &v1beta1.ClusterServiceBrokerAuthInfo{
+					Basic: &v1beta1.ClusterBasicAuthConfig{
+						SecretRef: &v1beta1.ObjectReference{Name: "id-in-sm", Namespace: settings.K8S.Secret.Namespace},
+					},
+				}
+				return broker, nil
+			}
+			k8sApi.RetrieveSecretReturns(newServiceBrokerCredentialsSecret(settings.K8S.Secret.Namespace, "id-in-sm", "admin", "admin", nil), nil)
+			namespaces := types.Labels{NamespacesLabelKey: {"team-a"}}
+
+			errs := modifyConcurrently(platformClient,
+				&platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-1", Labels: namespaces},
+				&platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-2", Labels: namespaces},
+			)
+
+			Expect(errs).To(ConsistOf(BeNil(), BeNil()))
+			Expect(k8sApi.CreateNamespaceServiceBrokerCallCount()).To(Equal(1))
+			_, broker, namespace := k8sApi.CreateNamespaceServiceBrokerArgsForCall(0)
+			Expect(namespace).To(Equal("team-a"))
+			Expect(broker.Spec.CatalogRestrictions.ServicePlan).To(ConsistOf("spec.externalID in (plan-1, plan-2)"))
+		})
+	})
+
+	Describe("Broker syncs", func() {
+		It("starts the configured number of sync workers", func() {
+			settings.K8S.SyncWorkers = 3
+			platformClient := newDefaultPlatformClient()
+
+			Expect(platformClient.StartBrokerSyncs(ctx)).To(Succeed())
+
+			Expect(k8sApi.StartBrokerSyncsCallCount()).To(Equal(1))
+			_, workers, rateLimiter := k8sApi.StartBrokerSyncsArgsForCall(0)
+			Expect(workers).To(Equal(3))
+			Expect(rateLimiter).ToNot(BeNil())
+		})
+
+		It("requests relists with the configured retry limit", func() {
+			settings.K8S.SyncRetries = 5
+			platformClient := newDefaultPlatformClient()
+			k8sApi.RetrieveClusterServiceBrokerByNameReturns(newRestrictedClusterServiceBroker(fakeBrokerName), nil)
+
+			Expect(platformClient.Fetch(ctx, &platform.UpdateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})).To(Succeed())
+
+			Expect(k8sApi.SyncClusterServiceBrokerCallCount()).To(Equal(1))
+			_, _, retries := k8sApi.SyncClusterServiceBrokerArgsForCall(0)
+			Expect(retries).To(Equal(5))
+		})
+	})
+
+	Describe("Metrics", func() {
+		var (
+			platformClient  *PlatformClient
+			instrumentedAPI *instrumentedKubernetesAPI
+		)
+
+		operations := func(operation, scope, result string) float64 {
+			return testutil.ToFloat64(platformClient.metrics.operations.WithLabelValues(operation, scope, result))
+		}
+
+		BeforeEach(func() {
+			platformClient = newDefaultPlatformClient()
+			instrumentedAPI = newInstrumentedKubernetesAPI(k8sApi, platformClient.metrics)
+		})
+
+		It("counts the calls to the Kubernetes API by operation, scope and result", func() {
+			k8sApi.RetrieveNamespaceServiceBrokerByNameReturnsOnCall(1, nil, expectedError)
+
+			_, err := instrumentedAPI.RetrieveNamespaceServiceBrokerByName(ctx, fakeBrokerName, "team-a")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = instrumentedAPI.RetrieveNamespaceServiceBrokerByName(ctx, fakeBrokerName, "team-a")
+			Expect(err).To(Equal(expectedError))
+			Expect(instrumentedAPI.DeleteClusterServiceBroker(ctx, fakeBrokerName, &v1.DeleteOptions{})).To(Succeed())
+
+			Expect(operations("RetrieveNamespaceServiceBrokerByName", "namespace", "success")).To(Equal(1.0))
+			Expect(operations("RetrieveNamespaceServiceBrokerByName", "namespace", "error")).To(Equal(1.0))
+			Expect(operations("DeleteClusterServiceBroker", "cluster", "success")).To(Equal(1.0))
+			Expect(testutil.CollectAndCount(platformClient.metrics.operationDuration)).To(Equal(3))
+		})
+
+		It("counts the failed broker syncs by scope", func() {
+			k8sApi.SyncNamespaceServiceBrokerReturns(expectedError)
+
+			Expect(instrumentedAPI.SyncClusterServiceBroker(ctx, fakeBrokerName, 1)).To(Succeed())
+			Expect(instrumentedAPI.SyncNamespaceServiceBroker(ctx, fakeBrokerName, "team-a", 1)).To(Equal(expectedError))
+
+			Expect(testutil.ToFloat64(platformClient.metrics.syncFailures.WithLabelValues("cluster"))).To(Equal(0.0))
+			Expect(testutil.ToFloat64(platformClient.metrics.syncFailures.WithLabelValues("namespace"))).To(Equal(1.0))
+		})
+
+		It("counts the retries of broker syncs", func() {
+			Expect(platformClient.StartBrokerSyncs(ctx)).To(Succeed())
+			_, _, rateLimiter := k8sApi.StartBrokerSyncsArgsForCall(0)
+
+			rateLimiter.When("sync")
+			rateLimiter.When("sync")
+
+			Expect(testutil.ToFloat64(platformClient.metrics.syncRetries)).To(Equal(2.0))
+		})
+
+		It("counts the managed brokers by their readiness when the brokers are listed", func() {
+			readyBroker := newRestrictedClusterServiceBroker("ready-broker")
+			readyBroker.UID = "ready-uid"
+			readyBroker.Status.Conditions = []v1beta1.ServiceBrokerCondition{
+				{Type: v1beta1.ServiceBrokerConditionReady, Status: v1beta1.ConditionTrue},
+			}
+			failingBroker := newRestrictedClusterServiceBroker("failing-broker")
+			failingBroker.UID = "failing-uid"
+			failingBroker.Status.Conditions = []v1beta1.ServiceBrokerCondition{
+				{Type: v1beta1.ServiceBrokerConditionReady, Status: v1beta1.ConditionFalse, Reason: "ErrorFetchingCatalog"},
+			}
+			k8sApi.RetrieveClusterServiceBrokersReturns(&v1beta1.ClusterServiceBrokerList{Items: []v1beta1.ClusterServiceBroker{
+				*readyBroker, *failingBroker, *newRestrictedClusterServiceBroker("new-broker"),
+			}}, nil)
+
+			_, err := platformClient.GetBrokers(ctx)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(testutil.ToFloat64(platformClient.metrics.managedBrokers.WithLabelValues("True"))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(platformClient.metrics.managedBrokers.WithLabelValues("False"))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(platformClient.metrics.managedBrokers.WithLabelValues("Unknown"))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(platformClient.metrics.lastBrokerList)).To(BeNumerically(">", 0))
+		})
+
+		It("does not update the managed brokers when the brokers cannot be listed", func() {
+			k8sApi.RetrieveClusterServiceBrokersReturns(nil, expectedError)
+
+			_, err := platformClient.GetBrokers(ctx)
+
+			Expect(err).To(HaveOccurred())
+			Expect(testutil.CollectAndCount(platformClient.metrics.managedBrokers)).To(Equal(0))
+			Expect(testutil.ToFloat64(platformClient.metrics.lastBrokerList)).To(Equal(0.0))
+		})
+
+		It("exports the metrics", func() {
+			_, err := instrumentedAPI.RetrieveClusterServiceBrokers(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			routes := platformClient.MetricsController().Routes()
+			Expect(routes).To(HaveLen(1))
+			Expect(routes[0].Endpoint).To(Equal(web.Endpoint{Method: http.MethodGet, Path: MetricsPath}))
+
+			response, err := routes[0].Handler(&web.Request{Request: httptest.NewRequest(http.MethodGet, MetricsPath, nil)})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(string(response.Body)).To(ContainSubstring(
+				`sbproxy_k8s_api_operations_total{operation="RetrieveClusterServiceBrokers",result="success",scope="cluster"} 1`))
+		})
+	})
+
+	Describe("Logging", func() {
+		var (
+			logCtx  context.Context
+			logHook *logtest.Hook
+		)
+
+		BeforeEach(func() {
+			var logger *logrus.Logger
+			logger, logHook = logtest.NewNullLogger()
+			logCtx = log.ContextWithLogger(ctx, logrus.NewEntry(logger).WithField(log.FieldCorrelationID, "correlation-id"))
+		})
+
+		It("logs the broker, the scope, the duration and the result of an operation with the correlation ID", func() {
+			platformClient := newDefaultPlatformClient()
+			k8sApi.CreateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+
+			_, err := platformClient.CreateBroker(logCtx, &platform.CreateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+			Expect(err).ToNot(HaveOccurred())
+			entry := logHook.LastEntry()
+			Expect(entry.Level).To(Equal(logrus.InfoLevel))
+			Expect(entry.Message).To(Equal("Creating broker succeeded"))
+			Expect(entry.Data).To(HaveKeyWithValue(log.FieldCorrelationID, "correlation-id"))
+			Expect(entry.Data).To(HaveKeyWithValue("broker", fakeBrokerName))
+			Expect(entry.Data).To(HaveKeyWithValue("broker_id", "id-in-sm"))
+			Expect(entry.Data).To(HaveKeyWithValue("scope", "cluster"))
+			Expect(entry.Data).To(HaveKeyWithValue("result", "success"))
+			Expect(entry.Data).To(HaveKey("duration"))
+		})
+
+		It("logs the namespaces and the error of a failed operation", func() {
+			settings.K8S.TargetNamespaces = []string{"team-a", "team-b"}
+			platformClient := newDefaultPlatformClient()
+			k8sApi.DeleteNamespaceServiceBrokerReturns(expectedError)
+
+			err := platformClient.DeleteBroker(logCtx, &platform.DeleteServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+			Expect(err).To(HaveOccurred())
+			entry := logHook.LastEntry()
+			Expect(entry.Level).To(Equal(logrus.ErrorLevel))
+			Expect(entry.Message).To(Equal("Deleting broker failed"))
+			Expect(entry.Data).To(HaveKeyWithValue("scope", "namespace"))
+			Expect(entry.Data).To(HaveKeyWithValue("namespace", "team-a,team-b"))
+			Expect(entry.Data).To(HaveKeyWithValue("result", "error"))
+			Expect(entry.Data).To(HaveKey(logrus.ErrorKey))
+		})
+
+		It("logs the writes to the Kubernetes API with the broker of the operation", func() {
+			catalogAPI := NewDefaultKubernetesAPI(&servicecatalog.SDK{
+				K8sClient:            k8sfake.NewSimpleClientset(),
+				ServiceCatalogClient: svcatfake.NewSimpleClientset(),
+			})
+			platformClient := newDefaultPlatformClient()
+			platformClient.platformAPI = catalogAPI
+
+			err := platformClient.DeleteBroker(logCtx, &platform.DeleteServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+			Expect(err).To(HaveOccurred())
+			entry := logHook.AllEntries()[0]
+			Expect(entry.Level).To(Equal(logrus.WarnLevel))
+			Expect(entry.Message).To(Equal("Deleting broker credentials secret failed"))
+			Expect(entry.Data).To(HaveKeyWithValue(log.FieldCorrelationID, "correlation-id"))
+			Expect(entry.Data).To(HaveKeyWithValue("broker", fakeBrokerName))
+			Expect(entry.Data).To(HaveKeyWithValue("broker_id", "id-in-sm"))
+			Expect(entry.Data).To(HaveKeyWithValue("secret", "id-in-sm"))
+			Expect(entry.Data).To(HaveKeyWithValue("namespace", settings.K8S.Secret.Namespace))
+		})
+	})
+
+	Describe("Events", func() {
+		var (
+			eventsCtx      context.Context
+			k8sClient      *k8sfake.Clientset
+			svcatClient    *svcatfake.Clientset
+			platformClient *PlatformClient
+			eventsLock     sync.Mutex
+			events         []v1core.Event
+		)
+
+		// the fake clientset rejects the Events which the recorder creates without a namespace in the request
+		eventsIn := func(namespace string) func() []v1core.Event {
+			return func() []v1core.Event {
+				eventsLock.Lock()
+				defer eventsLock.Unlock()
+				var result []v1core.Event
+				for _, event := range events {
+					if event.Namespace == namespace {
+						result = append(result, event)
+					}
+				}
+				return result
+			}
+		}
+
+		BeforeEach(func() {
+			logger, _ := logtest.NewNullLogger()
+			eventsCtx = log.ContextWithLogger(ctx, logrus.NewEntry(logger).WithField(log.FieldCorrelationID, "correlation-id"))
+			k8sClient = k8sfake.NewSimpleClientset()
+			events = nil
+			k8sClient.PrependReactor("create", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				event := action.(k8stesting.CreateAction).GetObject().(*v1core.Event)
+				eventsLock.Lock()
+				defer eventsLock.Unlock()
+				events = append(events, *event)
+				return true, event, nil
+			})
+			svcatClient = svcatfake.NewSimpleClientset()
+			platformClient = newDefaultPlatformClient()
+			platformClient.platformAPI = NewDefaultKubernetesAPI(&servicecatalog.SDK{
+				K8sClient:            k8sClient,
+				ServiceCatalogClient: svcatClient,
+			})
+		})
+
+		It("writes a Normal Event with the Service Manager context on a created broker", func() {
+			_, err := platformClient.CreateBroker(eventsCtx, &platform.CreateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(eventsIn(v1.NamespaceDefault)).Should(HaveLen(1))
+			event := eventsIn(v1.NamespaceDefault)()[0]
+			Expect(event.Type).To(Equal(v1core.EventTypeNormal))
+			Expect(event.Reason).To(Equal(BrokerCreatedReason))
+			Expect(event.InvolvedObject.Kind).To(Equal("ClusterServiceBroker"))
+			Expect(event.InvolvedObject.Name).To(Equal(fakeBrokerName))
+			Expect(event.Message).To(Equal("Creating cluster service broker " + fakeBrokerName +
+				" succeeded (Service Manager broker id-in-sm, correlation ID correlation-id)"))
+			Expect(event.Annotations).To(HaveKeyWithValue(BrokerIDLabelKey, "id-in-sm"))
+			Expect(event.Annotations).To(HaveKeyWithValue(CorrelationIDAnnotationKey, "correlation-id"))
+		})
+
+		It("writes a Warning Event with the error if a broker could not be deleted", func() {
+			catalogAPI := platformClient.platformAPI.(*ServiceCatalogAPI)
+
+			err := catalogAPI.DeleteNamespaceServiceBroker(withBrokerLogger(eventsCtx, fakeBrokerName, "id-in-sm"), fakeBrokerName, "team-a", &v1.DeleteOptions{})
+
+			Expect(err).To(HaveOccurred())
+			Eventually(eventsIn("team-a")).Should(HaveLen(1))
+			event := eventsIn("team-a")()[0]
+			Expect(event.Type).To(Equal(v1core.EventTypeWarning))
+			Expect(event.Reason).To(Equal(BrokerDeleteFailedReason))
+			Expect(event.InvolvedObject.Kind).To(Equal("ServiceBroker"))
+			Expect(event.Message).To(HavePrefix("Deleting service broker " + fakeBrokerName +
+				" failed (Service Manager broker id-in-sm, correlation ID correlation-id): "))
+		})
+
+		It("writes the relist Event with the Service Manager context of the sync", func() {
+			_, err := svcatClient.ServicecatalogV1beta1().ClusterServiceBrokers().Create(ctx, &v1beta1.ClusterServiceBroker{
+				ObjectMeta: v1.ObjectMeta{Name: fakeBrokerName, UID: "broker-uid"},
+			}, v1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			catalogAPI := platformClient.platformAPI.(*ServiceCatalogAPI)
+
+			err = catalogAPI.SyncClusterServiceBroker(withBrokerLogger(eventsCtx, fakeBrokerName, "id-in-sm"), fakeBrokerName, 1)
+
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(eventsIn(v1.NamespaceDefault)).Should(HaveLen(1))
+			event := eventsIn(v1.NamespaceDefault)()[0]
+			Expect(event.Reason).To(Equal(BrokerRelistRequestedReason))
+			Expect(event.Message).To(Equal("Requesting a relist of cluster service broker " + fakeBrokerName +
+				" succeeded (Service Manager broker id-in-sm, correlation ID correlation-id)"))
+			Expect(event.Annotations).To(HaveKeyWithValue(BrokerIDLabelKey, "id-in-sm"))
+			Expect(event.Annotations).To(HaveKeyWithValue(CorrelationIDAnnotationKey, "correlation-id"))
+		})
+
+		It("writes the changed plans on the broker whose plan access has been updated", func() {
+			_, err := svcatClient.ServicecatalogV1beta1().ClusterServiceBrokers().Create(ctx, &v1beta1.ClusterServiceBroker{
+				ObjectMeta: v1.ObjectMeta{Name: fakeBrokerName, UID: "broker-uid", Labels: map[string]string{BrokerIDLabelKey: "id-in-sm"}},
+			}, v1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = platformClient.EnableAccessForPlan(eventsCtx, &platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-1"})
+
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(eventsIn(v1.NamespaceDefault)).Should(HaveLen(1))
+			event := eventsIn(v1.NamespaceDefault)()[0]
+			Expect(event.Reason).To(Equal(PlanAccessUpdatedReason))
+			Expect(event.InvolvedObject.UID).To(BeEquivalentTo("broker-uid"))
+			Expect(event.Message).To(Equal("Enabling plans plan-1 of cluster service broker " + fakeBrokerName +
+				" succeeded (Service Manager broker id-in-sm, correlation ID correlation-id)"))
+			Expect(event.Annotations).To(HaveKeyWithValue(CatalogPlanIDsAnnotationKey, "plan-1"))
+		})
+
+		It("writes a single Event with the Service Manager context for the plan access changes of a debounce window", func() {
+			_, err := svcatClient.ServicecatalogV1beta1().ClusterServiceBrokers().Create(ctx, &v1beta1.ClusterServiceBroker{
+				ObjectMeta: v1.ObjectMeta{Name: fakeBrokerName, UID: "broker-uid", Labels: map[string]string{BrokerIDLabelKey: "id-in-sm"}},
+			}, v1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			platformClient.planAccess = newPlanAccessDebouncer(50 * time.Millisecond)
+			pendingBatches := func() int {
+				platformClient.planAccess.lock.Lock()
+				defer platformClient.planAccess.lock.Unlock()
+				return len(platformClient.planAccess.batches)
+			}
+
+			firstChange := make(chan error, 1)
+			go func() {
+				firstChange <- platformClient.EnableAccessForPlan(eventsCtx, &platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-1"})
+			}()
+			Eventually(pendingBatches).Should(Equal(1))
+			err = platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{BrokerName: fakeBrokerName, CatalogPlanID: "plan-2"})
+
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(firstChange).Should(Receive(BeNil()))
+			Eventually(eventsIn(v1.NamespaceDefault)).Should(HaveLen(1))
+			event := eventsIn(v1.NamespaceDefault)()[0]
+			Expect(event.Reason).To(Equal(PlanAccessUpdatedReason))
+			Expect(event.Message).To(Equal("Enabling plans plan-1, plan-2 of cluster service broker " + fakeBrokerName +
+				" succeeded (Service Manager broker id-in-sm, correlation ID correlation-id)"))
+			Expect(event.Annotations).To(HaveKeyWithValue(CatalogPlanIDsAnnotationKey, "plan-1,plan-2"))
+		})
+	})
+
+	Describe("Tracing", func() {
+		var (
+			spans            *tracetest.InMemoryExporter
+			previousProvider trace.TracerProvider
+		)
+
+		spanNamed := func(name string) *sdktrace.SpanSnapshot {
+			for _, span := range spans.GetSpans() {
+				if span.Name == name {
+					return span
+				}
+			}
+			Fail("no span named " + name)
+			return nil
+		}
+
+		BeforeEach(func() {
+			spans = tracetest.NewInMemoryExporter()
+			previousProvider = otel.GetTracerProvider()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)))
+			_, err := StartTracing(ctx, config.DefaultTracingSettings())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			otel.SetTracerProvider(previousProvider)
+		})
+
+		It("traces the platform operations and the Kubernetes API calls they make", func() {
+			platformClient := newDefaultPlatformClient()
+			platformClient.platformAPI = newInstrumentedKubernetesAPI(k8sApi, platformClient.metrics)
+			k8sApi.CreateClusterServiceBrokerReturns(nil, expectedError)
+
+			_, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+			Expect(err).To(HaveOccurred())
+			operation := spanNamed("PlatformClient.CreateBroker")
+			Expect(operation.StatusCode).To(Equal(codes.Error))
+			Expect(operation.Attributes).To(ContainElement(attribute.String("sbproxy.broker_id", "id-in-sm")))
+			call := spanNamed("KubernetesAPI.CreateClusterServiceBroker")
+			Expect(call.Parent.SpanID()).To(Equal(operation.SpanContext.SpanID()))
+			Expect(call.StatusCode).To(Equal(codes.Error))
+			Expect(call.Attributes).To(ContainElement(attribute.String("k8s.scope", "cluster")))
+			secretCall := spanNamed("KubernetesAPI.UpdateServiceBrokerCredentials")
+			Expect(secretCall.Parent.SpanID()).To(Equal(operation.SpanContext.SpanID()))
+			Expect(secretCall.StatusCode).To(Equal(codes.Unset))
+		})
+
+		It("continues the trace of incoming requests", func() {
+			traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+			request := httptest.NewRequest(http.MethodGet, "/v1/osb/catalog", nil)
+			request.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+			var handlerCtx context.Context
+
+			response, err := NewTracingFilter().Run(&web.Request{Request: request}, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+				handlerCtx = req.Context()
+				return &web.Response{StatusCode: http.StatusOK}, nil
+			}))
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(trace.SpanContextFromContext(handlerCtx).TraceID().String()).To(Equal(traceID))
+			span := spanNamed("GET /v1/osb/catalog")
+			Expect(span.SpanContext.TraceID().String()).To(Equal(traceID))
+			Expect(span.Parent.SpanID().String()).To(Equal("00f067aa0ba902b7"))
+		})
+	})
+
+	Describe("Broker cache", func() {
+		Context("when disabled", func() {
+			It("reads brokers from the API server", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveClusterServiceBrokersReturns(&v1beta1.ClusterServiceBrokerList{}, nil)
+
+				_, err := platformClient.GetBrokers(ctx)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sApi.RetrieveClusterServiceBrokersCallCount()).To(Equal(1))
+				Expect(k8sApi.RetrieveCachedClusterServiceBrokersCallCount()).To(BeZero())
+			})
+
+			It("is not started and has no health indicators", func() {
+				platformClient := newDefaultPlatformClient()
+
+				Expect(platformClient.StartBrokerCache(ctx)).To(Succeed())
+
+				Expect(k8sApi.StartBrokerCacheCallCount()).To(BeZero())
+				Expect(platformClient.HealthIndicators()).To(BeEmpty())
+			})
+		})
+
+		Context("when enabled", func() {
+			BeforeEach(func() {
+				settings.K8S.BrokerCache = true
+			})
+
+			It("starts the cache", func() {
+				platformClient := newDefaultPlatformClient()
+
+				Expect(platformClient.StartBrokerCache(ctx)).To(Succeed())
+
+				Expect(k8sApi.StartBrokerCacheCallCount()).To(Equal(1))
+			})
+
+			It("returns the error when the cache cannot be started", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.StartBrokerCacheReturns(expectedError)
+
+				Expect(platformClient.StartBrokerCache(ctx)).To(Equal(expectedError))
+			})
+
+			It("lists cluster-scoped brokers from the cache", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveCachedClusterServiceBrokersReturns(&v1beta1.ClusterServiceBrokerList{
+					Items: []v1beta1.ClusterServiceBroker{*newRestrictedClusterServiceBroker(fakeBrokerName)},
+				}, nil)
+
+				brokers, err := platformClient.GetBrokers(ctx)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(brokers).To(HaveLen(1))
+				Expect(brokers[0].Name).To(Equal(fakeBrokerName))
+				Expect(k8sApi.RetrieveClusterServiceBrokersCallCount()).To(BeZero())
+			})
+
+			It("gets a cluster-scoped broker from the cache", func() {
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveCachedClusterServiceBrokerByNameReturns(newRestrictedClusterServiceBroker(fakeBrokerName), nil)
+
+				broker, err := platformClient.GetBrokerByName(ctx, fakeBrokerName)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(broker.Name).To(Equal(fakeBrokerName))
+				Expect(k8sApi.RetrieveClusterServiceBrokerByNameCallCount()).To(BeZero())
+			})
+
+			It("lists namespace-scoped brokers from the cache", func() {
+				settings.K8S.TargetNamespaces = []string{"team-a", "team-b"}
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveCachedNamespaceServiceBrokersReturns(&v1beta1.ServiceBrokerList{
+					Items: []v1beta1.ServiceBroker{*newRestrictedNamespaceServiceBroker(fakeBrokerName, "team-a")},
+				}, nil)
+
+				brokers, err := platformClient.GetBrokers(ctx)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(brokers).To(HaveLen(1))
+				Expect(k8sApi.RetrieveCachedNamespaceServiceBrokersCallCount()).To(Equal(2))
+				Expect(k8sApi.RetrieveNamespaceServiceBrokersCallCount()).To(BeZero())
+			})
+
+			It("gets a namespace-scoped broker from the cache", func() {
+				settings.K8S.TargetNamespaces = []string{"team-a", "team-b"}
+				platformClient := newDefaultPlatformClient()
+				k8sApi.RetrieveCachedNamespaceServiceBrokerByNameStub = func(_ context.Context, name, namespace string) (*v1beta1.ServiceBroker, error) {
+					if namespace == "team-a" {
+						return nil, apierrors.NewNotFound(v1beta1.Resource("servicebrokers"), name)
+					}
+					return newRestrictedNamespaceServiceBroker(name, namespace), nil
+				}
+
+				broker, err := platformClient.GetBrokerByName(ctx, fakeBrokerName)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(broker.Name).To(Equal(fakeBrokerName))
+				Expect(k8sApi.RetrieveCachedNamespaceServiceBrokerByNameCallCount()).To(Equal(2))
+				Expect(k8sApi.RetrieveNamespaceServiceBrokerByNameCallCount()).To(BeZero())
+			})
+
+			It("reports readiness once the cache has been synced", func() {
+				platformClient := newDefaultPlatformClient()
+				indicators := platformClient.HealthIndicators()
+				Expect(indicators).To(HaveLen(1))
+				Expect(indicators[0].Name()).To(Equal(BrokerCacheIndicatorName))
+
+				_, err := indicators[0].Status()
+				Expect(err).To(HaveOccurred())
+
+				k8sApi.BrokerCacheSyncedReturns(true)
+				_, err = indicators[0].Status()
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Context propagation", func() {
+		type contextKey struct{}
+
+		It("passes the context of the caller to the kubernetes API", func() {
+			platformClient := newDefaultPlatformClient()
+			callerCtx := context.WithValue(ctx, contextKey{}, "caller")
+			k8sApi.CreateClusterServiceBrokerStub = func(_ context.Context, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+				return broker, nil
+			}
+
+			_, err := platformClient.CreateBroker(callerCtx, &platform.CreateServiceBrokerRequest{ID: "id-in-sm", Name: fakeBrokerName})
+
+			Expect(err).ToNot(HaveOccurred())
+			secretCtx, _ := k8sApi.UpdateServiceBrokerCredentialsArgsForCall(0)
+			Expect(secretCtx.Value(contextKey{})).To(Equal("caller"))
+			brokerCtx, _ := k8sApi.CreateClusterServiceBrokerArgsForCall(0)
+			Expect(brokerCtx.Value(contextKey{})).To(Equal("caller"))
+		})
+
+		It("stops relisting when the context is done", func() {
+			cancelledCtx, cancel := context.WithCancel(ctx)
+			cancel()
+			requests := 0
+
+			err := relist(cancelledCtx, 3, func() error {
+				requests++
+				return nil
+			})
+
+			Expect(err).To(Equal(context.Canceled))
+			Expect(requests).To(Equal(0))
+		})
+
+		It("retries relisting on conflicts", func() {
+			requests := 0
+
+			err := relist(ctx, 3, func() error {
+				requests++
+				if requests < 3 {
+					return apierrors.NewConflict(v1beta1.Resource("clusterservicebrokers"), fakeBrokerName, expectedError)
+				}
+				return nil
+			})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal(3))
+		})
+	})
+
+	Describe("Platform Broker Name", func() {
+		It("returns lower case and replaces underscores to hyphens", func() {
+			brokerNameWithUnderscoreAndCaps := "Fake_Broker-Name_1234"
+			expectedBrokerName := "fake-broker-name-1234"
+			platformClient := newDefaultPlatformClient()
+			Expect(platformClient.GetBrokerPlatformName(brokerNameWithUnderscoreAndCaps)).To(Equal(expectedBrokerName))
+		})
+	})
+})
+
+func ownedLabels() map[string]string {
+	return map[string]string{
+		ManagedByLabelKey:     ManagedByLabelValue,
+		ProxyInstanceLabelKey: "default",
+		BrokerIDLabelKey:      "id-in-sm",
+	}
+}
+
+func newRestrictedClusterServiceBroker(name string, planRestrictions ...string) *v1beta1.ClusterServiceBroker {
+	return &v1beta1.ClusterServiceBroker{
+		ObjectMeta: v1.ObjectMeta{
+			Name:   name,
+			Labels: ownedLabels(),
+		},
+		Spec: v1beta1.ClusterServiceBrokerSpec{
+			CommonServiceBrokerSpec: v1beta1.CommonServiceBrokerSpec{
+				CatalogRestrictions: &v1beta1.CatalogRestrictions{
+					ServicePlan: planRestrictions,
+				},
+			},
+		},
+	}
+}
+
+func newRestrictedNamespaceServiceBroker(name, namespace string, planRestrictions ...string) *v1beta1.ServiceBroker {
+	return &v1beta1.ServiceBroker{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    ownedLabels(),
+		},
+		Spec: v1beta1.ServiceBrokerSpec{
+			CommonServiceBrokerSpec: v1beta1.CommonServiceBrokerSpec{
+				CatalogRestrictions: &v1beta1.CatalogRestrictions{
+					ServicePlan: planRestrictions,
+				},
+			},
+		},
+	}
+}
+
+func newClusterServicePlanList(catalogPlanIDs ...string) *v1beta1.ClusterServicePlanList {
+	plans := &v1beta1.ClusterServicePlanList{}
+	for _, catalogPlanID := range catalogPlanIDs {
+		plan := v1beta1.ClusterServicePlan{}
+		plan.Spec.ExternalID = catalogPlanID
+		plans.Items = append(plans.Items, plan)
+	}
+	return plans
+}
+
+func newServicePlanList(catalogPlanIDs ...string) *v1beta1.ServicePlanList {
+	plans := &v1beta1.ServicePlanList{}
+	for _, catalogPlanID := range catalogPlanIDs {
+		plan := v1beta1.ServicePlan{}
+		plan.Spec.ExternalID = catalogPlanID
+		plans.Items = append(plans.Items, plan)
+	}
+	return plans
+}
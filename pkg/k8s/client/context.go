@@ -0,0 +1,21 @@
+package client
+
+import "context"
+
+// valueOnlyContext has the values of one context and the deadline and cancellation of another one. Work which outlives
+// the callers waiting for it runs on it, so that it keeps their logger, correlation ID and trace, but stops only
+// together with the proxy.
+type valueOnlyContext struct {
+	context.Context
+	values context.Context
+}
+
+// withoutCancel returns a context with the values of ctx, which is done when the shutdown context is done
+func withoutCancel(ctx, shutdown context.Context) context.Context {
+	return valueOnlyContext{Context: shutdown, values: ctx}
+}
+
+// Value returns the value of the context whose values are kept
+func (c valueOnlyContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
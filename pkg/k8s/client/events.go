@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	v1core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	typedv1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// BrokerCreatedReason is the reason of the Event written when the proxy has registered a broker
+	BrokerCreatedReason = "BrokerCreated"
+	// BrokerCreateFailedReason is the reason of the Event written when the proxy could not register a broker
+	BrokerCreateFailedReason = "BrokerCreateFailed"
+	// BrokerUpdatedReason is the reason of the Event written when the proxy has updated a broker
+	BrokerUpdatedReason = "BrokerUpdated"
+	// BrokerUpdateFailedReason is the reason of the Event written when the proxy could not update a broker
+	BrokerUpdateFailedReason = "BrokerUpdateFailed"
+	// PlanAccessUpdatedReason is the reason of the Event written when the proxy has updated the plans of a broker
+	// which are visible in Service Catalog
+	PlanAccessUpdatedReason = "PlanAccessUpdated"
+	// PlanAccessUpdateFailedReason is the reason of the Event written when the proxy could not update the plans of a
+	// broker which are visible in Service Catalog
+	PlanAccessUpdateFailedReason = "PlanAccessUpdateFailed"
+	// BrokerRelistRequestedReason is the reason of the Event written when the proxy has requested a relist of the
+	// catalog of a broker
+	BrokerRelistRequestedReason = "BrokerRelistRequested"
+	// BrokerRelistFailedReason is the reason of the Event written when the proxy could not request a relist of the
+	// catalog of a broker
+	BrokerRelistFailedReason = "BrokerRelistFailed"
+	// BrokerDeletedReason is the reason of the Event written when the proxy has deleted a broker
+	BrokerDeletedReason = "BrokerDeleted"
+	// BrokerDeleteFailedReason is the reason of the Event written when the proxy could not delete a broker
+	BrokerDeleteFailedReason = "BrokerDeleteFailed"
+
+	// CorrelationIDAnnotationKey is the annotation of the Events which holds the correlation ID of the Service
+	// Manager request which has caused the action of the proxy
+	CorrelationIDAnnotationKey = "sbproxy.peripli.io/correlation-id"
+	// CatalogPlanIDsAnnotationKey is the annotation of the Events which holds the catalog IDs of the plans whose
+	// access has been changed
+	CatalogPlanIDsAnnotationKey = "sbproxy.peripli.io/catalog-plan-ids"
+
+	eventComponent = "service-broker-proxy-k8s"
+)
+
+type planAccessChangesKey struct{}
+
+// newEventRecorder returns the recorder which writes the Events of the proxy with the Kubernetes client.
+// No Events are written without a client.
+func newEventRecorder(k8sClient kubernetes.Interface) record.EventRecorder {
+	if k8sClient == nil {
+		return nil
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedv1core.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(runtime.NewScheme(), v1core.EventSource{Component: eventComponent})
+}
+
+// withPlanAccessChanges returns a context whose broker updates are recorded as changes of the plan access
+func withPlanAccessChanges(ctx context.Context, changes []planAccessChange) context.Context {
+	return context.WithValue(ctx, planAccessChangesKey{}, changes)
+}
+
+// clusterBrokerReference returns the reference of the cluster service broker, whose Events are written to the
+// default namespace
+func clusterBrokerReference(name string, uid types.UID) *v1core.ObjectReference {
+	return &v1core.ObjectReference{
+		APIVersion: v1beta1.SchemeGroupVersion.String(),
+		Kind:       "ClusterServiceBroker",
+		Name:       name,
+		UID:        uid,
+	}
+}
+
+// namespaceBrokerReference returns the reference of the service broker in the namespace
+func namespaceBrokerReference(name, namespace string, uid types.UID) *v1core.ObjectReference {
+	return &v1core.ObjectReference{
+		APIVersion: v1beta1.SchemeGroupVersion.String(),
+		Kind:       "ServiceBroker",
+		Namespace:  namespace,
+		Name:       name,
+		UID:        uid,
+	}
+}
+
+// brokerEvent describes the Events of an action of the proxy on a broker
+type brokerEvent struct {
+	reason        string
+	failureReason string
+	action        string
+}
+
+var (
+	createBrokerEvent = brokerEvent{reason: BrokerCreatedReason, failureReason: BrokerCreateFailedReason, action: "Creating"}
+	updateBrokerEvent = brokerEvent{reason: BrokerUpdatedReason, failureReason: BrokerUpdateFailedReason, action: "Updating"}
+	relistBrokerEvent = brokerEvent{reason: BrokerRelistRequestedReason, failureReason: BrokerRelistFailedReason, action: "Requesting a relist of"}
+	deleteBrokerEvent = brokerEvent{reason: BrokerDeletedReason, failureReason: BrokerDeleteFailedReason, action: "Deleting"}
+)
+
+// brokerUpdateEvent returns the Events of a broker update, which changes the plan access if the context carries
+// plan access changes
+func brokerUpdateEvent(ctx context.Context) brokerEvent {
+	changes, found := ctx.Value(planAccessChangesKey{}).([]planAccessChange)
+	if !found {
+		return updateBrokerEvent
+	}
+
+	var enabled, disabled []string
+	for _, change := range changes {
+		if change.enabled {
+			enabled = append(enabled, change.catalogPlanID)
+		} else {
+			disabled = append(disabled, change.catalogPlanID)
+		}
+	}
+	var actions []string
+	if len(enabled) > 0 {
+		actions = append(actions, "enabling plans "+strings.Join(enabled, ", "))
+	}
+	if len(disabled) > 0 {
+		actions = append(actions, "disabling plans "+strings.Join(disabled, ", "))
+	}
+	action := strings.Join(actions, " and ") + " of"
+	return brokerEvent{
+		reason:        PlanAccessUpdatedReason,
+		failureReason: PlanAccessUpdateFailedReason,
+		action:        strings.ToUpper(action[:1]) + action[1:],
+	}
+}
+
+// recordBrokerEvent writes a Normal Event on the broker if the action has succeeded, and a Warning Event with the
+// error otherwise. The Events carry the Service Manager ID of the broker, taken from its labels or from the broker
+// logger of the context, and the correlation ID of the Service Manager request which has caused the action.
+func (sca *ServiceCatalogAPI) recordBrokerEvent(ctx context.Context, broker *v1core.ObjectReference, labels map[string]string, event brokerEvent, err error) {
+	if sca.recorder == nil {
+		return
+	}
+
+	kind := "cluster service broker"
+	if len(broker.Namespace) > 0 {
+		kind = "service broker"
+	}
+	annotations := serviceManagerContext(ctx, labels)
+	message := fmt.Sprintf("%s %s %s", event.action, kind, broker.Name)
+	if err != nil {
+		sca.recorder.AnnotatedEventf(broker, annotations, v1core.EventTypeWarning, event.failureReason,
+			"%s failed%s: %s", message, describeServiceManagerContext(annotations), err)
+		return
+	}
+	sca.recorder.AnnotatedEventf(broker, annotations, v1core.EventTypeNormal, event.reason,
+		"%s succeeded%s", message, describeServiceManagerContext(annotations))
+}
+
+// serviceManagerContext returns the annotations of the Events of a broker
+func serviceManagerContext(ctx context.Context, labels map[string]string) map[string]string {
+	annotations := make(map[string]string)
+	brokerID := labels[BrokerIDLabelKey]
+	if len(brokerID) == 0 {
+		brokerID, _ = log.C(ctx).Data[brokerIDLogField].(string)
+	}
+	if len(brokerID) > 0 {
+		annotations[BrokerIDLabelKey] = brokerID
+	}
+	if correlationID := log.CorrelationIDFromContext(ctx); len(correlationID) > 0 && correlationID != "-" {
+		annotations[CorrelationIDAnnotationKey] = correlationID
+	}
+	if changes, found := ctx.Value(planAccessChangesKey{}).([]planAccessChange); found {
+		planIDs := make([]string, 0, len(changes))
+		for _, change := range changes {
+			planIDs = append(planIDs, change.catalogPlanID)
+		}
+		annotations[CatalogPlanIDsAnnotationKey] = strings.Join(planIDs, ",")
+	}
+	return annotations
+}
+
+// describeServiceManagerContext returns the Service Manager context of the annotations for the message of an Event
+func describeServiceManagerContext(annotations map[string]string) string {
+	var parts []string
+	if brokerID, found := annotations[BrokerIDLabelKey]; found {
+		parts = append(parts, "Service Manager broker "+brokerID)
+	}
+	if correlationID, found := annotations[CorrelationIDAnnotationKey]; found {
+		parts = append(parts, "correlation ID "+correlationID)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(parts, ", "))
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Peripli/service-broker-proxy-k8s/pkg/k8s/config"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// A broker may already exist with the name of a broker which is registered, e.g. if it has been registered manually
+// before the proxy was installed. The existing broker policy decides whether such a broker is adopted, reported as a
+// conflict or replaced. Brokers which the proxy has created for the same Service Manager broker are always adopted,
+// including the brokers which a proxy without ownership labels has created.
+
+// defaultReplacedBrokerTimeout is the time to wait for a replaced broker to disappear if no broker ready timeout is configured
+const defaultReplacedBrokerTimeout = 2 * time.Minute
+
+// createClusterBroker creates the cluster-scoped broker and applies the existing broker policy if it already exists
+func (pc *PlatformClient) createClusterBroker(ctx context.Context, brokerID string, broker *v1beta1.ClusterServiceBroker) (*v1beta1.ClusterServiceBroker, error) {
+	createdBroker, err := pc.platformAPI.CreateClusterServiceBroker(ctx, broker)
+	if !errors.IsAlreadyExists(err) {
+		return createdBroker, err
+	}
+
+	existingBroker, err := pc.platformAPI.RetrieveClusterServiceBrokerByName(ctx, broker.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch pc.policyForExistingBroker(pc.ownedBrokerLabels(existingBroker.Labels, pc.legacyClusterBrokerID(existingBroker)), brokerID) {
+	case config.AdoptExistingBroker:
+		log.C(ctx).Infof("Adopting existing broker %s", broker.Name)
+		adoptedBroker := existingBroker.DeepCopy()
+		adoptedBroker.Labels = adoptedLabels(existingBroker.Labels, broker.Labels)
+		adoptedBroker.Spec.URL = broker.Spec.URL
+		adoptedBroker.Spec.AuthInfo = broker.Spec.AuthInfo
+		brokerRelistOf(broker.Spec.CommonServiceBrokerSpec).apply(&adoptedBroker.Spec.CommonServiceBrokerSpec)
+		brokerTLSOf(broker.Spec.CommonServiceBrokerSpec).apply(&adoptedBroker.Spec.CommonServiceBrokerSpec)
+		return pc.platformAPI.UpdateClusterServiceBroker(ctx, adoptedBroker)
+	case config.ReplaceExistingBroker:
+		log.C(ctx).Infof("Replacing existing broker %s", broker.Name)
+		if err := pc.platformAPI.DeleteClusterServiceBroker(ctx, broker.Name, &v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		err = pc.recreateBroker(ctx, broker.Name, func() error {
+			createdBroker, err = pc.platformAPI.CreateClusterServiceBroker(ctx, broker)
+			return err
+		})
+		return createdBroker, err
+	default:
+		secretRef := clusterBrokerSecretRef(broker.Spec.AuthInfo)
+		if !referencesClusterSecret(existingBroker, secretRef) {
+			pc.deleteConflictingBrokerSecret(ctx, secretRef.Namespace, secretRef.Name)
+		}
+		return nil, brokerConflictError(broker.Name, existingBroker.Labels)
+	}
+}
+
+// createNamespaceServiceBroker creates the namespace-scoped broker and applies the existing broker policy if it already exists
+func (pc *PlatformClient) createNamespaceServiceBroker(ctx context.Context, brokerID string, broker *v1beta1.ServiceBroker, namespace string) (*v1beta1.ServiceBroker, error) {
+	createdBroker, err := pc.platformAPI.CreateNamespaceServiceBroker(ctx, broker, namespace)
+	if !errors.IsAlreadyExists(err) {
+		return createdBroker, err
+	}
+
+	existingBroker, err := pc.platformAPI.RetrieveNamespaceServiceBrokerByName(ctx, broker.Name, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	switch pc.policyForExistingBroker(pc.ownedBrokerLabels(existingBroker.Labels, pc.legacyNamespaceBrokerID(existingBroker)), brokerID) {
+	case config.AdoptExistingBroker:
+		log.C(ctx).Infof("Adopting existing broker %s in namespace %s", broker.Name, namespace)
+		adoptedBroker := existingBroker.DeepCopy()
+		adoptedBroker.Labels = adoptedLabels(existingBroker.Labels, broker.Labels)
+		adoptedBroker.Spec.URL = broker.Spec.URL
+		adoptedBroker.Spec.AuthInfo = broker.Spec.AuthInfo
+		brokerRelistOf(broker.Spec.CommonServiceBrokerSpec).apply(&adoptedBroker.Spec.CommonServiceBrokerSpec)
+		brokerTLSOf(broker.Spec.CommonServiceBrokerSpec).apply(&adoptedBroker.Spec.CommonServiceBrokerSpec)
+		return pc.platformAPI.UpdateNamespaceServiceBroker(ctx, adoptedBroker, namespace)
+	case config.ReplaceExistingBroker:
+		log.C(ctx).Infof("Replacing existing broker %s in namespace %s", broker.Name, namespace)
+		if err := pc.platformAPI.DeleteNamespaceServiceBroker(ctx, broker.Name, namespace, &v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		err = pc.recreateBroker(ctx, broker.Name, func() error {
+			createdBroker, err = pc.platformAPI.CreateNamespaceServiceBroker(ctx, broker, namespace)
+			return err
+		})
+		return createdBroker, err
+	default:
+		secretRef := namespaceBrokerSecretRef(broker.Spec.AuthInfo)
+		if !referencesNamespaceSecret(existingBroker, secretRef) {
+			pc.deleteConflictingBrokerSecret(ctx, namespace, secretRef.Name)
+		}
+		return nil, brokerConflictError(broker.Name, existingBroker.Labels)
+	}
+}
+
+// recreateBroker creates a replaced broker again. The deleted broker still exists until service-catalog has removed
+// its finalizers, which takes as long as the deprovisioning of its instances, so the creation is retried until the
+// broker ready timeout expires.
+func (pc *PlatformClient) recreateBroker(ctx context.Context, name string, create func() error) error {
+	timeout := pc.brokerReadyTimeout
+	if timeout <= 0 {
+		timeout = defaultReplacedBrokerTimeout
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lastErr error
+	err := wait.PollImmediateUntil(pc.brokerStatusPollInterval, func() (bool, error) {
+		lastErr = create()
+		if errors.IsAlreadyExists(lastErr) {
+			return false, nil
+		}
+		return true, lastErr
+	}, waitCtx.Done())
+	if err == wait.ErrWaitTimeout && ctx.Err() == nil {
+		return fmt.Errorf("replaced broker %s still exists after %s: %s", name, timeout, lastErr)
+	}
+	return err
+}
+
+// policyForExistingBroker returns the policy for an existing broker with the labels
+func (pc *PlatformClient) policyForExistingBroker(labels map[string]string, brokerID string) string {
+	if pc.isOwned(labels) && labels[BrokerIDLabelKey] == brokerID {
+		return config.AdoptExistingBroker
+	}
+	return pc.existingBrokerPolicy
+}
+
+// deleteConflictingBrokerSecret deletes the credentials secret which has been written for a broker which could not be created
+func (pc *PlatformClient) deleteConflictingBrokerSecret(ctx context.Context, namespace, name string) {
+	if err := pc.platformAPI.DeleteSecret(ctx, namespace, name); err != nil && !errors.IsNotFound(err) {
+		log.C(ctx).WithError(err).Errorf("Could not delete broker credentials secret %s in namespace %s", name, namespace)
+	}
+}
+
+// adoptedLabels returns the labels of an existing broker with the ownership labels of the proxy
+func adoptedLabels(existingLabels, ownerLabels map[string]string) map[string]string {
+	labels := make(map[string]string, len(existingLabels)+len(ownerLabels))
+	for key, value := range existingLabels {
+		labels[key] = value
+	}
+	for key, value := range ownerLabels {
+		labels[key] = value
+	}
+	return labels
+}
+
+// brokerConflictError describes the owner of an existing broker
+func brokerConflictError(name string, labels map[string]string) error {
+	switch {
+	case labels[ManagedByLabelKey] != ManagedByLabelValue:
+		return fmt.Errorf("broker %s already exists and is not managed by a service broker proxy", name)
+	case labels[BrokerIDLabelKey] != "":
+		return fmt.Errorf("broker %s already exists and is managed by service broker proxy instance %s for Service Manager broker %s",
+			name, labels[ProxyInstanceLabelKey], labels[BrokerIDLabelKey])
+	default:
+		return fmt.Errorf("broker %s already exists and is managed by service broker proxy instance %s", name, labels[ProxyInstanceLabelKey])
+	}
+}
+
+func referencesClusterSecret(broker *v1beta1.ClusterServiceBroker, secretRef *v1beta1.ObjectReference) bool {
+	existingSecretRef := clusterBrokerSecretRef(broker.Spec.AuthInfo)
+	return existingSecretRef != nil && *existingSecretRef == *secretRef
+}
+
+func referencesNamespaceSecret(broker *v1beta1.ServiceBroker, secretRef *v1beta1.LocalObjectReference) bool {
+	existingSecretRef := namespaceBrokerSecretRef(broker.Spec.AuthInfo)
+	return existingSecretRef != nil && *existingSecretRef == *secretRef
+}
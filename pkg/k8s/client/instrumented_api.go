@@ -0,0 +1,257 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/Peripli/service-broker-proxy-k8s/pkg/k8s/api"
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1core "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// instrumentedKubernetesAPI records the metrics and the span of each call to the Kubernetes API
+type instrumentedKubernetesAPI struct {
+	api     api.KubernetesAPI
+	metrics *metrics
+}
+
+var _ api.KubernetesAPI = &instrumentedKubernetesAPI{}
+
+func newInstrumentedKubernetesAPI(kubernetesAPI api.KubernetesAPI, metrics *metrics) *instrumentedKubernetesAPI {
+	return &instrumentedKubernetesAPI{
+		api:     kubernetesAPI,
+		metrics: metrics,
+	}
+}
+
+// instrument starts the span of a call to the Kubernetes API and returns the function which ends the span and
+// records the metrics of the call
+func (i *instrumentedKubernetesAPI) instrument(ctx context.Context, operation, scope string) (context.Context, func(err *error)) {
+	start := time.Now()
+	ctx, span := startSpan(ctx, "KubernetesAPI."+operation, scopeAttributeKey.String(scope))
+	return ctx, func(err *error) {
+		i.metrics.observe(operation, scope, start, err)
+		endSpan(span, err)
+	}
+}
+
+// CreateClusterServiceBroker creates a cluster service broker
+func (i *instrumentedKubernetesAPI) CreateClusterServiceBroker(ctx context.Context, broker *v1beta1.ClusterServiceBroker) (_ *v1beta1.ClusterServiceBroker, err error) {
+	ctx, done := i.instrument(ctx, "CreateClusterServiceBroker", clusterScopeLabel)
+	defer done(&err)
+	return i.api.CreateClusterServiceBroker(ctx, broker)
+}
+
+// DeleteClusterServiceBroker deletes a cluster service broker
+func (i *instrumentedKubernetesAPI) DeleteClusterServiceBroker(ctx context.Context, name string, options *v1.DeleteOptions) (err error) {
+	ctx, done := i.instrument(ctx, "DeleteClusterServiceBroker", clusterScopeLabel)
+	defer done(&err)
+	return i.api.DeleteClusterServiceBroker(ctx, name, options)
+}
+
+// RetrieveClusterServiceBrokers returns all cluster service brokers
+func (i *instrumentedKubernetesAPI) RetrieveClusterServiceBrokers(ctx context.Context) (_ *v1beta1.ClusterServiceBrokerList, err error) {
+	ctx, done := i.instrument(ctx, "RetrieveClusterServiceBrokers", clusterScopeLabel)
+	defer done(&err)
+	return i.api.RetrieveClusterServiceBrokers(ctx)
+}
+
+// RetrieveClusterServiceBrokerByName returns a cluster service broker by name
+func (i *instrumentedKubernetesAPI) RetrieveClusterServiceBrokerByName(ctx context.Context, name string) (_ *v1beta1.ClusterServiceBroker, err error) {
+	ctx, done := i.instrument(ctx, "RetrieveClusterServiceBrokerByName", clusterScopeLabel)
+	defer done(&err)
+	return i.api.RetrieveClusterServiceBrokerByName(ctx, name)
+}
+
+// UpdateClusterServiceBroker updates a cluster service broker
+func (i *instrumentedKubernetesAPI) UpdateClusterServiceBroker(ctx context.Context, broker *v1beta1.ClusterServiceBroker) (_ *v1beta1.ClusterServiceBroker, err error) {
+	ctx, done := i.instrument(ctx, "UpdateClusterServiceBroker", clusterScopeLabel)
+	defer done(&err)
+	return i.api.UpdateClusterServiceBroker(ctx, broker)
+}
+
+// SyncClusterServiceBroker synchronizes a cluster service broker and counts it as failed if it returns an error
+func (i *instrumentedKubernetesAPI) SyncClusterServiceBroker(ctx context.Context, name string, retries int) (err error) {
+	ctx, done := i.instrument(ctx, "SyncClusterServiceBroker", clusterScopeLabel)
+	defer done(&err)
+	defer i.metrics.observeSync(clusterScopeLabel, &err)
+	return i.api.SyncClusterServiceBroker(ctx, name, retries)
+}
+
+// RetrieveClusterServicePlans returns the cluster service plans of a cluster service broker
+func (i *instrumentedKubernetesAPI) RetrieveClusterServicePlans(ctx context.Context, brokerName string) (_ *v1beta1.ClusterServicePlanList, err error) {
+	ctx, done := i.instrument(ctx, "RetrieveClusterServicePlans", clusterScopeLabel)
+	defer done(&err)
+	return i.api.RetrieveClusterServicePlans(ctx, brokerName)
+}
+
+// CreateNamespaceServiceBroker creates a service broker in a namespace
+func (i *instrumentedKubernetesAPI) CreateNamespaceServiceBroker(ctx context.Context, broker *v1beta1.ServiceBroker, namespace string) (_ *v1beta1.ServiceBroker, err error) {
+	ctx, done := i.instrument(ctx, "CreateNamespaceServiceBroker", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.CreateNamespaceServiceBroker(ctx, broker, namespace)
+}
+
+// DeleteNamespaceServiceBroker deletes a service broker in a namespace
+func (i *instrumentedKubernetesAPI) DeleteNamespaceServiceBroker(ctx context.Context, name string, namespace string, options *v1.DeleteOptions) (err error) {
+	ctx, done := i.instrument(ctx, "DeleteNamespaceServiceBroker", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.DeleteNamespaceServiceBroker(ctx, name, namespace, options)
+}
+
+// RetrieveNamespaceServiceBrokers returns all service brokers in a namespace
+func (i *instrumentedKubernetesAPI) RetrieveNamespaceServiceBrokers(ctx context.Context, namespace string) (_ *v1beta1.ServiceBrokerList, err error) {
+	ctx, done := i.instrument(ctx, "RetrieveNamespaceServiceBrokers", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.RetrieveNamespaceServiceBrokers(ctx, namespace)
+}
+
+// RetrieveNamespaceServiceBrokerByName returns a service broker in a namespace by name
+func (i *instrumentedKubernetesAPI) RetrieveNamespaceServiceBrokerByName(ctx context.Context, name, namespace string) (_ *v1beta1.ServiceBroker, err error) {
+	ctx, done := i.instrument(ctx, "RetrieveNamespaceServiceBrokerByName", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.RetrieveNamespaceServiceBrokerByName(ctx, name, namespace)
+}
+
+// UpdateNamespaceServiceBroker updates a service broker in a namespace
+func (i *instrumentedKubernetesAPI) UpdateNamespaceServiceBroker(ctx context.Context, broker *v1beta1.ServiceBroker, namespace string) (_ *v1beta1.ServiceBroker, err error) {
+	ctx, done := i.instrument(ctx, "UpdateNamespaceServiceBroker", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.UpdateNamespaceServiceBroker(ctx, broker, namespace)
+}
+
+// SyncNamespaceServiceBroker synchronizes a service broker in a namespace and counts it as failed if it returns an error
+func (i *instrumentedKubernetesAPI) SyncNamespaceServiceBroker(ctx context.Context, name, namespace string, retries int) (err error) {
+	ctx, done := i.instrument(ctx, "SyncNamespaceServiceBroker", namespaceScopeLabel)
+	defer done(&err)
+	defer i.metrics.observeSync(namespaceScopeLabel, &err)
+	return i.api.SyncNamespaceServiceBroker(ctx, name, namespace, retries)
+}
+
+// RetrieveNamespaceServicePlans returns the service plans of a service broker in a namespace
+func (i *instrumentedKubernetesAPI) RetrieveNamespaceServicePlans(ctx context.Context, brokerName, namespace string) (_ *v1beta1.ServicePlanList, err error) {
+	ctx, done := i.instrument(ctx, "RetrieveNamespaceServicePlans", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.RetrieveNamespaceServicePlans(ctx, brokerName, namespace)
+}
+
+// UpdateServiceBrokerCredentials updates the credentials secret of a broker
+func (i *instrumentedKubernetesAPI) UpdateServiceBrokerCredentials(ctx context.Context, secret *v1core.Secret) (_ *v1core.Secret, err error) {
+	ctx, done := i.instrument(ctx, "UpdateServiceBrokerCredentials", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.UpdateServiceBrokerCredentials(ctx, secret)
+}
+
+// CreateSecret creates the credentials secret of a broker
+func (i *instrumentedKubernetesAPI) CreateSecret(ctx context.Context, secret *v1core.Secret) (_ *v1core.Secret, err error) {
+	ctx, done := i.instrument(ctx, "CreateSecret", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.CreateSecret(ctx, secret)
+}
+
+// RetrieveSecret returns the credentials secret of a broker
+func (i *instrumentedKubernetesAPI) RetrieveSecret(ctx context.Context, namespace, name string) (_ *v1core.Secret, err error) {
+	ctx, done := i.instrument(ctx, "RetrieveSecret", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.RetrieveSecret(ctx, namespace, name)
+}
+
+// DeleteSecret deletes the credentials secret of a broker
+func (i *instrumentedKubernetesAPI) DeleteSecret(ctx context.Context, namespace, name string) (err error) {
+	ctx, done := i.instrument(ctx, "DeleteSecret", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.DeleteSecret(ctx, namespace, name)
+}
+
+// StartBrokerCache starts the broker cache
+func (i *instrumentedKubernetesAPI) StartBrokerCache(ctx context.Context) (err error) {
+	ctx, done := i.instrument(ctx, "StartBrokerCache", noScopeLabel)
+	defer done(&err)
+	return i.api.StartBrokerCache(ctx)
+}
+
+// BrokerCacheSynced reports whether the broker cache has been synced. It is not recorded, as it only reads the
+// state of the cache and is called by each health check.
+func (i *instrumentedKubernetesAPI) BrokerCacheSynced() bool {
+	return i.api.BrokerCacheSynced()
+}
+
+// RetrieveCachedClusterServiceBrokers returns all cluster service brokers from the broker cache
+func (i *instrumentedKubernetesAPI) RetrieveCachedClusterServiceBrokers(ctx context.Context) (_ *v1beta1.ClusterServiceBrokerList, err error) {
+	ctx, done := i.instrument(ctx, "RetrieveCachedClusterServiceBrokers", clusterScopeLabel)
+	defer done(&err)
+	return i.api.RetrieveCachedClusterServiceBrokers(ctx)
+}
+
+// RetrieveCachedClusterServiceBrokerByName returns a cluster service broker by name from the broker cache
+func (i *instrumentedKubernetesAPI) RetrieveCachedClusterServiceBrokerByName(ctx context.Context, name string) (_ *v1beta1.ClusterServiceBroker, err error) {
+	ctx, done := i.instrument(ctx, "RetrieveCachedClusterServiceBrokerByName", clusterScopeLabel)
+	defer done(&err)
+	return i.api.RetrieveCachedClusterServiceBrokerByName(ctx, name)
+}
+
+// RetrieveCachedNamespaceServiceBrokers returns all service brokers in a namespace from the broker cache
+func (i *instrumentedKubernetesAPI) RetrieveCachedNamespaceServiceBrokers(ctx context.Context, namespace string) (_ *v1beta1.ServiceBrokerList, err error) {
+	ctx, done := i.instrument(ctx, "RetrieveCachedNamespaceServiceBrokers", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.RetrieveCachedNamespaceServiceBrokers(ctx, namespace)
+}
+
+// RetrieveCachedNamespaceServiceBrokerByName returns a service broker in a namespace by name from the broker cache
+func (i *instrumentedKubernetesAPI) RetrieveCachedNamespaceServiceBrokerByName(ctx context.Context, name, namespace string) (_ *v1beta1.ServiceBroker, err error) {
+	ctx, done := i.instrument(ctx, "RetrieveCachedNamespaceServiceBrokerByName", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.RetrieveCachedNamespaceServiceBrokerByName(ctx, name, namespace)
+}
+
+// WatchNamespaces watches the namespaces which match the label selector
+func (i *instrumentedKubernetesAPI) WatchNamespaces(ctx context.Context, labelSelector string, handler cache.ResourceEventHandler) (err error) {
+	ctx, done := i.instrument(ctx, "WatchNamespaces", clusterScopeLabel)
+	defer done(&err)
+	return i.api.WatchNamespaces(ctx, labelSelector, handler)
+}
+
+// StartBrokerSyncs starts the broker sync workers
+func (i *instrumentedKubernetesAPI) StartBrokerSyncs(ctx context.Context, workers int, rateLimiter workqueue.RateLimiter) (err error) {
+	ctx, done := i.instrument(ctx, "StartBrokerSyncs", noScopeLabel)
+	defer done(&err)
+	return i.api.StartBrokerSyncs(ctx, workers, rateLimiter)
+}
+
+// NewLeaseLock returns the lock of the Lease. It is not recorded, as it makes no call to the Kubernetes API.
+func (i *instrumentedKubernetesAPI) NewLeaseLock(namespace, name, identity string) resourcelock.Interface {
+	return i.api.NewLeaseLock(namespace, name, identity)
+}
+
+// CreateLease creates a Lease
+func (i *instrumentedKubernetesAPI) CreateLease(ctx context.Context, lease *coordinationv1.Lease) (_ *coordinationv1.Lease, err error) {
+	ctx, done := i.instrument(ctx, "CreateLease", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.CreateLease(ctx, lease)
+}
+
+// UpdateLease updates a Lease
+func (i *instrumentedKubernetesAPI) UpdateLease(ctx context.Context, lease *coordinationv1.Lease) (_ *coordinationv1.Lease, err error) {
+	ctx, done := i.instrument(ctx, "UpdateLease", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.UpdateLease(ctx, lease)
+}
+
+// RetrieveLeases returns the Leases in a namespace which match the label selector
+func (i *instrumentedKubernetesAPI) RetrieveLeases(ctx context.Context, namespace, labelSelector string) (_ *coordinationv1.LeaseList, err error) {
+	ctx, done := i.instrument(ctx, "RetrieveLeases", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.RetrieveLeases(ctx, namespace, labelSelector)
+}
+
+// DeleteLease deletes a Lease
+func (i *instrumentedKubernetesAPI) DeleteLease(ctx context.Context, namespace, name string) (err error) {
+	ctx, done := i.instrument(ctx, "DeleteLease", namespaceScopeLabel)
+	defer done(&err)
+	return i.api.DeleteLease(ctx, namespace, name)
+}
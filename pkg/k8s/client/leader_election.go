@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// With leader election, all replicas of the proxy serve the OSB API and receive the notifications of Service Manager,
+// but only the replica holding the Lease in the secret namespace writes brokers, secrets and plan access changes.
+// The other replicas skip these operations. A replica which has skipped operations as a follower resyncs all brokers
+// when it becomes the leader, as the changes of the skipped operations may have been missed by the previous leader.
+// Likewise, it registers or removes the brokers of the namespaces which have started or stopped matching the namespace
+// selector while it was a follower.
+
+// leadership tracks whether the replica is the leader and whether it has skipped operations as a follower
+type leadership struct {
+	lock       sync.Mutex
+	leading    bool
+	skipped    bool
+	namespaces sets.String
+}
+
+func newLeadership(leading bool) *leadership {
+	return &leadership{leading: leading, namespaces: sets.NewString()}
+}
+
+// skip reports whether an operation must be skipped, as the replica is not the leader
+func (l *leadership) skip() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !l.leading {
+		l.skipped = true
+	}
+	return !l.leading
+}
+
+// skipNamespace reports whether the changes of brokers in a namespace which has started or stopped matching the
+// namespace selector must be skipped, as the replica is not the leader. The skipped namespace is remembered.
+func (l *leadership) skipNamespace(namespace string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !l.leading {
+		l.skipped = true
+		l.namespaces.Insert(namespace)
+	}
+	return !l.leading
+}
+
+// start makes the replica the leader and reports whether it has skipped operations as a follower,
+// along with the namespaces it has skipped
+func (l *leadership) start() (bool, []string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	skipped := l.skipped
+	namespaces := l.namespaces.List()
+	l.leading = true
+	l.skipped = false
+	l.namespaces = sets.NewString()
+	return skipped, namespaces
+}
+
+// stop makes the replica a follower
+func (l *leadership) stop() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.leading = false
+}
+
+// NewLeaseLock returns the lock of the Lease in the namespace, which is held by the identity when it is the leader
+func (sca *ServiceCatalogAPI) NewLeaseLock(namespace, name, identity string) resourcelock.Interface {
+	return &resourcelock.LeaseLock{
+		LeaseMeta:  v1.ObjectMeta{Namespace: namespace, Name: name},
+		Client:     sca.K8sClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+}
+
+// skippedAsFollower reports whether the operation is skipped, as the replica is not the leader
+func (pc *PlatformClient) skippedAsFollower(ctx context.Context, operation string) bool {
+	if !pc.leadership.skip() {
+		return false
+	}
+	log.C(ctx).Debugf("Skipping %s, as this replica is not the leader", operation)
+	return true
+}
+
+// StartLeaderElection competes for the Lease of the proxy until the context is done, if leader election is enabled.
+// The resync function is called when the replica becomes the leader after it has skipped operations as a follower.
+func (pc *PlatformClient) StartLeaderElection(ctx context.Context, resync func(context.Context)) error {
+	if !pc.leaderElection {
+		return nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("unable to determine the leader election identity (%s)", err)
+	}
+	identity := fmt.Sprintf("%s_%s", hostname, uuid.NewUUID())
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            pc.platformAPI.NewLeaseLock(pc.secretNamespace, pc.leaseName, identity),
+		LeaseDuration:   pc.leaseDuration,
+		RenewDeadline:   pc.leaseRenewDeadline,
+		RetryPeriod:     pc.leaseRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            pc.leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				log.C(ctx).Infof("Replica %s has become the leader", identity)
+				skipped, namespaces := pc.leadership.start()
+				pc.reconcileNamespaces(leaderCtx, namespaces)
+				if skipped {
+					resync(leaderCtx)
+				}
+			},
+			OnStoppedLeading: func() {
+				pc.leadership.stop()
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					log.C(ctx).Infof("Replica %s is the leader", leader)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create leader elector (%s)", err)
+	}
+
+	go func() {
+		// a replica which loses the Lease keeps serving as a follower and competes for it again
+		for ctx.Err() == nil {
+			elector.Run(ctx)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/Peripli/service-broker-proxy-k8s/pkg/k8s/api/apifakes"
+	"github.com/Peripli/service-broker-proxy/pkg/platform"
+	servicecatalog "github.com/kubernetes-sigs/service-catalog/pkg/svcat/service-catalog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Leader election", func() {
+	var (
+		ctx            context.Context
+		cancel         context.CancelFunc
+		k8sFake        *k8sfake.Clientset
+		platformClient *PlatformClient
+		resyncs        int32
+	)
+
+	resync := func(context.Context) {
+		atomic.AddInt32(&resyncs, 1)
+	}
+
+	leading := func() bool {
+		platformClient.leadership.lock.Lock()
+		defer platformClient.leadership.lock.Unlock()
+		return platformClient.leadership.leading
+	}
+
+	holderIdentity := func() string {
+		lease, err := k8sFake.CoordinationV1().Leases("sbproxy").Get(ctx, "sbproxy-lease", v1.GetOptions{})
+		if err != nil || lease.Spec.HolderIdentity == nil {
+			return ""
+		}
+		return *lease.Spec.HolderIdentity
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		k8sFake = k8sfake.NewSimpleClientset()
+		resyncs = 0
+		platformClient = &PlatformClient{
+			platformAPI:        NewDefaultKubernetesAPI(&servicecatalog.SDK{K8sClient: k8sFake}),
+			secretNamespace:    "sbproxy",
+			leaderElection:     true,
+			leaseName:          "sbproxy-lease",
+			leaseDuration:      time.Second,
+			leaseRenewDeadline: 500 * time.Millisecond,
+			leaseRetryPeriod:   100 * time.Millisecond,
+			leadership:         newLeadership(false),
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("skips the writes of a follower", func() {
+		fakeAPI := &apifakes.FakeKubernetesAPI{}
+		platformClient.platformAPI = fakeAPI
+
+		broker, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: "id", Name: "broker", BrokerURL: "url"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(broker.Name).To(Equal("broker"))
+		_, err = platformClient.UpdateBroker(ctx, &platform.UpdateServiceBrokerRequest{ID: "id", Name: "broker"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(platformClient.Fetch(ctx, &platform.UpdateServiceBrokerRequest{ID: "id", Name: "broker"})).To(Succeed())
+		Expect(platformClient.DeleteBroker(ctx, &platform.DeleteServiceBrokerRequest{ID: "id", Name: "broker"})).To(Succeed())
+		Expect(platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{BrokerName: "broker", CatalogPlanID: "plan"})).To(Succeed())
+		Expect(platformClient.DisableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{BrokerName: "broker", CatalogPlanID: "plan"})).To(Succeed())
+
+		Expect(fakeAPI.Invocations()).To(BeEmpty())
+	})
+
+	It("acquires the Lease in the secret namespace and resyncs if it has skipped operations as a follower", func() {
+		Expect(platformClient.skippedAsFollower(ctx, "deleting broker")).To(BeTrue())
+
+		Expect(platformClient.StartLeaderElection(ctx, resync)).To(Succeed())
+
+		hostname, err := os.Hostname()
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(holderIdentity).Should(HavePrefix(hostname + "_"))
+		Eventually(func() int32 { return atomic.LoadInt32(&resyncs) }).Should(Equal(int32(1)))
+		Expect(platformClient.skippedAsFollower(ctx, "deleting broker")).To(BeFalse())
+	})
+
+	It("does not resync if it has not skipped operations", func() {
+		Expect(platformClient.StartLeaderElection(ctx, resync)).To(Succeed())
+
+		Eventually(leading).Should(BeTrue())
+		Consistently(func() int32 { return atomic.LoadInt32(&resyncs) }, 300*time.Millisecond).Should(BeZero())
+	})
+
+	It("remains a follower while another replica holds the Lease", func() {
+		other := &PlatformClient{
+			platformAPI:        NewDefaultKubernetesAPI(&servicecatalog.SDK{K8sClient: k8sFake}),
+			secretNamespace:    "sbproxy",
+			leaderElection:     true,
+			leaseName:          "sbproxy-lease",
+			leaseDuration:      time.Minute,
+			leaseRenewDeadline: 500 * time.Millisecond,
+			leaseRetryPeriod:   100 * time.Millisecond,
+			leadership:         newLeadership(false),
+		}
+		Expect(other.StartLeaderElection(ctx, resync)).To(Succeed())
+		Eventually(holderIdentity).ShouldNot(BeEmpty())
+		leader := holderIdentity()
+
+		Expect(platformClient.StartLeaderElection(ctx, resync)).To(Succeed())
+
+		Consistently(func() bool { return platformClient.skippedAsFollower(ctx, "deleting broker") }, 300*time.Millisecond).Should(BeTrue())
+		Expect(holderIdentity()).To(Equal(leader))
+	})
+
+	It("does not compete for the Lease if leader election is disabled", func() {
+		platformClient.leaderElection = false
+
+		Expect(platformClient.StartLeaderElection(ctx, resync)).To(Succeed())
+
+		Consistently(holderIdentity, 300*time.Millisecond).Should(BeEmpty())
+	})
+})
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	brokerLogField    = "broker"
+	brokerIDLogField  = "broker_id"
+	secretLogField    = "secret"
+	scopeLogField     = "scope"
+	namespaceLogField = "namespace"
+	durationLogField  = "duration"
+	resultLogField    = "result"
+)
+
+// withBrokerLogger returns a context whose logger adds the name and the Service Manager ID of the broker to each entry,
+// so that all entries written for a change in Service Manager can be related to it by the broker and the correlation ID
+func withBrokerLogger(ctx context.Context, name, brokerID string) context.Context {
+	fields := logrus.Fields{brokerLogField: name}
+	if len(brokerID) > 0 {
+		fields[brokerIDLogField] = brokerID
+	}
+	return log.ContextWithLogger(ctx, log.C(ctx).WithFields(fields))
+}
+
+// clusterBrokerLogFields returns the log fields of a cluster service broker
+func clusterBrokerLogFields(name string) logrus.Fields {
+	return logrus.Fields{brokerLogField: name, scopeLogField: clusterScopeLabel}
+}
+
+// namespaceBrokerLogFields returns the log fields of a service broker in the namespace
+func namespaceBrokerLogFields(name, namespace string) logrus.Fields {
+	return logrus.Fields{brokerLogField: name, scopeLogField: namespaceScopeLabel, namespaceLogField: namespace}
+}
+
+// secretLogFields returns the log fields of a broker credentials secret, which is named after the Service Manager broker ID
+func secretLogFields(namespace, name string) logrus.Fields {
+	return logrus.Fields{secretLogField: name, scopeLogField: namespaceScopeLabel, namespaceLogField: namespace}
+}
+
+// setScopeLogFields sets the scope and the namespaces in which a broker is registered
+func setScopeLogFields(fields logrus.Fields, cluster bool, namespaces []string) {
+	if cluster {
+		fields[scopeLogField] = clusterScopeLabel
+		return
+	}
+	fields[scopeLogField] = namespaceScopeLabel
+	fields[namespaceLogField] = strings.Join(namespaces, ",")
+}
+
+// logOperation writes the duration and the result of an operation of the platform client which has started at the
+// given time. Failures are logged as errors, as they are returned to sbproxy.
+func logOperation(ctx context.Context, operation string, fields logrus.Fields, start time.Time, err *error) {
+	logResult(ctx, operation, fields, start, *err, logrus.ErrorLevel)
+}
+
+// logCall writes the duration and the result of a write to the Kubernetes API which has started at the given time.
+// Failures are logged as warnings, as the caller may tolerate them, e.g. deleting a secret which does not exist.
+func logCall(ctx context.Context, call string, fields logrus.Fields, start time.Time, err *error) {
+	logResult(ctx, call, fields, start, *err, logrus.WarnLevel)
+}
+
+func logResult(ctx context.Context, message string, fields logrus.Fields, start time.Time, err error, failureLevel logrus.Level) {
+	entry := log.C(ctx).WithFields(fields).WithField(durationLogField, time.Since(start).String())
+	if err != nil {
+		entry.WithField(resultLogField, errorResultLabel).WithError(err).Logf(failureLevel, "%s failed", message)
+		return
+	}
+	entry.WithField(resultLogField, successResultLabel).Infof("%s succeeded", message)
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// MetricsPath is the path on which the sbproxy server exports the metrics of the platform client
+const MetricsPath = "/metrics"
+
+const (
+	metricsNamespace = "sbproxy_k8s"
+
+	clusterScopeLabel   = "cluster"
+	namespaceScopeLabel = "namespace"
+	noScopeLabel        = "none"
+
+	successResultLabel = "success"
+	errorResultLabel   = "error"
+)
+
+// metrics holds the metrics of the calls to the Kubernetes API, of the broker syncs and of the managed brokers
+type metrics struct {
+	registry          *prometheus.Registry
+	operations        *prometheus.CounterVec
+	operationDuration *prometheus.HistogramVec
+	syncRetries       prometheus.Counter
+	syncFailures      *prometheus.CounterVec
+	managedBrokers    *prometheus.GaugeVec
+	lastBrokerList    prometheus.Gauge
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "api_operations_total",
+			Help:      "Number of calls to the Kubernetes API by operation, scope and result.",
+		}, []string{"operation", "scope", "result"}),
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "api_operation_duration_seconds",
+			Help:      "Duration of the calls to the Kubernetes API by operation, scope and result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "scope", "result"}),
+		syncRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "broker_sync_retries_total",
+			Help:      "Number of retried relists of service brokers.",
+		}),
+		syncFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "broker_sync_failures_total",
+			Help:      "Number of syncs of service brokers which failed after all retries, by scope.",
+		}, []string{"scope"}),
+		managedBrokers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "managed_brokers",
+			Help:      "Number of service brokers managed by the proxy by the status of their Ready condition, as of the last broker list.",
+		}, []string{"ready"}),
+		lastBrokerList: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "last_broker_list_timestamp_seconds",
+			Help:      "Time of the last successful list of the managed service brokers, which starts each reconciliation.",
+		}),
+	}
+	m.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		m.operations,
+		m.operationDuration,
+		m.syncRetries,
+		m.syncFailures,
+		m.managedBrokers,
+		m.lastBrokerList,
+	)
+	return m
+}
+
+// observe records a call to the Kubernetes API which has started at the given time and failed with the given error, if any
+func (m *metrics) observe(operation, scope string, start time.Time, err *error) {
+	result := successResultLabel
+	if *err != nil {
+		result = errorResultLabel
+	}
+	m.operations.WithLabelValues(operation, scope, result).Inc()
+	m.operationDuration.WithLabelValues(operation, scope, result).Observe(time.Since(start).Seconds())
+}
+
+// observeSync counts the broker sync as failed if it returned an error
+func (m *metrics) observeSync(scope string, err *error) {
+	if *err != nil {
+		m.syncFailures.WithLabelValues(scope).Inc()
+	}
+}
+
+// setManagedBrokers counts the managed brokers by the status of their Ready condition
+func (m *metrics) setManagedBrokers(readiness map[string]v1beta1.ConditionStatus) {
+	counts := map[v1beta1.ConditionStatus]int{
+		v1beta1.ConditionTrue:    0,
+		v1beta1.ConditionFalse:   0,
+		v1beta1.ConditionUnknown: 0,
+	}
+	for _, status := range readiness {
+		counts[status]++
+	}
+	for status, count := range counts {
+		m.managedBrokers.WithLabelValues(string(status)).Set(float64(count))
+	}
+	m.lastBrokerList.SetToCurrentTime()
+}
+
+// countRetries returns a rate limiter which counts the retries of the broker syncs, the only items which are added
+// rate limited to the sync queue. The first attempts of the syncs are not counted.
+func (m *metrics) countRetries(rateLimiter firstAttemptRateLimiter) firstAttemptRateLimiter {
+	return &retryCountingRateLimiter{firstAttemptRateLimiter: rateLimiter, retries: m.syncRetries}
+}
+
+type retryCountingRateLimiter struct {
+	firstAttemptRateLimiter
+	retries prometheus.Counter
+}
+
+// When counts the retry and returns the delay of the wrapped rate limiter
+func (r *retryCountingRateLimiter) When(item interface{}) time.Duration {
+	r.retries.Inc()
+	return r.firstAttemptRateLimiter.When(item)
+}
+
+// MetricsController returns the controller which exports the metrics of the platform client on the sbproxy server
+func (pc *PlatformClient) MetricsController() web.Controller {
+	return &metricsController{gatherer: pc.metrics.registry}
+}
+
+type metricsController struct {
+	gatherer prometheus.Gatherer
+}
+
+// Routes returns the route of the metrics endpoint
+func (c *metricsController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   MetricsPath,
+			},
+			Handler: c.metrics,
+		},
+	}
+}
+
+// metrics handler for GET /metrics, which encodes the metrics in the format accepted by the scraper
+func (c *metricsController) metrics(r *web.Request) (*web.Response, error) {
+	families, err := c.gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	format := expfmt.Negotiate(r.Header)
+	var body bytes.Buffer
+	encoder := expfmt.NewEncoder(&body, format)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return nil, err
+		}
+	}
+
+	return &web.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{string(format)}},
+		Body:       body.Bytes(),
+	}, nil
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	v1core "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Besides the configured target namespaces, brokers are registered in all namespaces which match the namespace selector.
+// When a namespace starts matching, the brokers of another target namespace are copied into it, otherwise they are
+// registered with the next resync. When a namespace stops matching, the brokers and their secrets are removed from it.
+// With leader election, only the leader copies and removes brokers. The namespaces which have started or stopped matching
+// while a replica was a follower are reconciled when it becomes the leader. With sharding, each replica copies and removes
+// only the brokers it owns.
+
+// WatchNamespaces watches the namespaces matching the namespace selector until the context is done.
+// It returns once the currently matching namespaces are known.
+func (pc *PlatformClient) WatchNamespaces(ctx context.Context) error {
+	if len(pc.namespaceSelector) == 0 {
+		return nil
+	}
+
+	return pc.platformAPI.WatchNamespaces(ctx, pc.namespaceSelector, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if namespace, ok := obj.(*v1core.Namespace); ok {
+				pc.namespaceSelected(ctx, namespace.Name)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if namespace, ok := obj.(*v1core.Namespace); ok {
+				pc.namespaceUnselected(ctx, namespace.Name)
+			}
+		},
+	})
+}
+
+// namespaces returns the target namespaces and the namespaces matching the namespace selector
+func (pc *PlatformClient) namespaces() []string {
+	pc.namespacesLock.RLock()
+	defer pc.namespacesLock.RUnlock()
+
+	namespaces := make([]string, 0, len(pc.targetNamespaces)+pc.selectedNamespaces.Len())
+	namespaces = append(namespaces, pc.targetNamespaces...)
+	for _, namespace := range pc.selectedNamespaces.List() {
+		if !pc.isTargetNamespace(namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	return namespaces
+}
+
+func (pc *PlatformClient) isTargetNamespace(namespace string) bool {
+	for _, targetNamespace := range pc.targetNamespaces {
+		if targetNamespace == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func (pc *PlatformClient) isSelectedNamespace(namespace string) bool {
+	pc.namespacesLock.RLock()
+	defer pc.namespacesLock.RUnlock()
+
+	return pc.selectedNamespaces.Has(namespace)
+}
+
+func (pc *PlatformClient) namespaceSelected(ctx context.Context, namespace string) {
+	pc.namespacesLock.Lock()
+	pc.selectedNamespaces.Insert(namespace)
+	pc.namespacesLock.Unlock()
+
+	if pc.isTargetNamespace(namespace) {
+		return
+	}
+	if pc.leadership.skipNamespace(namespace) {
+		log.C(ctx).Debugf("Skipping registering brokers in namespace %s, as this replica is not the leader", namespace)
+		return
+	}
+	pc.registerNamespaceBrokers(ctx, namespace)
+}
+
+func (pc *PlatformClient) namespaceUnselected(ctx context.Context, namespace string) {
+	pc.namespacesLock.Lock()
+	pc.selectedNamespaces.Delete(namespace)
+	pc.namespacesLock.Unlock()
+
+	if pc.isTargetNamespace(namespace) {
+		return
+	}
+	if pc.leadership.skipNamespace(namespace) {
+		log.C(ctx).Debugf("Skipping removing brokers from namespace %s, as this replica is not the leader", namespace)
+		return
+	}
+	pc.removeNamespaceBrokers(ctx, namespace)
+}
+
+// reconcileNamespaces registers or removes the brokers of the namespaces which have started or stopped matching
+// the namespace selector, depending on whether they match it now
+func (pc *PlatformClient) reconcileNamespaces(ctx context.Context, namespaces []string) {
+	for _, namespace := range namespaces {
+		if pc.isTargetNamespace(namespace) {
+			continue
+		}
+		if pc.isSelectedNamespace(namespace) {
+			pc.registerNamespaceBrokers(ctx, namespace)
+		} else {
+			pc.removeNamespaceBrokers(ctx, namespace)
+		}
+	}
+}
+
+// registerNamespaceBrokers copies the brokers of another registered namespace into the namespace
+func (pc *PlatformClient) registerNamespaceBrokers(ctx context.Context, namespace string) {
+	var referenceNamespace string
+	for _, registeredNamespace := range pc.namespaces() {
+		if registeredNamespace != namespace {
+			referenceNamespace = registeredNamespace
+			break
+		}
+	}
+	if len(referenceNamespace) == 0 {
+		return
+	}
+
+	log.C(ctx).Infof("Registering brokers in namespace %s matching %s", namespace, pc.namespaceSelector)
+	if err := pc.copyNamespaceBrokers(ctx, referenceNamespace, namespace); err != nil {
+		log.C(ctx).WithError(err).Errorf("Could not register brokers in namespace %s", namespace)
+	}
+}
+
+// removeNamespaceBrokers deletes the brokers of the proxy from the namespace
+func (pc *PlatformClient) removeNamespaceBrokers(ctx context.Context, namespace string) {
+	log.C(ctx).Infof("Removing brokers from namespace %s which no longer matches %s", namespace, pc.namespaceSelector)
+	if err := pc.deleteNamespaceBrokers(ctx, namespace); err != nil {
+		log.C(ctx).WithError(err).Errorf("Could not remove brokers from namespace %s", namespace)
+	}
+}
+
+// copyNamespaceBrokers registers the brokers of the source namespace which are missing in the target namespace
+func (pc *PlatformClient) copyNamespaceBrokers(ctx context.Context, sourceNamespace, targetNamespace string) error {
+	sourceBrokers, err := pc.platformAPI.RetrieveNamespaceServiceBrokers(ctx, sourceNamespace)
+	if err != nil {
+		return err
+	}
+	targetBrokers, err := pc.platformAPI.RetrieveNamespaceServiceBrokers(ctx, targetNamespace)
+	if err != nil {
+		return err
+	}
+
+	registered := make(map[string]bool, len(targetBrokers.Items))
+	for _, broker := range targetBrokers.Items {
+		registered[broker.Name] = true
+	}
+
+	var errs []error
+	for i := range sourceBrokers.Items {
+		broker := &sourceBrokers.Items[i]
+		if registered[broker.Name] || !pc.isOwnedNamespaceBroker(broker) {
+			continue
+		}
+		broker.Labels = pc.ownedBrokerLabels(broker.Labels, pc.legacyNamespaceBrokerID(broker))
+		if !pc.ownsBroker(broker.Labels) {
+			continue
+		}
+		secretRef := namespaceBrokerSecretRef(broker.Spec.AuthInfo)
+		if secretRef == nil {
+			continue
+		}
+
+		if err := pc.copyNamespaceBrokerSecret(ctx, broker, targetNamespace, secretRef.Name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := pc.createNamespaceBroker(ctx, broker.Labels[BrokerIDLabelKey], secretRef.Name, broker.Name, broker.Spec.URL,
+			targetNamespace, broker.Spec.CatalogRestrictions, brokerTLSOf(broker.Spec.CommonServiceBrokerSpec)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// deleteNamespaceBrokers deletes all brokers of the proxy and their credentials secrets in the namespace
+func (pc *PlatformClient) deleteNamespaceBrokers(ctx context.Context, namespace string) error {
+	namespaceBrokers, err := pc.platformAPI.RetrieveNamespaceServiceBrokers(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	brokers := pc.ownedNamespaceBrokers(namespaceBrokers)
+
+	var errs []error
+	for i := range brokers.Items {
+		if !pc.ownsBroker(brokers.Items[i].Labels) {
+			continue
+		}
+		if err := pc.deleteNamespaceBroker(ctx, &brokers.Items[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
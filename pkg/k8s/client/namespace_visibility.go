@@ -0,0 +1,232 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Peripli/service-broker-proxy/pkg/platform"
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	v1core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/retry"
+)
+
+// NamespacesLabelKey is the visibility label which scopes Service Manager visibilities to kubernetes namespaces
+const NamespacesLabelKey = "namespaces"
+
+// Plans which are visible only in some namespaces are exposed through namespace-scoped brokers,
+// which have the same name, URL and credentials as the cluster-scoped broker and are restricted
+// to the plans enabled in their namespace. Such a broker exists only while at least one of its plans is enabled.
+
+// modifyNamespacePlanAccess enables or disables the plan in the namespace-scoped broker in the given namespace.
+// Changes of the same broker within the visibility debounce window are applied together.
+func (pc *PlatformClient) modifyNamespacePlanAccess(ctx context.Context, request *platform.ModifyPlanAccessRequest, namespace string, enabled bool) error {
+	change := planAccessChange{catalogPlanID: request.CatalogPlanID, enabled: enabled}
+	err := pc.planAccess.modify(ctx, brokerSyncKey{namespace: namespace, name: request.BrokerName}, change, func(ctx context.Context, changes []planAccessChange) error {
+		return pc.modifyNamespaceVisibilityBroker(ctx, request.BrokerName, namespace, changes)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to modify access for plan %s of broker %s in namespace %s (%s)", request.CatalogPlanID, request.BrokerName, namespace, err)
+	}
+
+	return nil
+}
+
+// modifyNamespaceVisibilityBroker applies the plan access changes to the namespace-scoped broker in the given namespace,
+// which is created for its first enabled plan and deleted with its last one
+func (pc *PlatformClient) modifyNamespaceVisibilityBroker(ctx context.Context, brokerName, namespace string, changes []planAccessChange) error {
+	return retry.OnError(retry.DefaultRetry, isConflictOrAlreadyExists, func() error {
+		broker, err := pc.platformAPI.RetrieveNamespaceServiceBrokerByName(ctx, brokerName, namespace)
+		if errors.IsNotFound(err) {
+			planIDs := sets.NewString()
+			if setPlansAccess(planIDs, changes); planIDs.Len() == 0 {
+				return nil
+			}
+			return pc.createNamespaceVisibilityBroker(ctx, brokerName, namespace, planIDs)
+		}
+		if err != nil {
+			return err
+		}
+
+		planIDs, _ := visiblePlanIDs(broker.Spec.CatalogRestrictions)
+		if !setPlansAccess(planIDs, changes) {
+			return nil
+		}
+
+		if planIDs.Len() == 0 {
+			return pc.deleteNamespaceBroker(ctx, broker)
+		}
+
+		broker.Spec.CatalogRestrictions = restrictPlans(broker.Spec.CatalogRestrictions, planIDs)
+		_, err = pc.platformAPI.UpdateNamespaceServiceBroker(ctx, broker, namespace)
+		return err
+	})
+}
+
+func (pc *PlatformClient) createNamespaceVisibilityBroker(ctx context.Context, brokerName, namespace string, planIDs sets.String) error {
+	clusterBroker, err := pc.platformAPI.RetrieveClusterServiceBrokerByName(ctx, brokerName)
+	if err != nil {
+		return err
+	}
+
+	secretName, err := pc.copyBrokerSecret(ctx, clusterBroker, namespace)
+	if err != nil {
+		return err
+	}
+
+	broker := newNamespaceServiceBroker(brokerName, clusterBroker.Spec.URL, &v1beta1.LocalObjectReference{
+		Name: secretName,
+	}, pc.ownerLabels(clusterBroker.Labels[BrokerIDLabelKey]))
+	broker.Spec.CommonServiceBrokerSpec.CatalogRestrictions = restrictPlans(nil, planIDs)
+	brokerRelistOf(clusterBroker.Spec.CommonServiceBrokerSpec).apply(&broker.Spec.CommonServiceBrokerSpec)
+	brokerTLSOf(clusterBroker.Spec.CommonServiceBrokerSpec).apply(&broker.Spec.CommonServiceBrokerSpec)
+
+	_, err = pc.platformAPI.CreateNamespaceServiceBroker(ctx, broker, namespace)
+	return err
+}
+
+func (pc *PlatformClient) deleteNamespaceBroker(ctx context.Context, broker *v1beta1.ServiceBroker) error {
+	if err := pc.platformAPI.DeleteNamespaceServiceBroker(ctx, broker.Name, broker.Namespace, &v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if secretRef := namespaceBrokerSecretRef(broker.Spec.AuthInfo); secretRef != nil {
+		if err := pc.platformAPI.DeleteSecret(ctx, broker.Namespace, secretRef.Name); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("error deleting broker credentials secret in namespace %s: %v", broker.Namespace, err)
+		}
+	}
+
+	return nil
+}
+
+// copyBrokerSecret copies the credentials secret of the cluster-scoped broker to the namespace and returns its name
+func (pc *PlatformClient) copyBrokerSecret(ctx context.Context, clusterBroker *v1beta1.ClusterServiceBroker, namespace string) (string, error) {
+	secretRef := clusterBrokerSecretRef(clusterBroker.Spec.AuthInfo)
+	if secretRef == nil {
+		return "", fmt.Errorf("broker %s has no credentials secret", clusterBroker.Name)
+	}
+
+	secret, err := pc.platformAPI.RetrieveSecret(ctx, secretRef.Namespace, secretRef.Name)
+	if err != nil {
+		return "", fmt.Errorf("error getting broker credentials secret in namespace %s: %v", secretRef.Namespace, err)
+	}
+
+	_, err = pc.platformAPI.UpdateServiceBrokerCredentials(ctx, &v1core.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: namespace,
+			Name:      secretRef.Name,
+			Labels:    pc.ownerLabels(clusterBroker.Labels[BrokerIDLabelKey]),
+		},
+		Data: secret.Data,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error updating broker credentials secret in namespace %s: %v", namespace, err)
+	}
+
+	return secretRef.Name, nil
+}
+
+// namespaceVisibilityBrokers returns the namespace-scoped brokers in all namespaces which expose plans of the given cluster-scoped brokers
+func (pc *PlatformClient) namespaceVisibilityBrokers(ctx context.Context, brokerNames sets.String) ([]*v1beta1.ServiceBroker, error) {
+	namespaceBrokers, err := pc.platformAPI.RetrieveNamespaceServiceBrokers(ctx, v1.NamespaceAll)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list namespace-scoped brokers (%s)", err)
+	}
+
+	brokers := make([]*v1beta1.ServiceBroker, 0)
+	for i := range namespaceBrokers.Items {
+		broker := &namespaceBrokers.Items[i]
+		if !brokerNames.Has(broker.Name) || !pc.isOwnedNamespaceBroker(broker) {
+			continue
+		}
+		if _, restricted := visiblePlanIDs(broker.Spec.CatalogRestrictions); restricted {
+			brokers = append(brokers, broker)
+		}
+	}
+
+	return brokers, nil
+}
+
+// namespaceVisibilities returns the visibilities of the plans which are visible only in some namespaces
+func (pc *PlatformClient) namespaceVisibilities(ctx context.Context, brokerNames sets.String) ([]*platform.Visibility, error) {
+	brokers, err := pc.namespaceVisibilityBrokers(ctx, brokerNames)
+	if err != nil {
+		return nil, err
+	}
+
+	visibilities := make([]*platform.Visibility, 0)
+	for _, broker := range brokers {
+		planIDs, _ := visiblePlanIDs(broker.Spec.CatalogRestrictions)
+		for _, planID := range planIDs.List() {
+			visibilities = append(visibilities, &platform.Visibility{
+				Public:             false,
+				CatalogPlanID:      planID,
+				PlatformBrokerName: broker.Name,
+				Labels:             map[string]string{NamespacesLabelKey: broker.Namespace},
+			})
+		}
+	}
+
+	return visibilities, nil
+}
+
+// updateNamespaceVisibilityBrokers propagates the URL, the TLS trust, the relist behavior and optionally the credentials of the cluster-scoped broker
+// to its namespace-scoped brokers and requests a relist of their catalogs
+func (pc *PlatformClient) updateNamespaceVisibilityBrokers(ctx context.Context, brokerName string, updateCredentials, relist bool) error {
+	brokers, err := pc.namespaceVisibilityBrokers(ctx, sets.NewString(brokerName))
+	if err != nil || len(brokers) == 0 {
+		return err
+	}
+
+	clusterBroker, err := pc.platformAPI.RetrieveClusterServiceBrokerByName(ctx, brokerName)
+	if err != nil {
+		return fmt.Errorf("unable to get cluster-scoped broker (%s)", err)
+	}
+
+	for _, broker := range brokers {
+		if updateCredentials {
+			if _, err := pc.copyBrokerSecret(ctx, clusterBroker, broker.Namespace); err != nil {
+				return err
+			}
+		}
+
+		tlsChanged := brokerTLSOf(clusterBroker.Spec.CommonServiceBrokerSpec).apply(&broker.Spec.CommonServiceBrokerSpec)
+		relistChanged := brokerRelistOf(clusterBroker.Spec.CommonServiceBrokerSpec).apply(&broker.Spec.CommonServiceBrokerSpec)
+		if broker.Spec.URL != clusterBroker.Spec.URL || tlsChanged || relistChanged {
+			broker.Spec.URL = clusterBroker.Spec.URL
+			if _, err := pc.platformAPI.UpdateNamespaceServiceBroker(ctx, broker, broker.Namespace); err != nil {
+				return fmt.Errorf("unable to update broker %s in namespace %s (%s)", broker.Name, broker.Namespace, err)
+			}
+		}
+
+		if relist {
+			if err := pc.platformAPI.SyncNamespaceServiceBroker(ctx, broker.Name, broker.Namespace, pc.syncRetries); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deleteNamespaceVisibilityBrokers deletes all namespace-scoped brokers of the cluster-scoped broker
+func (pc *PlatformClient) deleteNamespaceVisibilityBrokers(ctx context.Context, brokerName string) error {
+	brokers, err := pc.namespaceVisibilityBrokers(ctx, sets.NewString(brokerName))
+	if err != nil {
+		return err
+	}
+
+	for _, broker := range brokers {
+		if err := pc.deleteNamespaceBroker(ctx, broker); err != nil {
+			return fmt.Errorf("unable to delete broker %s in namespace %s (%s)", broker.Name, broker.Namespace, err)
+		}
+	}
+
+	return nil
+}
+
+func isConflictOrAlreadyExists(err error) bool {
+	return errors.IsConflict(err) || errors.IsAlreadyExists(err)
+}
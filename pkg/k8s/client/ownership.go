@@ -0,0 +1,116 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+)
+
+const (
+	// ManagedByLabelKey is the label which marks the brokers and secrets created by the proxy
+	ManagedByLabelKey = "app.kubernetes.io/managed-by"
+	// ManagedByLabelValue is the value of the managed-by label of the brokers and secrets created by the proxy
+	ManagedByLabelValue = "service-broker-proxy-k8s"
+	// ProxyInstanceLabelKey is the label which holds the instance name of the proxy which created the broker or secret
+	ProxyInstanceLabelKey = "sbproxy.peripli.io/instance"
+	// BrokerIDLabelKey is the label which holds the Service Manager ID of the broker
+	BrokerIDLabelKey = "sbproxy.peripli.io/broker-id"
+)
+
+// ownerLabels returns the labels of the brokers and secrets which the proxy creates for the Service Manager broker
+func (pc *PlatformClient) ownerLabels(brokerID string) map[string]string {
+	return map[string]string{
+		ManagedByLabelKey:     ManagedByLabelValue,
+		ProxyInstanceLabelKey: pc.instanceName,
+		BrokerIDLabelKey:      brokerID,
+	}
+}
+
+// isOwned reports whether the object with the labels has been created by this proxy.
+// Brokers registered by other means are neither returned nor modified by the proxy.
+func (pc *PlatformClient) isOwned(labels map[string]string) bool {
+	return labels[ManagedByLabelKey] == ManagedByLabelValue && labels[ProxyInstanceLabelKey] == pc.instanceName
+}
+
+// Proxies without ownership labels registered the brokers without labels. Such a broker is recognized by the
+// naming of the proxy: its credentials secret is named after the Service Manager broker, whose ID is also the last
+// segment of the broker URL, and the secret of a cluster-scoped broker is in the secret namespace. These brokers are
+// managed like the brokers with ownership labels, which they get when they are updated or registered again.
+
+// legacyClusterBrokerID returns the Service Manager ID of a cluster-scoped broker which has been registered by a proxy
+// without ownership labels, or an empty string for all other brokers
+func (pc *PlatformClient) legacyClusterBrokerID(broker *v1beta1.ClusterServiceBroker) string {
+	secretRef := clusterBrokerSecretRef(broker.Spec.AuthInfo)
+	if secretRef == nil || secretRef.Namespace != pc.secretNamespace {
+		return ""
+	}
+	return legacyBrokerID(broker.Labels, broker.Spec.URL, secretRef.Name)
+}
+
+// legacyNamespaceBrokerID returns the Service Manager ID of a broker in a namespace which has been registered by a
+// proxy without ownership labels, or an empty string for all other brokers
+func (pc *PlatformClient) legacyNamespaceBrokerID(broker *v1beta1.ServiceBroker) string {
+	secretRef := namespaceBrokerSecretRef(broker.Spec.AuthInfo)
+	if secretRef == nil {
+		return ""
+	}
+	return legacyBrokerID(broker.Labels, broker.Spec.URL, secretRef.Name)
+}
+
+func legacyBrokerID(labels map[string]string, url, secretName string) string {
+	if _, labeled := labels[ManagedByLabelKey]; labeled || len(secretName) == 0 {
+		return ""
+	}
+	if !strings.HasSuffix(strings.TrimSuffix(url, "/"), "/"+secretName) {
+		return ""
+	}
+	return secretName
+}
+
+// isOwnedClusterBroker reports whether the cluster-scoped broker has been created by this proxy, with or without
+// ownership labels
+func (pc *PlatformClient) isOwnedClusterBroker(broker *v1beta1.ClusterServiceBroker) bool {
+	return pc.isOwned(broker.Labels) || len(pc.legacyClusterBrokerID(broker)) > 0
+}
+
+// isOwnedNamespaceBroker reports whether the broker in a namespace has been created by this proxy, with or without
+// ownership labels
+func (pc *PlatformClient) isOwnedNamespaceBroker(broker *v1beta1.ServiceBroker) bool {
+	return pc.isOwned(broker.Labels) || len(pc.legacyNamespaceBrokerID(broker)) > 0
+}
+
+// ownedBrokerLabels returns the labels of a broker the proxy has created, which are the ownership labels for the
+// Service Manager broker if it has been created without them
+func (pc *PlatformClient) ownedBrokerLabels(labels map[string]string, legacyBrokerID string) map[string]string {
+	if len(legacyBrokerID) == 0 {
+		return labels
+	}
+	return adoptedLabels(labels, pc.ownerLabels(legacyBrokerID))
+}
+
+func (pc *PlatformClient) ownedClusterBrokers(brokers *v1beta1.ClusterServiceBrokerList) *v1beta1.ClusterServiceBrokerList {
+	owned := &v1beta1.ClusterServiceBrokerList{Items: make([]v1beta1.ClusterServiceBroker, 0, len(brokers.Items))}
+	for _, broker := range brokers.Items {
+		if pc.isOwnedClusterBroker(&broker) {
+			broker.Labels = pc.ownedBrokerLabels(broker.Labels, pc.legacyClusterBrokerID(&broker))
+			owned.Items = append(owned.Items, broker)
+		}
+	}
+	return owned
+}
+
+func (pc *PlatformClient) ownedNamespaceBrokers(brokers *v1beta1.ServiceBrokerList) *v1beta1.ServiceBrokerList {
+	owned := &v1beta1.ServiceBrokerList{Items: make([]v1beta1.ServiceBroker, 0, len(brokers.Items))}
+	for _, broker := range brokers.Items {
+		if pc.isOwnedNamespaceBroker(&broker) {
+			broker.Labels = pc.ownedBrokerLabels(broker.Labels, pc.legacyNamespaceBrokerID(&broker))
+			owned.Items = append(owned.Items, broker)
+		}
+	}
+	return owned
+}
+
+func notOwnedError(name string) error {
+	return fmt.Errorf("broker %s is not managed by this proxy", name)
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// planAccessFlushTimeout bounds the update which applies a batch, which runs apart from the cancellation of its changes
+const planAccessFlushTimeout = 5 * time.Minute
+
+// planAccessChange enables or disables a plan of a broker
+type planAccessChange struct {
+	catalogPlanID string
+	enabled       bool
+}
+
+// planAccessBatch collects the plan access changes of a broker until the debounce window has passed
+type planAccessBatch struct {
+	ctx     context.Context
+	changes []planAccessChange
+	done    chan struct{}
+	err     error
+}
+
+// planAccessDebouncer collapses bursts of plan access changes of a broker into a single update of its catalog
+// restrictions, each of which makes service-catalog relist the catalog of the broker. The first change of a broker
+// opens a batch which collects the changes of the debounce window, and all changes of the batch receive the result
+// of the update. The update runs with the values of the context of the change which has opened the batch, but does
+// not fail for all changes of the batch when one of them stops waiting. It stops only when the shutdown context is done.
+// Changes are applied right away if no debounce window is configured.
+type planAccessDebouncer struct {
+	window   time.Duration
+	lock     sync.Mutex
+	batches  map[brokerSyncKey]*planAccessBatch
+	shutdown context.Context
+}
+
+func newPlanAccessDebouncer(window time.Duration) *planAccessDebouncer {
+	return &planAccessDebouncer{
+		window:   window,
+		batches:  make(map[brokerSyncKey]*planAccessBatch),
+		shutdown: context.Background(),
+	}
+}
+
+// stopWith stops the updates of the batches when the context is done
+func (d *planAccessDebouncer) stopWith(ctx context.Context) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.shutdown = ctx
+}
+
+// modify adds the change to the batch of the broker and waits for the result of the update which applies the batch
+// until the context is done. The update of a batch is made with the function of the change which has opened the batch.
+func (d *planAccessDebouncer) modify(ctx context.Context, key brokerSyncKey, change planAccessChange,
+	apply func(ctx context.Context, changes []planAccessChange) error) error {
+	if d.window <= 0 {
+		return apply(ctx, []planAccessChange{change})
+	}
+
+	d.lock.Lock()
+	batch, pending := d.batches[key]
+	if !pending {
+		batch = &planAccessBatch{ctx: ctx, done: make(chan struct{})}
+		d.batches[key] = batch
+		time.AfterFunc(d.window, func() {
+			d.lock.Lock()
+			delete(d.batches, key)
+			changes := batch.changes
+			shutdown := d.shutdown
+			d.lock.Unlock()
+
+			flushCtx, cancel := context.WithTimeout(withoutCancel(batch.ctx, shutdown), planAccessFlushTimeout)
+			defer cancel()
+			batch.err = apply(flushCtx, changes)
+			close(batch.done)
+		})
+	}
+	batch.changes = append(batch.changes, change)
+	d.lock.Unlock()
+
+	select {
+	case <-batch.done:
+		return batch.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RelistBehaviorLabelKey is the Service Manager broker label which overrides the relist behavior of the broker,
+	// either "Manual" or "Duration"
+	RelistBehaviorLabelKey = "k8s-relist-behavior"
+	// RelistDurationLabelKey is the Service Manager broker label which overrides the interval in which service-catalog
+	// relists the broker with the Duration relist behavior, e.g. "15m"
+	RelistDurationLabelKey = "k8s-relist-duration"
+)
+
+// brokerRelistOverrides are the relist settings which a Service Manager broker selects with its labels
+type brokerRelistOverrides struct {
+	behavior v1beta1.ServiceBrokerRelistBehavior
+	duration time.Duration
+}
+
+// brokerRelist is the relist behavior of a broker. The proxy requests relists itself, a relist duration makes
+// service-catalog relist the broker also while the proxy is down.
+type brokerRelist struct {
+	behavior v1beta1.ServiceBrokerRelistBehavior
+	duration time.Duration
+}
+
+// apply sets the relist behavior in the broker spec and reports whether the spec has changed
+func (r brokerRelist) apply(spec *v1beta1.CommonServiceBrokerSpec) bool {
+	var duration *v1.Duration
+	if r.behavior == v1beta1.ServiceBrokerRelistBehaviorDuration {
+		duration = &v1.Duration{Duration: r.duration}
+	}
+
+	if spec.RelistBehavior == r.behavior && equalDurations(spec.RelistDuration, duration) {
+		return false
+	}
+	spec.RelistBehavior = r.behavior
+	spec.RelistDuration = duration
+	return true
+}
+
+// brokerRelistOf returns the relist behavior of a broker spec, e.g. to register a broker like an existing one
+func brokerRelistOf(spec v1beta1.CommonServiceBrokerSpec) brokerRelist {
+	relist := brokerRelist{behavior: spec.RelistBehavior}
+	if spec.RelistDuration != nil {
+		relist.duration = spec.RelistDuration.Duration
+	}
+	return relist
+}
+
+// parseBrokerRelistOverrides returns the relist settings selected by the labels of a Service Manager broker
+func parseBrokerRelistOverrides(labels types.Labels) (brokerRelistOverrides, error) {
+	var overrides brokerRelistOverrides
+
+	if values := labels[RelistBehaviorLabelKey]; len(values) > 0 {
+		if len(values) > 1 {
+			return brokerRelistOverrides{}, fmt.Errorf("label %s has more than one value", RelistBehaviorLabelKey)
+		}
+		behavior := v1beta1.ServiceBrokerRelistBehavior(values[0])
+		if behavior != v1beta1.ServiceBrokerRelistBehaviorManual && behavior != v1beta1.ServiceBrokerRelistBehaviorDuration {
+			return brokerRelistOverrides{}, fmt.Errorf("label %s has an invalid value %s", RelistBehaviorLabelKey, values[0])
+		}
+		overrides.behavior = behavior
+	}
+
+	if values := labels[RelistDurationLabelKey]; len(values) > 0 {
+		if len(values) > 1 {
+			return brokerRelistOverrides{}, fmt.Errorf("label %s has more than one value", RelistDurationLabelKey)
+		}
+		duration, err := time.ParseDuration(values[0])
+		if err != nil || duration <= 0 {
+			return brokerRelistOverrides{}, fmt.Errorf("label %s has an invalid value %s", RelistDurationLabelKey, values[0])
+		}
+		overrides.duration = duration
+	}
+
+	return overrides, nil
+}
+
+// brokerRelist returns the relist behavior of the Service Manager broker, which is Manual unless it is configured
+func (pc *PlatformClient) brokerRelist(ctx context.Context, brokerID string) brokerRelist {
+	pc.scopesLock.RLock()
+	overrides := pc.smBrokerRelistOverrides[brokerID]
+	pc.scopesLock.RUnlock()
+
+	relist := brokerRelist{behavior: v1beta1.ServiceBrokerRelistBehaviorManual, duration: pc.relistDuration}
+	if len(pc.relistBehavior) > 0 {
+		relist.behavior = pc.relistBehavior
+	}
+	if len(overrides.behavior) > 0 {
+		relist.behavior = overrides.behavior
+	}
+	if overrides.duration > 0 {
+		relist.duration = overrides.duration
+	}
+
+	if relist.behavior == v1beta1.ServiceBrokerRelistBehaviorDuration && relist.duration <= 0 {
+		log.C(ctx).Errorf("Broker %s selects the %s relist behavior without a relist duration, using the %s relist behavior",
+			brokerID, v1beta1.ServiceBrokerRelistBehaviorDuration, v1beta1.ServiceBrokerRelistBehaviorManual)
+		relist.behavior = v1beta1.ServiceBrokerRelistBehaviorManual
+	}
+	return relist
+}
+
+// configuredBrokerRelist returns the relist behavior of the Service Manager broker if it is configured globally or by
+// the labels of the broker, or nil to keep the relist behavior of a registered broker, e.g. one which has been set by hand
+func (pc *PlatformClient) configuredBrokerRelist(ctx context.Context, brokerID string) *brokerRelist {
+	pc.scopesLock.RLock()
+	overrides := pc.smBrokerRelistOverrides[brokerID]
+	pc.scopesLock.RUnlock()
+
+	if len(pc.relistBehavior) == 0 && overrides == (brokerRelistOverrides{}) {
+		return nil
+	}
+	spec := pc.brokerRelist(ctx, brokerID)
+	return &spec
+}
+
+func equalDurations(a, b *v1.Duration) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Duration == b.Duration
+}
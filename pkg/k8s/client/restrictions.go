@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Peripli/service-broker-proxy/pkg/platform"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// visiblePlanIDs returns the catalog plan IDs which the catalog restrictions allow and whether the plans of the broker
+// are restricted by the proxy at all. Brokers without a plan restriction expose their whole catalog.
+func visiblePlanIDs(restrictions *v1beta1.CatalogRestrictions) (sets.String, bool) {
+	if restrictions == nil {
+		return sets.NewString(), false
+	}
+
+	for _, restriction := range restrictions.ServicePlan {
+		if planIDs, ok := parsePlanRestriction(restriction); ok {
+			return planIDs, true
+		}
+	}
+
+	return sets.NewString(), false
+}
+
+// restrictPlans returns catalog restrictions which allow only the specified catalog plan IDs.
+// Restrictions which are not managed by the proxy are preserved.
+func restrictPlans(restrictions *v1beta1.CatalogRestrictions, planIDs sets.String) *v1beta1.CatalogRestrictions {
+	result := unrestrictPlans(restrictions)
+	if result == nil {
+		result = &v1beta1.CatalogRestrictions{}
+	}
+
+	restriction := fmt.Sprintf("%s in (%s)", v1beta1.FilterSpecExternalID, strings.Join(planIDs.List(), ", "))
+	result.ServicePlan = append(result.ServicePlan, restriction)
+
+	return result
+}
+
+// unrestrictPlans returns catalog restrictions without the plan restriction of the proxy, which allow all plans.
+// Restrictions which are not managed by the proxy are preserved.
+func unrestrictPlans(restrictions *v1beta1.CatalogRestrictions) *v1beta1.CatalogRestrictions {
+	if restrictions == nil {
+		return nil
+	}
+
+	result := &v1beta1.CatalogRestrictions{ServiceClass: restrictions.ServiceClass}
+	for _, restriction := range restrictions.ServicePlan {
+		if _, ok := parsePlanRestriction(restriction); !ok {
+			result.ServicePlan = append(result.ServicePlan, restriction)
+		}
+	}
+	if len(result.ServiceClass) == 0 && len(result.ServicePlan) == 0 {
+		return nil
+	}
+
+	return result
+}
+
+// brokerPlanRestrictions returns the catalog restrictions of a broker which allow the catalog plan IDs, and whether they
+// differ from the current ones. Catalog plan IDs which are not valid label values cannot be used in catalog restrictions.
+// Brokers with such plans are left unrestricted, so that all of their plans are visible, as before they were restricted.
+func brokerPlanRestrictions(ctx context.Context, brokerName string, restrictions *v1beta1.CatalogRestrictions, planIDs sets.String, restricted bool) (*v1beta1.CatalogRestrictions, bool) {
+	for _, planID := range planIDs.List() {
+		if err := validateCatalogPlanID(planID); err != nil {
+			log.C(ctx).Warnf("Leaving all plans of broker %s visible: %s", brokerName, err)
+			return unrestrictPlans(restrictions), restricted
+		}
+	}
+
+	return restrictPlans(restrictions, planIDs), true
+}
+
+// setPlanAccess adds or removes the catalog plan ID and reports whether the set of plan IDs has changed
+func setPlanAccess(planIDs sets.String, catalogPlanID string, enabled bool) bool {
+	if enabled == planIDs.Has(catalogPlanID) {
+		return false
+	}
+
+	if enabled {
+		planIDs.Insert(catalogPlanID)
+	} else {
+		planIDs.Delete(catalogPlanID)
+	}
+
+	return true
+}
+
+// setPlansAccess applies the plan access changes in their order and reports whether the set of plan IDs has changed
+func setPlansAccess(planIDs sets.String, changes []planAccessChange) bool {
+	changed := false
+	for _, change := range changes {
+		changed = setPlanAccess(planIDs, change.catalogPlanID, change.enabled) || changed
+	}
+
+	return changed
+}
+
+// validateCatalogPlanID checks that the catalog plan ID can be used in catalog restrictions
+func validateCatalogPlanID(catalogPlanID string) error {
+	if errs := validation.IsValidLabelValue(catalogPlanID); len(errs) > 0 {
+		return fmt.Errorf("catalog plan ID %s cannot be used in catalog restrictions (%s)", catalogPlanID, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func parsePlanRestriction(restriction string) (sets.String, bool) {
+	selector, err := labels.Parse(restriction)
+	if err != nil {
+		return nil, false
+	}
+
+	requirements, _ := selector.Requirements()
+	if len(requirements) != 1 {
+		return nil, false
+	}
+
+	requirement := requirements[0]
+	if requirement.Key() != v1beta1.FilterSpecExternalID || requirement.Operator() != selection.In {
+		return nil, false
+	}
+
+	// an empty value list is parsed as a single empty value
+	return requirement.Values().Delete(""), true
+}
+
+func clusterPlanIDs(plans *v1beta1.ClusterServicePlanList) sets.String {
+	planIDs := sets.NewString()
+	for _, plan := range plans.Items {
+		if !plan.Status.RemovedFromBrokerCatalog {
+			planIDs.Insert(plan.Spec.ExternalID)
+		}
+	}
+	return planIDs
+}
+
+func namespacePlanIDs(plans *v1beta1.ServicePlanList) sets.String {
+	planIDs := sets.NewString()
+	for _, plan := range plans.Items {
+		if !plan.Status.RemovedFromBrokerCatalog {
+			planIDs.Insert(plan.Spec.ExternalID)
+		}
+	}
+	return planIDs
+}
+
+// publicVisibilities converts the catalog plan IDs visible through a broker to platform visibilities
+func publicVisibilities(brokerName string, planIDs sets.String) []*platform.Visibility {
+	visibilities := make([]*platform.Visibility, 0, planIDs.Len())
+	for _, planID := range planIDs.List() {
+		visibilities = append(visibilities, &platform.Visibility{
+			Public:             true,
+			CatalogPlanID:      planID,
+			PlatformBrokerName: brokerName,
+			Labels:             map[string]string{},
+		})
+	}
+	return visibilities
+}
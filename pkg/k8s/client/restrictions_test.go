@@ -0,0 +1,69 @@
+package client
+
+import (
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var _ = Describe("Catalog restrictions", func() {
+	Describe("visiblePlanIDs", func() {
+		It("reports brokers without restrictions as unrestricted", func() {
+			planIDs, restricted := visiblePlanIDs(nil)
+			Expect(restricted).To(BeFalse())
+			Expect(planIDs.Len()).To(Equal(0))
+		})
+
+		It("ignores restrictions which are not managed by the proxy", func() {
+			_, restricted := visiblePlanIDs(&v1beta1.CatalogRestrictions{
+				ServicePlan: []string{"spec.free=true", "spec.externalID notin (plan-1)"},
+			})
+			Expect(restricted).To(BeFalse())
+		})
+
+		It("returns the allowed plan IDs", func() {
+			planIDs, restricted := visiblePlanIDs(&v1beta1.CatalogRestrictions{
+				ServicePlan: []string{"spec.free=true", "spec.externalID in (plan-1, plan-2)"},
+			})
+			Expect(restricted).To(BeTrue())
+			Expect(planIDs.List()).To(ConsistOf("plan-1", "plan-2"))
+		})
+
+		It("returns no plan IDs for an empty restriction", func() {
+			planIDs, restricted := visiblePlanIDs(&v1beta1.CatalogRestrictions{
+				ServicePlan: []string{"spec.externalID in ()"},
+			})
+			Expect(restricted).To(BeTrue())
+			Expect(planIDs.Len()).To(Equal(0))
+		})
+	})
+
+	Describe("restrictPlans", func() {
+		It("replaces the plan restriction and preserves the others", func() {
+			restrictions := restrictPlans(&v1beta1.CatalogRestrictions{
+				ServiceClass: []string{"spec.externalName=service"},
+				ServicePlan:  []string{"spec.externalID in (plan-1)", "spec.free=true"},
+			}, sets.NewString("plan-3", "plan-2"))
+
+			Expect(restrictions.ServiceClass).To(ConsistOf("spec.externalName=service"))
+			Expect(restrictions.ServicePlan).To(ConsistOf("spec.free=true", "spec.externalID in (plan-2, plan-3)"))
+		})
+	})
+
+	Describe("unrestrictPlans", func() {
+		It("removes the plan restriction and preserves the others", func() {
+			restrictions := unrestrictPlans(&v1beta1.CatalogRestrictions{
+				ServiceClass: []string{"spec.externalName=service"},
+				ServicePlan:  []string{"spec.externalID in (plan-1)", "spec.free=true"},
+			})
+
+			Expect(restrictions.ServiceClass).To(ConsistOf("spec.externalName=service"))
+			Expect(restrictions.ServicePlan).To(ConsistOf("spec.free=true"))
+		})
+
+		It("returns no restrictions if only the plan restriction is set", func() {
+			Expect(unrestrictPlans(&v1beta1.CatalogRestrictions{ServicePlan: []string{"spec.externalID in ()"}})).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,449 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	servicecatalog "github.com/kubernetes-sigs/service-catalog/pkg/svcat/service-catalog"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// With sharding, the replicas of the proxy split the managed brokers between them. Each replica holds a Lease in the
+// secret namespace, which is labeled with the lease name as the shard group, and renews it every lease retry period.
+// The replicas whose Lease has been renewed within the lease duration are the members of the group, and each broker is
+// owned by one member, chosen by consistent hashing on its Service Manager ID. All replicas serve the OSB API, receive
+// the notifications of Service Manager and list all brokers, but a replica writes only the brokers it owns and skips
+// the operations on the other brokers. When the members change, the brokers of a replica which has stopped are
+// rebalanced to the remaining members, and each replica resyncs the brokers to take over the brokers it has gained.
+// A replica which has not renewed its own Lease within the lease duration is considered stopped by the other members,
+// so it owns no brokers until it renews the Lease again, e.g. when it is cut off from the API server.
+
+const (
+	// ShardGroupLabelKey is the label of the shard Leases which holds the name of the group of replicas sharing the
+	// brokers
+	ShardGroupLabelKey = "sbproxy.peripli.io/shard-group"
+
+	// shardVirtualNodes is the number of points of each member on the hash ring, which spread the brokers evenly
+	shardVirtualNodes = 100
+)
+
+// shardRing assigns the brokers to the members by consistent hashing, so that only the brokers of a member which
+// joins or leaves change their owner
+type shardRing struct {
+	hashes  []uint32
+	members map[uint32]string
+}
+
+func newShardRing(members []string) *shardRing {
+	ring := &shardRing{
+		hashes:  make([]uint32, 0, len(members)*shardVirtualNodes),
+		members: make(map[uint32]string, len(members)*shardVirtualNodes),
+	}
+	for _, member := range members {
+		for i := 0; i < shardVirtualNodes; i++ {
+			hash := shardHash(member + "#" + strconv.Itoa(i))
+			// of colliding points, the member which sorts first is used by all replicas
+			if owner, found := ring.members[hash]; found {
+				if member < owner {
+					ring.members[hash] = member
+				}
+				continue
+			}
+			ring.hashes = append(ring.hashes, hash)
+			ring.members[hash] = member
+		}
+	}
+	sort.Slice(ring.hashes, func(i, j int) bool { return ring.hashes[i] < ring.hashes[j] })
+	return ring
+}
+
+// owner returns the member which owns the broker, or an empty string if the ring has no members
+func (r *shardRing) owner(brokerID string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	hash := shardHash(brokerID)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= hash })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.members[r.hashes[i]]
+}
+
+func shardHash(key string) uint32 {
+	hash := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint32(hash[:4])
+}
+
+// shards tracks the members of the shard group, the last renewal of the Lease of the replica and the Service Manager
+// IDs of the platform brokers, which are needed to find the owner of a broker for the plan access changes
+type shards struct {
+	lock          sync.RWMutex
+	member        string
+	members       []string
+	ring          *shardRing
+	leaseDuration time.Duration
+	renewed       time.Time
+	brokerIDs     map[string]string
+}
+
+func newShards(leaseDuration time.Duration) *shards {
+	return &shards{
+		ring:          newShardRing(nil),
+		leaseDuration: leaseDuration,
+		brokerIDs:     make(map[string]string),
+	}
+}
+
+// owns reports whether the replica owns the broker. Brokers whose Service Manager ID is unknown are owned by no
+// replica, so that they are not written by all of them. No brokers are owned before the members are known or while the
+// Lease of the replica is not renewed.
+func (s *shards) owns(brokerID string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.members) == 0 || s.fenced() || len(brokerID) == 0 {
+		return false
+	}
+	return s.ring.owner(brokerID) == s.member
+}
+
+// renew records that the Lease of the replica has been renewed at the time
+func (s *shards) renew(renewed time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.renewed = renewed
+}
+
+// isFenced reports whether the Lease of the replica has not been renewed within the lease duration
+func (s *shards) isFenced() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.fenced()
+}
+
+func (s *shards) fenced() bool {
+	return time.Since(s.renewed) >= s.leaseDuration
+}
+
+// setMembers sets the members of the shard group and reports whether they have changed
+func (s *shards) setMembers(member string, members []string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.member == member && strings.Join(s.members, ",") == strings.Join(members, ",") {
+		return false
+	}
+	s.member = member
+	s.members = members
+	s.ring = newShardRing(members)
+	return true
+}
+
+// brokerID returns the Service Manager ID of the platform broker, which is remembered if it is given
+func (s *shards) brokerID(name, id string) string {
+	if len(id) > 0 {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.brokerIDs[name] = id
+		return id
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.brokerIDs[name]
+}
+
+func (s *shards) forgetBroker(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.brokerIDs, name)
+}
+
+// shardMembership observes the renewals of the shard Leases with the local clock, so that the members do not depend
+// on the clocks of the other replicas
+type shardMembership struct {
+	lease         string
+	leaseDuration time.Duration
+	renewals      map[string]leaseRenewal
+}
+
+type leaseRenewal struct {
+	resourceVersion string
+	observed        time.Time
+}
+
+func newShardMembership(lease string, leaseDuration time.Duration) *shardMembership {
+	return &shardMembership{
+		lease:         lease,
+		leaseDuration: leaseDuration,
+		renewals:      make(map[string]leaseRenewal),
+	}
+}
+
+// observe returns the sorted names of the Leases of the members, which always include the Lease of the replica, and
+// the names of the Leases which have expired
+func (m *shardMembership) observe(leases []coordinationv1.Lease, now time.Time) (members, expired []string) {
+	members = []string{m.lease}
+	renewals := make(map[string]leaseRenewal, len(leases))
+	for _, lease := range leases {
+		if lease.Name == m.lease {
+			continue
+		}
+		renewal, found := m.renewals[lease.Name]
+		if !found || renewal.resourceVersion != lease.ResourceVersion {
+			renewal = leaseRenewal{resourceVersion: lease.ResourceVersion, observed: now}
+		}
+		renewals[lease.Name] = renewal
+
+		if now.Sub(renewal.observed) < m.leaseDuration {
+			members = append(members, lease.Name)
+		} else {
+			expired = append(expired, lease.Name)
+		}
+	}
+	m.renewals = renewals
+
+	sort.Strings(members)
+	return members, expired
+}
+
+// skipped reports whether the operation on the broker is skipped, as the replica is not the leader or does not own
+// the broker. The Service Manager ID of the broker is looked up by its platform name if it is not given.
+func (pc *PlatformClient) skipped(ctx context.Context, name, id, operation string) bool {
+	if pc.skippedAsFollower(ctx, operation) {
+		return true
+	}
+	if !pc.sharding {
+		return false
+	}
+
+	id = pc.shards.brokerID(name, id)
+	if len(id) == 0 {
+		log.C(ctx).Warnf("Skipping %s, as the Service Manager ID of the broker is unknown", operation)
+		return true
+	}
+	if pc.shards.owns(id) {
+		return false
+	}
+	log.C(ctx).Debugf("Skipping %s, as the broker is owned by another replica", operation)
+	return true
+}
+
+// planAccessBrokerID returns the Service Manager ID of the broker whose plan access is modified, which is remembered
+// from the listed brokers or else read from the labels of the platform broker. The ID is only needed with sharding, and
+// it is empty if the broker cannot be retrieved.
+func (pc *PlatformClient) planAccessBrokerID(ctx context.Context, name string) string {
+	if !pc.sharding {
+		return ""
+	}
+	if id := pc.shards.brokerID(name, ""); len(id) > 0 {
+		return id
+	}
+
+	scope, err := pc.brokerScopeByName(ctx, name)
+	if err != nil {
+		log.C(ctx).WithError(err).Warnf("Unable to get the scope of broker %s", name)
+		return ""
+	}
+	cluster, namespaces := pc.registrationScope(scope)
+	if cluster {
+		broker, err := pc.retrieveClusterServiceBrokerByName(ctx, name)
+		if err != nil {
+			log.C(ctx).WithError(err).Warnf("Unable to get cluster-scoped broker %s", name)
+			return ""
+		}
+		return pc.ownedBrokerLabels(broker.Labels, pc.legacyClusterBrokerID(broker))[BrokerIDLabelKey]
+	}
+
+	broker, err := pc.retrieveNamespaceBroker(ctx, name, namespaces)
+	if err != nil {
+		log.C(ctx).WithError(err).Warnf("Unable to get namespace-scoped broker %s", name)
+		return ""
+	}
+	return pc.ownedBrokerLabels(broker.Labels, pc.legacyNamespaceBrokerID(broker))[BrokerIDLabelKey]
+}
+
+// ownsBroker reports whether the replica owns the broker with the labels
+func (pc *PlatformClient) ownsBroker(brokerLabels map[string]string) bool {
+	return !pc.sharding || pc.shards.owns(brokerLabels[BrokerIDLabelKey])
+}
+
+// rememberShardBroker remembers the Service Manager ID of a listed platform broker
+func (pc *PlatformClient) rememberShardBroker(broker servicecatalog.Broker) {
+	if object, ok := broker.(v1.Object); pc.sharding && ok {
+		pc.shards.brokerID(broker.GetName(), object.GetLabels()[BrokerIDLabelKey])
+	}
+}
+
+func (pc *PlatformClient) forgetShardBroker(name string) {
+	if pc.sharding {
+		pc.shards.forgetBroker(name)
+	}
+}
+
+// CreateLease creates a Lease
+func (sca *ServiceCatalogAPI) CreateLease(ctx context.Context, lease *coordinationv1.Lease) (*coordinationv1.Lease, error) {
+	return sca.K8sClient.CoordinationV1().Leases(lease.Namespace).Create(ctx, lease, v1.CreateOptions{})
+}
+
+// UpdateLease updates a Lease
+func (sca *ServiceCatalogAPI) UpdateLease(ctx context.Context, lease *coordinationv1.Lease) (*coordinationv1.Lease, error) {
+	return sca.K8sClient.CoordinationV1().Leases(lease.Namespace).Update(ctx, lease, v1.UpdateOptions{})
+}
+
+// RetrieveLeases gets all Leases in a namespace which match the label selector
+func (sca *ServiceCatalogAPI) RetrieveLeases(ctx context.Context, namespace, labelSelector string) (*coordinationv1.LeaseList, error) {
+	return sca.K8sClient.CoordinationV1().Leases(namespace).List(ctx, v1.ListOptions{LabelSelector: labelSelector})
+}
+
+// DeleteLease deletes a Lease
+func (sca *ServiceCatalogAPI) DeleteLease(ctx context.Context, namespace, name string) error {
+	return sca.K8sClient.CoordinationV1().Leases(namespace).Delete(ctx, name, v1.DeleteOptions{})
+}
+
+// StartSharding joins the shard group of the proxy until the context is done, if sharding is enabled.
+// It returns once the members of the group are known. The resync function is called when the members change.
+func (pc *PlatformClient) StartSharding(ctx context.Context, resync func(context.Context)) error {
+	if !pc.sharding {
+		return nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("unable to determine the shard identity (%s)", err)
+	}
+	leaseDurationSeconds := int32(pc.leaseDuration.Seconds())
+	renewed := time.Now()
+	now := v1.NewMicroTime(renewed)
+	lease, err := pc.platformAPI.CreateLease(ctx, &coordinationv1.Lease{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: pc.secretNamespace,
+			Name:      fmt.Sprintf("%s-%s", pc.leaseName, uuid.NewUUID()),
+			Labels:    map[string]string{ShardGroupLabelKey: pc.leaseName},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &hostname,
+			LeaseDurationSeconds: &leaseDurationSeconds,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create shard Lease (%s)", err)
+	}
+	pc.shards.renew(renewed)
+
+	membership := newShardMembership(lease.Name, pc.leaseDuration)
+	if _, err := pc.syncShards(ctx, membership); err != nil {
+		return fmt.Errorf("unable to list shard Leases (%s)", err)
+	}
+
+	// resyncs run apart from the renewals, so that a long resync does not let the Lease of the replica expire
+	resyncs := make(chan struct{}, 1)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-resyncs:
+				resync(ctx)
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(pc.leaseRetryPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// releasing the Lease hands the brokers over without waiting for the Lease to expire
+				if err := pc.platformAPI.DeleteLease(context.Background(), lease.Namespace, lease.Name); err != nil && !errors.IsNotFound(err) {
+					log.C(ctx).WithError(err).Errorf("Could not release shard Lease %s", lease.Name)
+				}
+				return
+			case <-ticker.C:
+				wasFenced := pc.shards.isFenced()
+				lease = pc.renewShardLease(ctx, lease)
+				fenced := pc.shards.isFenced()
+				if fenced && !wasFenced {
+					log.C(ctx).Errorf("Shard Lease %s has not been renewed within %s, skipping the operations on all brokers until it is renewed",
+						lease.Name, pc.leaseDuration)
+				}
+
+				changed, err := pc.syncShards(ctx, membership)
+				if err != nil {
+					log.C(ctx).WithError(err).Error("Could not list shard Leases")
+				}
+				// a replica which renews its Lease again may have missed the changes of its brokers
+				if changed || wasFenced && !fenced {
+					select {
+					case resyncs <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// renewShardLease renews the Lease of the replica and returns its current state. A Lease which has been deleted as
+// expired by another replica is created again.
+func (pc *PlatformClient) renewShardLease(ctx context.Context, lease *coordinationv1.Lease) *coordinationv1.Lease {
+	renewal := lease.DeepCopy()
+	renewed := time.Now()
+	now := v1.NewMicroTime(renewed)
+	renewal.Spec.RenewTime = &now
+
+	renewedLease, err := pc.platformAPI.UpdateLease(ctx, renewal)
+	if errors.IsNotFound(err) {
+		renewal.ResourceVersion = ""
+		renewedLease, err = pc.platformAPI.CreateLease(ctx, renewal)
+	}
+	if err != nil {
+		log.C(ctx).WithError(err).Errorf("Could not renew shard Lease %s", lease.Name)
+		return lease
+	}
+	pc.shards.renew(renewed)
+	return renewedLease
+}
+
+// syncShards updates the members of the shard group from its Leases, deletes the expired Leases and reports whether
+// the members have changed
+func (pc *PlatformClient) syncShards(ctx context.Context, membership *shardMembership) (bool, error) {
+	leases, err := pc.platformAPI.RetrieveLeases(ctx, pc.secretNamespace, labels.Set{ShardGroupLabelKey: pc.leaseName}.String())
+	if err != nil {
+		return false, err
+	}
+
+	members, expired := membership.observe(leases.Items, time.Now())
+	for _, name := range expired {
+		if err := pc.platformAPI.DeleteLease(ctx, pc.secretNamespace, name); err != nil && !errors.IsNotFound(err) {
+			log.C(ctx).WithError(err).Errorf("Could not delete expired shard Lease %s", name)
+		}
+	}
+
+	if !pc.shards.setMembers(membership.lease, members) {
+		return false, nil
+	}
+	log.C(ctx).Infof("Shard group %s has %d members, this replica holds shard Lease %s", pc.leaseName, len(members), membership.lease)
+	return true, nil
+}
@@ -0,0 +1,273 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Peripli/service-broker-proxy-k8s/pkg/k8s/api/apifakes"
+	"github.com/Peripli/service-broker-proxy/pkg/platform"
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	servicecatalog "github.com/kubernetes-sigs/service-catalog/pkg/svcat/service-catalog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var _ = Describe("Sharding", func() {
+	var (
+		ctx     context.Context
+		cancel  context.CancelFunc
+		k8sFake *k8sfake.Clientset
+		resyncs int32
+	)
+
+	resync := func(context.Context) {
+		atomic.AddInt32(&resyncs, 1)
+	}
+
+	newShardedClient := func() *PlatformClient {
+		return &PlatformClient{
+			platformAPI:      NewDefaultKubernetesAPI(&servicecatalog.SDK{K8sClient: k8sFake}),
+			secretNamespace:  "sbproxy",
+			leaseName:        "sbproxy-lease",
+			leaseDuration:    500 * time.Millisecond,
+			leaseRetryPeriod: 50 * time.Millisecond,
+			leadership:       newLeadership(true),
+			sharding:         true,
+			shards:           newShards(500 * time.Millisecond),
+		}
+	}
+
+	members := func(pc *PlatformClient) func() []string {
+		return func() []string {
+			pc.shards.lock.RLock()
+			defer pc.shards.lock.RUnlock()
+			return pc.shards.members
+		}
+	}
+
+	shardLeases := func() []string {
+		leases, err := k8sFake.CoordinationV1().Leases("sbproxy").List(ctx, v1.ListOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		names := make([]string, 0, len(leases.Items))
+		for _, lease := range leases.Items {
+			names = append(names, lease.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		k8sFake = k8sfake.NewSimpleClientset()
+		resyncs = 0
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("shard ring", func() {
+		brokerIDs := func(count int) []string {
+			ids := make([]string, 0, count)
+			for i := 0; i < count; i++ {
+				ids = append(ids, fmt.Sprintf("broker-%d", i))
+			}
+			return ids
+		}
+
+		It("spreads the brokers over all members", func() {
+			ring := newShardRing([]string{"a", "b", "c"})
+
+			owned := make(map[string]int)
+			for _, id := range brokerIDs(3000) {
+				owned[ring.owner(id)]++
+			}
+			Expect(owned).To(HaveLen(3))
+			for _, count := range owned {
+				Expect(count).To(BeNumerically(">", 600))
+			}
+		})
+
+		It("moves only the brokers of a member which leaves", func() {
+			before := newShardRing([]string{"a", "b", "c"})
+			after := newShardRing([]string{"a", "c"})
+
+			for _, id := range brokerIDs(1000) {
+				if owner := before.owner(id); owner != "b" {
+					Expect(after.owner(id)).To(Equal(owner))
+				}
+			}
+		})
+	})
+
+	It("skips the operations on the brokers of another replica", func() {
+		fakeAPI := &apifakes.FakeKubernetesAPI{}
+		platformClient := newShardedClient()
+		platformClient.platformAPI = fakeAPI
+		platformClient.shards.setMembers("a", []string{"a", "b"})
+		platformClient.shards.renew(time.Now())
+
+		var otherID string
+		for i := 0; len(otherID) == 0; i++ {
+			if id := fmt.Sprintf("broker-%d", i); !platformClient.shards.owns(id) {
+				otherID = id
+			}
+		}
+
+		broker, err := platformClient.CreateBroker(ctx, &platform.CreateServiceBrokerRequest{ID: otherID, Name: "broker", BrokerURL: "url"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(broker.Name).To(Equal("broker"))
+		_, err = platformClient.UpdateBroker(ctx, &platform.UpdateServiceBrokerRequest{ID: otherID, Name: "broker"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(platformClient.Fetch(ctx, &platform.UpdateServiceBrokerRequest{ID: otherID, Name: "broker"})).To(Succeed())
+		Expect(platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{BrokerName: "broker", CatalogPlanID: "plan"})).To(Succeed())
+		Expect(platformClient.DisableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{BrokerName: "broker", CatalogPlanID: "plan"})).To(Succeed())
+		Expect(platformClient.DeleteBroker(ctx, &platform.DeleteServiceBrokerRequest{ID: otherID, Name: "broker"})).To(Succeed())
+
+		Expect(fakeAPI.Invocations()).To(BeEmpty())
+	})
+
+	Describe("plan access changes", func() {
+		var fakeAPI *apifakes.FakeKubernetesAPI
+
+		newPlanAccessClient := func(members ...string) *PlatformClient {
+			platformClient := newShardedClient()
+			platformClient.platformAPI = fakeAPI
+			platformClient.brokerScopes = make(map[string]brokerScope)
+			platformClient.scopesLock = &sync.RWMutex{}
+			platformClient.brokerScopesLoaded = true
+			platformClient.shards.setMembers("a", members)
+			platformClient.shards.renew(time.Now())
+			return platformClient
+		}
+
+		BeforeEach(func() {
+			fakeAPI = &apifakes.FakeKubernetesAPI{}
+		})
+
+		It("skips the plan access changes of brokers of another replica which have not been listed", func() {
+			platformClient := newPlanAccessClient("a", "b")
+			var otherID string
+			for i := 0; len(otherID) == 0; i++ {
+				if id := fmt.Sprintf("broker-%d", i); !platformClient.shards.owns(id) {
+					otherID = id
+				}
+			}
+			fakeAPI.RetrieveClusterServiceBrokerByNameReturns(&v1beta1.ClusterServiceBroker{
+				ObjectMeta: v1.ObjectMeta{Name: "broker", Labels: map[string]string{BrokerIDLabelKey: otherID}},
+			}, nil)
+
+			Expect(platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{BrokerName: "broker", CatalogPlanID: "plan"})).To(Succeed())
+
+			Expect(fakeAPI.RetrieveClusterServiceBrokerByNameCallCount()).To(Equal(1))
+			Expect(fakeAPI.UpdateClusterServiceBrokerCallCount()).To(Equal(0))
+			Expect(platformClient.shards.brokerID("broker", "")).To(Equal(otherID))
+		})
+
+		It("skips the plan access changes of brokers whose Service Manager ID is unknown", func() {
+			platformClient := newPlanAccessClient("a")
+			fakeAPI.RetrieveClusterServiceBrokerByNameReturns(nil, errors.New("expected"))
+
+			Expect(platformClient.EnableAccessForPlan(ctx, &platform.ModifyPlanAccessRequest{BrokerName: "broker", CatalogPlanID: "plan"})).To(Succeed())
+
+			Expect(fakeAPI.RetrieveClusterServiceBrokerByNameCallCount()).To(Equal(1))
+			Expect(fakeAPI.UpdateClusterServiceBrokerCallCount()).To(Equal(0))
+		})
+	})
+
+	It("splits the brokers between the replicas and rebalances them when a replica stops", func() {
+		first := newShardedClient()
+		Expect(first.StartSharding(ctx, resync)).To(Succeed())
+		Expect(members(first)()).To(HaveLen(1))
+
+		secondCtx, stopSecond := context.WithCancel(ctx)
+		second := newShardedClient()
+		Expect(second.StartSharding(secondCtx, func(context.Context) {})).To(Succeed())
+		Expect(members(second)()).To(HaveLen(2))
+		Eventually(members(first)).Should(HaveLen(2))
+		Eventually(func() int32 { return atomic.LoadInt32(&resyncs) }).Should(Equal(int32(1)))
+
+		for i := 0; i < 100; i++ {
+			id := fmt.Sprintf("broker-%d", i)
+			Expect(first.shards.owns(id)).ToNot(Equal(second.shards.owns(id)))
+		}
+
+		stopSecond()
+
+		Eventually(shardLeases).Should(HaveLen(1))
+		Eventually(members(first)).Should(HaveLen(1))
+		Eventually(func() int32 { return atomic.LoadInt32(&resyncs) }).Should(Equal(int32(2)))
+		for i := 0; i < 100; i++ {
+			Expect(first.shards.owns(fmt.Sprintf("broker-%d", i))).To(BeTrue())
+		}
+	})
+
+	It("removes the Lease of a replica which has stopped renewing it", func() {
+		_, err := k8sFake.CoordinationV1().Leases("sbproxy").Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "sbproxy",
+				Name:      "sbproxy-lease-stopped",
+				Labels:    map[string]string{ShardGroupLabelKey: "sbproxy-lease"},
+			},
+		}, v1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		platformClient := newShardedClient()
+		Expect(platformClient.StartSharding(ctx, resync)).To(Succeed())
+		Expect(members(platformClient)()).To(HaveLen(2))
+
+		Eventually(shardLeases).Should(HaveLen(1))
+		Expect(shardLeases()[0]).ToNot(Equal("sbproxy-lease-stopped"))
+		Expect(members(platformClient)()).To(HaveLen(1))
+		Eventually(func() int32 { return atomic.LoadInt32(&resyncs) }).Should(Equal(int32(1)))
+	})
+
+	It("owns no brokers before the members are known", func() {
+		platformClient := newShardedClient()
+		platformClient.shards.renew(time.Now())
+
+		Expect(platformClient.shards.owns("broker-0")).To(BeFalse())
+		Expect(platformClient.shards.owns("")).To(BeFalse())
+	})
+
+	It("owns no brokers while its Lease is not renewed and resyncs once it is renewed again", func() {
+		var partitioned int32
+		k8sFake.PrependReactor("*", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetVerb() != "list" && atomic.LoadInt32(&partitioned) == 1 {
+				return true, nil, errors.New("connection refused")
+			}
+			return false, nil, nil
+		})
+		platformClient := newShardedClient()
+		Expect(platformClient.StartSharding(ctx, resync)).To(Succeed())
+		Expect(platformClient.shards.owns("broker-0")).To(BeTrue())
+
+		atomic.StoreInt32(&partitioned, 1)
+
+		Eventually(func() bool { return platformClient.shards.owns("broker-0") }).Should(BeFalse())
+		Expect(members(platformClient)()).To(HaveLen(1))
+		Expect(atomic.LoadInt32(&resyncs)).To(BeZero())
+
+		atomic.StoreInt32(&partitioned, 0)
+
+		Eventually(func() bool { return platformClient.shards.owns("broker-0") }).Should(BeTrue())
+		Eventually(func() int32 { return atomic.LoadInt32(&resyncs) }).Should(Equal(int32(1)))
+	})
+
+	It("does not join a shard group if sharding is disabled", func() {
+		platformClient := newShardedClient()
+		platformClient.sharding = false
+
+		Expect(platformClient.StartSharding(ctx, resync)).To(Succeed())
+
+		Consistently(shardLeases, 200*time.Millisecond).Should(BeEmpty())
+	})
+})
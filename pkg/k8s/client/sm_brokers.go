@@ -0,0 +1,130 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Peripli/service-broker-proxy/pkg/sm"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// readyBrokersQuery is the field query with which Service Manager clients list the brokers to register
+const readyBrokersQuery = "ready eq true"
+
+// smBrokerRecorder is the transport of the Service Manager clients of sbproxy. It records the brokers they list at the
+// start of a resync, so that the labels of the brokers are read from that list instead of listing them once more.
+type smBrokerRecorder struct {
+	next http.RoundTripper
+
+	lock *sync.Mutex
+	// pending holds the brokers of the lists whose retrieval is not complete by the token of their next page
+	pending  map[string][]*types.ServiceBroker
+	brokers  []*types.ServiceBroker
+	recorded bool
+}
+
+func newSMBrokerRecorder() *smBrokerRecorder {
+	return &smBrokerRecorder{
+		lock:    &sync.Mutex{},
+		pending: make(map[string][]*types.ServiceBroker),
+	}
+}
+
+// RoundTrip passes the request to the next transport and records the brokers of the responses to broker lists
+func (r *smBrokerRecorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := r.next.RoundTrip(request)
+	if err != nil || response.StatusCode != http.StatusOK || !isReadyBrokersList(request) {
+		return response, err
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var page struct {
+		Token string                 `json:"token"`
+		Items []*types.ServiceBroker `json:"items"`
+	}
+	// the Service Manager client reports responses which cannot be parsed
+	if err := json.Unmarshal(body, &page); err == nil {
+		r.record(request.URL.Query().Get("token"), page.Token, page.Items)
+	}
+
+	return response, nil
+}
+
+func isReadyBrokersList(request *http.Request) bool {
+	return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, web.ServiceBrokersURL) &&
+		request.URL.Query().Get("fieldQuery") == readyBrokersQuery
+}
+
+// record adds the brokers of a page to the list the page has been requested for, which is the list started with
+// the page if the token is empty
+func (r *smBrokerRecorder) record(token, nextToken string, brokers []*types.ServiceBroker) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(token) > 0 {
+		brokers = append(r.pending[token], brokers...)
+		delete(r.pending, token)
+	}
+	if len(nextToken) > 0 {
+		r.pending[nextToken] = brokers
+		return
+	}
+
+	r.brokers, r.recorded = brokers, true
+}
+
+// take returns the brokers of the last list which has been retrieved since the previous call
+func (r *smBrokerRecorder) take() ([]*types.ServiceBroker, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	brokers, recorded := r.brokers, r.recorded
+	r.brokers, r.recorded = nil, false
+	return brokers, recorded
+}
+
+// RecordSMBrokers sets the transport of the Service Manager settings, with which sbproxy creates its clients, to one
+// which passes the brokers listed at the start of each resync to the platform client. It must be called before the
+// clients are created.
+func (pc *PlatformClient) RecordSMBrokers(settings *sm.Settings) error {
+	if settings.Transport == nil {
+		certificates, err := settings.GetCertificates()
+		if err != nil {
+			return err
+		}
+		settings.Transport = &sm.SSLTransport{
+			SkipSslValidation: settings.SkipSSLValidation,
+			TLSCertificates:   certificates,
+		}
+	}
+
+	pc.smBrokers.next = settings.Transport
+	settings.Transport = pc.smBrokers
+	return nil
+}
+
+// listSMBrokers returns the brokers which sbproxy has listed since the previous call or, if it has not, lists them
+func (pc *PlatformClient) listSMBrokers(ctx context.Context) ([]*types.ServiceBroker, error) {
+	if brokers, recorded := pc.smBrokers.take(); recorded {
+		return brokers, nil
+	}
+
+	brokers, err := pc.smClient.GetBrokers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get broker scopes (%s)", err)
+	}
+	return brokers, nil
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Peripli/service-broker-proxy/pkg/platform"
+	"github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	v1core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// createNamespaceBroker registers the broker in one of the target namespaces with the credentials secret of that namespace
+func (pc *PlatformClient) createNamespaceBroker(ctx context.Context, brokerID, secretName, name, url, namespace string, restrictions *v1beta1.CatalogRestrictions, tls *brokerTLS) (*v1beta1.ServiceBroker, error) {
+	broker := newNamespaceServiceBroker(name, url, &v1beta1.LocalObjectReference{
+		Name: secretName,
+	}, pc.ownerLabels(brokerID))
+	broker.Spec.CommonServiceBrokerSpec.CatalogRestrictions = restrictions
+	pc.brokerRelist(ctx, brokerID).apply(&broker.Spec.CommonServiceBrokerSpec)
+	tls.apply(&broker.Spec.CommonServiceBrokerSpec)
+
+	sb, err := pc.createNamespaceServiceBroker(ctx, brokerID, broker, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create broker %s in namespace %s (%s)", name, namespace, err)
+	}
+
+	return sb, nil
+}
+
+// registerMissingNamespaceBroker registers the broker in a target namespace in which it is missing.
+// The credentials are taken from the request or, if the request has none, copied from a target namespace in which
+// the broker is registered. The plans which are visible in that namespace are made visible in the new one as well.
+func (pc *PlatformClient) registerMissingNamespaceBroker(ctx context.Context, r *platform.UpdateServiceBrokerRequest, registeredBroker *v1beta1.ServiceBroker, namespace string, tls *brokerTLS) error {
+	if r.Username != "" && r.Password != "" {
+		if err := pc.updateBrokerPlatformSecret(ctx, namespace, r.ID, r.Username, r.Password); err != nil {
+			return err
+		}
+	} else if registeredBroker != nil {
+		if err := pc.copyNamespaceBrokerSecret(ctx, registeredBroker, namespace, r.ID); err != nil {
+			return err
+		}
+	} else {
+		return fmt.Errorf("unable to register broker %s in namespace %s: no credentials available", r.Name, namespace)
+	}
+
+	restrictions := restrictPlans(nil, sets.NewString())
+	if registeredBroker != nil {
+		restrictions = registeredBroker.Spec.CatalogRestrictions
+	}
+
+	_, err := pc.createNamespaceBroker(ctx, r.ID, r.ID, r.Name, r.BrokerURL, namespace, restrictions, tls)
+	return err
+}
+
+// copyNamespaceBrokerSecret copies the credentials secret of a namespace-scoped broker to another namespace
+func (pc *PlatformClient) copyNamespaceBrokerSecret(ctx context.Context, broker *v1beta1.ServiceBroker, namespace, secretName string) error {
+	secretRef := namespaceBrokerSecretRef(broker.Spec.AuthInfo)
+	if secretRef == nil {
+		return fmt.Errorf("broker %s in namespace %s has no credentials secret", broker.Name, broker.Namespace)
+	}
+
+	secret, err := pc.platformAPI.RetrieveSecret(ctx, broker.Namespace, secretRef.Name)
+	if err != nil {
+		return fmt.Errorf("error getting broker credentials secret in namespace %s: %v", broker.Namespace, err)
+	}
+
+	_, err = pc.platformAPI.UpdateServiceBrokerCredentials(ctx, &v1core.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: namespace,
+			Name:      secretName,
+			Labels:    pc.ownerLabels(broker.Labels[BrokerIDLabelKey]),
+		},
+		Data: secret.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating broker credentials secret in namespace %s: %v", namespace, err)
+	}
+
+	return nil
+}
+
+// retrieveNamespaceBroker returns the broker from the first of the namespaces in which it is registered
+func (pc *PlatformClient) retrieveNamespaceBroker(ctx context.Context, name string, namespaces []string) (*v1beta1.ServiceBroker, error) {
+	var lastErr error
+	for _, namespace := range namespaces {
+		broker, err := pc.retrieveNamespaceServiceBrokerByName(ctx, name, namespace)
+		if err == nil {
+			return broker, nil
+		}
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = errors.NewNotFound(v1beta1.Resource("servicebrokers"), name)
+	}
+
+	return nil, lastErr
+}
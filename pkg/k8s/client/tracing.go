@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-broker-proxy-k8s/pkg/k8s/config"
+	"github.com/Peripli/service-manager/pkg/web"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TracingFilterName is the name of the filter which continues the traces of the incoming requests
+	TracingFilterName = "TracingFilter"
+
+	tracerName = "github.com/Peripli/service-broker-proxy-k8s/pkg/k8s/client"
+
+	brokerAttributeKey   = attribute.Key("sbproxy.broker")
+	brokerIDAttributeKey = attribute.Key("sbproxy.broker_id")
+	planIDAttributeKey   = attribute.Key("sbproxy.catalog_plan_id")
+	scopeAttributeKey    = attribute.Key("k8s.scope")
+)
+
+// StartTracing installs the tracer provider which exports the spans of the proxy as configured, and the W3C trace
+// context propagation. Spans are not recorded if no exporter is configured. The returned function flushes the
+// spans which have not been exported yet and stops the export.
+func StartTracing(ctx context.Context, settings *config.TracingSettings) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if settings == nil || settings.Exporter == config.NoTracingExporter {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlpgrpc.Option{otlpgrpc.WithEndpoint(settings.Endpoint)}
+	if settings.Insecure {
+		options = append(options, otlpgrpc.WithInsecure())
+	}
+	exporter, err := otlp.NewExporter(ctx, otlpgrpc.NewDriver(options...))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create OTLP trace exporter for %s (%s)", settings.Endpoint, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(settings.SampleRatio))),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(semconv.ServiceNameKey.String(settings.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// startSpan starts a span of the proxy as a child of the span of the context
+func startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// endSpan records the error, if any, and ends the span
+func endSpan(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+// NewTracingFilter returns the filter which continues the trace of each incoming request of the sbproxy server,
+// so that the work done for a request of Service Manager is part of its trace
+func NewTracingFilter() web.Filter {
+	return &tracingFilter{}
+}
+
+type tracingFilter struct{}
+
+// Name returns the name of the filter
+func (*tracingFilter) Name() string {
+	return TracingFilterName
+}
+
+// Run starts a server span which continues the trace propagated with the request headers
+func (*tracingFilter) Run(req *web.Request, next web.Handler) (resp *web.Response, err error) {
+	ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+	ctx, span := otel.Tracer(tracerName).Start(ctx, fmt.Sprintf("%s %s", req.Method, req.URL.Path),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.HTTPMethodKey.String(req.Method), semconv.HTTPTargetKey.String(req.URL.Path)))
+	defer endSpan(span, &err)
+
+	req.Request = req.WithContext(ctx)
+	resp, err = next.Handle(req)
+	if resp != nil {
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+		if resp.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+		}
+	}
+	return resp, err
+}
+
+// FilterMatchers returns the matchers of all requests
+func (*tracingFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path("/**"),
+			},
+		},
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"k8s.io/client-go/tools/clientcmd"
+	"strings"
 	"time"
 
 	"github.com/Peripli/service-broker-proxy/pkg/sbproxy"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/rest"
 
 	"github.com/Peripli/service-manager/pkg/env"
@@ -14,14 +17,35 @@ import (
 	servicecatalog "github.com/kubernetes-sigs/service-catalog/pkg/svcat/service-catalog"
 
 	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
 
 	"github.com/spf13/pflag"
 )
 
+const (
+	// AdoptExistingBroker takes over a broker of the same name which already exists
+	AdoptExistingBroker = "adopt"
+	// FailOnExistingBroker fails to register a broker if a broker of the same name already exists
+	FailOnExistingBroker = "fail"
+	// ReplaceExistingBroker deletes a broker of the same name which already exists and registers the broker again
+	ReplaceExistingBroker = "replace"
+
+	// ManualRelistBehavior makes service-catalog relist brokers only when the proxy requests it
+	ManualRelistBehavior = "Manual"
+	// DurationRelistBehavior makes service-catalog relist brokers also periodically after the relist duration
+	DurationRelistBehavior = "Duration"
+
+	// NoTracingExporter disables the export of traces
+	NoTracingExporter = "none"
+	// OTLPTracingExporter exports traces with the OpenTelemetry protocol over gRPC
+	OTLPTracingExporter = "otlp"
+)
+
 // Settings type wraps the K8S client configuration
 type Settings struct {
 	sbproxy.Settings `mapstructure:",squash"`
 	K8S              *ClientConfiguration `mapstructure:"k8s"`
+	Tracing          *TracingSettings     `mapstructure:"tracing"`
 }
 
 // DefaultSettings returns the default settings for the k8s agent
@@ -29,6 +53,7 @@ func DefaultSettings() *Settings {
 	return &Settings{
 		Settings: *sbproxy.DefaultSettings(),
 		K8S:      DefaultClientConfiguration(),
+		Tracing:  DefaultTracingSettings(),
 	}
 }
 
@@ -37,15 +62,100 @@ func (s *Settings) Validate() error {
 	if err := s.K8S.Validate(); err != nil {
 		return err
 	}
+	if s.Tracing != nil {
+		if err := s.Tracing.Validate(); err != nil {
+			return err
+		}
+	}
 	return s.Settings.Validate()
 }
 
+// TracingSettings type holds the configuration of the export of the traces of the proxy
+type TracingSettings struct {
+	Exporter    string  `mapstructure:"exporter"`
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	ServiceName string  `mapstructure:"service_name"`
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// DefaultTracingSettings returns the default tracing settings, which export no traces
+func DefaultTracingSettings() *TracingSettings {
+	return &TracingSettings{
+		Exporter:    NoTracingExporter,
+		Endpoint:    "localhost:4317",
+		ServiceName: "service-broker-proxy-k8s",
+		SampleRatio: 1,
+	}
+}
+
+// Validate validates the tracing settings and returns appropriate errors in case they are invalid
+func (t *TracingSettings) Validate() error {
+	switch t.Exporter {
+	case NoTracingExporter:
+		return nil
+	case OTLPTracingExporter:
+	default:
+		return fmt.Errorf("tracing exporter %s is invalid: must be %s or %s", t.Exporter, NoTracingExporter, OTLPTracingExporter)
+	}
+	if t.Endpoint == "" {
+		return errors.New("tracing endpoint missing")
+	}
+	if t.ServiceName == "" {
+		return errors.New("tracing service name missing")
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return errors.New("tracing sample ratio must be between 0 and 1")
+	}
+	return nil
+}
+
 // ClientConfiguration type holds config info for building the k8s service catalog client
 type ClientConfiguration struct {
-	ClientSettings      *LibraryConfig                                    `mapstructure:"client"`
-	Secret              *SecretRef                                        `mapstructure:"secret"`
-	K8sClientCreateFunc func(*LibraryConfig) (*servicecatalog.SDK, error) `mapstructure:"-"`
-	TargetNamespace     string                                            `mapstructure:"target_namespace"`
+	ClientSettings        *LibraryConfig                                    `mapstructure:"client"`
+	Secret                *SecretRef                                        `mapstructure:"secret"`
+	K8sClientCreateFunc   func(*LibraryConfig) (*servicecatalog.SDK, error) `mapstructure:"-"`
+	TargetNamespace       string                                            `mapstructure:"target_namespace"`
+	TargetNamespaces      []string                                          `mapstructure:"target_namespaces"`
+	NamespaceSelector     string                                            `mapstructure:"namespace_selector"`
+	BrokerCache           bool                                              `mapstructure:"broker_cache"`
+	InstanceName          string                                            `mapstructure:"instance_name"`
+	ExistingBrokerPolicy  string                                            `mapstructure:"existing_broker_policy"`
+	BrokerReadyTimeout    time.Duration                                     `mapstructure:"broker_ready_timeout"`
+	RelistTimeout         time.Duration                                     `mapstructure:"relist_timeout"`
+	CABundleFile          string                                            `mapstructure:"ca_bundle_file"`
+	CABundleSecret        string                                            `mapstructure:"ca_bundle_secret"`
+	InsecureSkipTLSVerify bool                                              `mapstructure:"insecure_skip_tls_verify"`
+	RelistBehavior        string                                            `mapstructure:"relist_behavior"`
+	RelistDuration        time.Duration                                     `mapstructure:"relist_duration"`
+	VisibilityDebounce    time.Duration                                     `mapstructure:"visibility_debounce"`
+	SyncWorkers           int                                               `mapstructure:"sync_workers"`
+	SyncRetries           int                                               `mapstructure:"sync_retries"`
+	SyncRateLimit         float64                                           `mapstructure:"sync_rate_limit"`
+	SyncBurst             int                                               `mapstructure:"sync_burst"`
+	LeaderElection        bool                                              `mapstructure:"leader_election"`
+	LeaseName             string                                            `mapstructure:"lease_name"`
+	LeaseDuration         time.Duration                                     `mapstructure:"lease_duration"`
+	LeaseRenewDeadline    time.Duration                                     `mapstructure:"lease_renew_deadline"`
+	LeaseRetryPeriod      time.Duration                                     `mapstructure:"lease_retry_period"`
+	Sharding              bool                                              `mapstructure:"sharding"`
+}
+
+// Namespaces returns the namespaces in which brokers should be registered.
+// Brokers are registered cluster-wide if no namespaces are configured.
+// TargetNamespace is kept for compatibility and is merged with TargetNamespaces.
+func (c *ClientConfiguration) Namespaces() []string {
+	namespaces := make([]string, 0, len(c.TargetNamespaces)+1)
+	seen := make(map[string]bool)
+	for _, namespace := range append([]string{c.TargetNamespace}, c.TargetNamespaces...) {
+		namespace = strings.TrimSpace(namespace)
+		if namespace == "" || seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces
 }
 
 // Validate validates the configuration and returns appropriate errors in case it is invalid
@@ -65,6 +175,112 @@ func (c *ClientConfiguration) Validate() error {
 	if err := c.Secret.Validate(); err != nil {
 		return err
 	}
+	for _, namespace := range c.Namespaces() {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fmt.Errorf("K8S target namespace %s is invalid: %s", namespace, strings.Join(errs, "; "))
+		}
+	}
+	if _, err := labels.Parse(c.NamespaceSelector); err != nil {
+		return fmt.Errorf("K8S namespace selector is invalid: %s", err)
+	}
+	if c.InstanceName == "" {
+		return errors.New("K8S instance name missing")
+	}
+	if errs := validation.IsValidLabelValue(c.InstanceName); len(errs) > 0 {
+		return fmt.Errorf("K8S instance name %s is invalid: %s", c.InstanceName, strings.Join(errs, "; "))
+	}
+	if c.BrokerReadyTimeout < 0 {
+		return errors.New("K8S broker ready timeout must not be negative")
+	}
+	if c.RelistTimeout < 0 {
+		return errors.New("K8S relist timeout must not be negative")
+	}
+	if len(c.CABundleFile) > 0 && len(c.CABundleSecret) > 0 {
+		return errors.New("K8S CA bundle file and CA bundle secret must not both be set")
+	}
+	if errs := validation.IsDNS1123Subdomain(c.CABundleSecret); len(c.CABundleSecret) > 0 && len(errs) > 0 {
+		return fmt.Errorf("K8S CA bundle secret %s is invalid: %s", c.CABundleSecret, strings.Join(errs, "; "))
+	}
+	if c.InsecureSkipTLSVerify && (len(c.CABundleFile) > 0 || len(c.CABundleSecret) > 0) {
+		return errors.New("K8S insecure skip TLS verify must not be combined with a CA bundle")
+	}
+	switch c.RelistBehavior {
+	case "", ManualRelistBehavior:
+	case DurationRelistBehavior:
+		if c.RelistDuration <= 0 {
+			return fmt.Errorf("K8S relist duration must be positive with the %s relist behavior", DurationRelistBehavior)
+		}
+	default:
+		return fmt.Errorf("K8S relist behavior %s is invalid: must be %s or %s", c.RelistBehavior, ManualRelistBehavior, DurationRelistBehavior)
+	}
+	if c.RelistDuration < 0 {
+		return errors.New("K8S relist duration must not be negative")
+	}
+	if c.VisibilityDebounce < 0 {
+		return errors.New("K8S visibility debounce must not be negative")
+	}
+	if c.SyncWorkers < 1 {
+		return errors.New("K8S sync workers must be positive")
+	}
+	if c.SyncRetries < 1 {
+		return errors.New("K8S sync retries must be positive")
+	}
+	if c.SyncRateLimit <= 0 {
+		return errors.New("K8S sync rate limit must be positive")
+	}
+	if c.SyncBurst < 1 {
+		return errors.New("K8S sync burst must be positive")
+	}
+	switch c.ExistingBrokerPolicy {
+	case AdoptExistingBroker, FailOnExistingBroker, ReplaceExistingBroker:
+	default:
+		return fmt.Errorf("K8S existing broker policy %s is invalid: must be one of %s, %s or %s",
+			c.ExistingBrokerPolicy, AdoptExistingBroker, FailOnExistingBroker, ReplaceExistingBroker)
+	}
+	if c.LeaderElection && c.Sharding {
+		return errors.New("K8S leader election and sharding must not be enabled together")
+	}
+	if c.LeaderElection {
+		return c.validateLeaderElection()
+	}
+	if c.Sharding {
+		return c.validateSharding()
+	}
+	return nil
+}
+
+func (c *ClientConfiguration) validateLeaderElection() error {
+	if err := c.validateLease(); err != nil {
+		return err
+	}
+	// the leader elector requires the renew deadline to exceed the retry period including its jitter
+	if float64(c.LeaseRenewDeadline) <= leaderelection.JitterFactor*float64(c.LeaseRetryPeriod) {
+		return fmt.Errorf("K8S lease renew deadline must be greater than %v times the lease retry period", leaderelection.JitterFactor)
+	}
+	if c.LeaseDuration <= c.LeaseRenewDeadline {
+		return errors.New("K8S lease duration must be greater than the lease renew deadline")
+	}
+	return nil
+}
+
+func (c *ClientConfiguration) validateSharding() error {
+	if err := c.validateLease(); err != nil {
+		return err
+	}
+	// a replica must renew its shard Lease at least once before the other replicas consider it to be gone
+	if c.LeaseDuration <= c.LeaseRetryPeriod {
+		return errors.New("K8S lease duration must be greater than the lease retry period")
+	}
+	return nil
+}
+
+func (c *ClientConfiguration) validateLease() error {
+	if errs := validation.IsDNS1123Subdomain(c.LeaseName); len(errs) > 0 {
+		return fmt.Errorf("K8S lease name %s is invalid: %s", c.LeaseName, strings.Join(errs, "; "))
+	}
+	if c.LeaseRetryPeriod <= 0 {
+		return errors.New("K8S lease retry period must be positive")
+	}
 	return nil
 }
 
@@ -137,8 +353,18 @@ func DefaultClientConfiguration() *ClientConfiguration {
 				return clientcmd.BuildConfigFromFlags("", kubeConfigPath) // if kubeConfigPath is empty fallbacks to InClusterConfig
 			},
 		},
-		Secret:              &SecretRef{},
-		K8sClientCreateFunc: NewSvcatSDK,
+		Secret:               &SecretRef{},
+		K8sClientCreateFunc:  NewSvcatSDK,
+		InstanceName:         "default",
+		ExistingBrokerPolicy: FailOnExistingBroker,
+		SyncWorkers:          5,
+		SyncRetries:          3,
+		SyncRateLimit:        10,
+		SyncBurst:            100,
+		LeaseName:            "service-broker-proxy-k8s",
+		LeaseDuration:        15 * time.Second,
+		LeaseRenewDeadline:   10 * time.Second,
+		LeaseRetryPeriod:     2 * time.Second,
 	}
 }
 
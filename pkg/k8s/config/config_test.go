@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Peripli/service-broker-proxy/pkg/sbproxy"
 
@@ -54,6 +55,38 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 					Expect(err).To(HaveOccurred())
 				})
 			})
+
+			Context("when the tracing exporter is unknown", func() {
+				It("should return error", func() {
+					settings.Tracing = DefaultTracingSettings()
+					settings.Tracing.Exporter = "jaeger"
+					err := settings.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("tracing exporter jaeger is invalid: must be none or otlp"))
+				})
+			})
+
+			Context("when the OTLP tracing endpoint is missing", func() {
+				It("should return error", func() {
+					settings.Tracing = DefaultTracingSettings()
+					settings.Tracing.Exporter = OTLPTracingExporter
+					settings.Tracing.Endpoint = ""
+					err := settings.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("tracing endpoint missing"))
+				})
+			})
+
+			Context("when the tracing sample ratio is out of range", func() {
+				It("should return error", func() {
+					settings.Tracing = DefaultTracingSettings()
+					settings.Tracing.Exporter = OTLPTracingExporter
+					settings.Tracing.SampleRatio = 1.5
+					err := settings.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("tracing sample ratio must be between 0 and 1"))
+				})
+			})
 		})
 	})
 
@@ -82,6 +115,244 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 				})
 			})
 
+			Context("when a target namespace is invalid", func() {
+				It("should fail", func() {
+					config.TargetNamespaces = []string{"other-namespace", "Invalid_Namespace"}
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("K8S target namespace Invalid_Namespace is invalid"))
+				})
+			})
+
+			Context("when the namespace selector is invalid", func() {
+				It("should fail", func() {
+					config.NamespaceSelector = "sbproxy.peripli.io/enabled in (true"
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("K8S namespace selector is invalid"))
+				})
+			})
+
+			Context("when the instance name is missing", func() {
+				It("should fail", func() {
+					config.InstanceName = ""
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S instance name missing"))
+				})
+			})
+
+			Context("when the instance name is not a valid label value", func() {
+				It("should fail", func() {
+					config.InstanceName = "proxy instance"
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("K8S instance name proxy instance is invalid"))
+				})
+			})
+
+			Context("when the broker ready timeout is negative", func() {
+				It("should fail", func() {
+					config.BrokerReadyTimeout = -time.Second
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S broker ready timeout must not be negative"))
+				})
+			})
+
+			Context("when the relist timeout is negative", func() {
+				It("should fail", func() {
+					config.RelistTimeout = -time.Second
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S relist timeout must not be negative"))
+				})
+			})
+
+			Context("when both a CA bundle file and a CA bundle secret are configured", func() {
+				It("should fail", func() {
+					config.CABundleFile = "/etc/ssl/broker/ca.crt"
+					config.CABundleSecret = "broker-ca"
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S CA bundle file and CA bundle secret must not both be set"))
+				})
+			})
+
+			Context("when the CA bundle secret name is invalid", func() {
+				It("should fail", func() {
+					config.CABundleSecret = "Broker CA"
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("K8S CA bundle secret Broker CA is invalid"))
+				})
+			})
+
+			Context("when the TLS verification is skipped although a CA bundle is configured", func() {
+				It("should fail", func() {
+					config.CABundleSecret = "broker-ca"
+					config.InsecureSkipTLSVerify = true
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S insecure skip TLS verify must not be combined with a CA bundle"))
+				})
+			})
+
+			Context("when the relist behavior is unknown", func() {
+				It("should fail", func() {
+					config.RelistBehavior = "Never"
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S relist behavior Never is invalid: must be Manual or Duration"))
+				})
+			})
+
+			Context("when the Duration relist behavior has no relist duration", func() {
+				It("should fail", func() {
+					config.RelistBehavior = "Duration"
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S relist duration must be positive with the Duration relist behavior"))
+				})
+			})
+
+			Context("when the relist duration is negative", func() {
+				It("should fail", func() {
+					config.RelistDuration = -time.Minute
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S relist duration must not be negative"))
+				})
+			})
+
+			Context("when the visibility debounce is negative", func() {
+				It("should fail", func() {
+					config.VisibilityDebounce = -time.Second
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S visibility debounce must not be negative"))
+				})
+			})
+
+			Context("when there are no sync workers", func() {
+				It("should fail", func() {
+					config.SyncWorkers = 0
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S sync workers must be positive"))
+				})
+			})
+
+			Context("when syncs are not attempted at all", func() {
+				It("should fail", func() {
+					config.SyncRetries = 0
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S sync retries must be positive"))
+				})
+			})
+
+			Context("when the sync rate limit is not positive", func() {
+				It("should fail", func() {
+					config.SyncRateLimit = 0
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S sync rate limit must be positive"))
+				})
+			})
+
+			Context("when the sync burst is not positive", func() {
+				It("should fail", func() {
+					config.SyncBurst = 0
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S sync burst must be positive"))
+				})
+			})
+
+			Context("when leader election is enabled with the default lease", func() {
+				It("should return nil", func() {
+					config.LeaderElection = true
+					err := config.Validate()
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+
+			Context("when the lease name is invalid", func() {
+				It("should fail", func() {
+					config.LeaderElection = true
+					config.LeaseName = "Lease"
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(HavePrefix("K8S lease name Lease is invalid"))
+				})
+			})
+
+			Context("when the lease renew deadline does not exceed the retry period", func() {
+				It("should fail", func() {
+					config.LeaderElection = true
+					config.LeaseRenewDeadline = config.LeaseRetryPeriod
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S lease renew deadline must be greater than 1.2 times the lease retry period"))
+				})
+			})
+
+			Context("when the lease duration does not exceed the renew deadline", func() {
+				It("should fail", func() {
+					config.LeaderElection = true
+					config.LeaseDuration = config.LeaseRenewDeadline
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S lease duration must be greater than the lease renew deadline"))
+				})
+			})
+
+			Context("when leader election is disabled", func() {
+				It("should not validate the lease", func() {
+					config.LeaseName = ""
+					err := config.Validate()
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+
+			Context("when sharding is enabled with the default lease", func() {
+				It("should return nil", func() {
+					config.Sharding = true
+					err := config.Validate()
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+
+			Context("when sharding and leader election are enabled", func() {
+				It("should fail", func() {
+					config.Sharding = true
+					config.LeaderElection = true
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S leader election and sharding must not be enabled together"))
+				})
+			})
+
+			Context("when the lease duration of the shards does not exceed the retry period", func() {
+				It("should fail", func() {
+					config.Sharding = true
+					config.LeaseDuration = config.LeaseRetryPeriod
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("K8S lease duration must be greater than the lease retry period"))
+				})
+			})
+
+			Context("when the existing broker policy is unknown", func() {
+				It("should fail", func() {
+					config.ExistingBrokerPolicy = "ignore"
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("K8S existing broker policy ignore is invalid"))
+				})
+			})
+
 			Context("when ClientCreateFunc is missing", func() {
 				It("should fail", func() {
 					config.K8sClientCreateFunc = nil
@@ -138,4 +409,22 @@ var _ = Describe("Kubernetes Broker Proxy", func() {
 
 		})
 	})
+
+	Describe("K8S target namespaces", func() {
+		var config *ClientConfiguration
+
+		BeforeEach(func() {
+			config = DefaultClientConfiguration()
+		})
+
+		It("are empty when no namespace is configured", func() {
+			Expect(config.Namespaces()).To(BeEmpty())
+		})
+
+		It("merge TargetNamespace and TargetNamespaces without duplicates", func() {
+			config.TargetNamespace = "namespace-1"
+			config.TargetNamespaces = []string{"namespace-2", " namespace-1", "", "namespace-3"}
+			Expect(config.Namespaces()).To(Equal([]string{"namespace-1", "namespace-2", "namespace-3"}))
+		})
+	})
 })